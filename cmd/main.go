@@ -8,16 +8,23 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -29,10 +36,69 @@ import (
 
 // Lease annotation keys
 const (
-	AnnTTL        = "object-lease-controller.ullberg.io/ttl"
-	AnnLeaseStart = "object-lease-controller.ullberg.io/lease-start" // RFC3339 UTC
-	AnnExpireAt   = "object-lease-controller.ullberg.io/expire-at"
-	AnnStatus     = "object-lease-controller.ullberg.io/lease-status"
+	AnnTTL             = "object-lease-controller.ullberg.io/ttl"
+	AnnLeaseStart      = "object-lease-controller.ullberg.io/lease-start" // RFC3339 UTC
+	AnnExpireAt        = "object-lease-controller.ullberg.io/expire-at"
+	AnnStatus          = "object-lease-controller.ullberg.io/lease-status"
+	AnnStatusJSON      = "object-lease-controller.ullberg.io/lease-status-json" // set by the controller
+	AnnDeleteAt        = "object-lease-controller.ullberg.io/delete-at"         // RFC3339, overrides ttl
+	AnnLeaseMode       = "object-lease-controller.ullberg.io/lease-mode"        // fixed (default), sliding or heartbeat
+	AnnHeartbeat       = "object-lease-controller.ullberg.io/heartbeat"         // RFC3339, refreshed by an external system
+	AnnHeartbeatMissed = "object-lease-controller.ullberg.io/heartbeat-missed"  // set by the controller
+	AnnLeaseClock      = "object-lease-controller.ullberg.io/lease-clock"       // wall (default) or business-hours
+
+	// Cron-like schedule expiry actions wait for, e.g. "* 2-4 * * mon-fri"
+	AnnDeletionWindow = "object-lease-controller.ullberg.io/deletion-window"
+
+	// Expiry action annotation keys
+	AnnOnExpire        = "object-lease-controller.ullberg.io/on-expire"         // delete (default), scale-to-zero, suspend, label:k=v, patch:cm/key
+	AnnOnExpireApplied = "object-lease-controller.ullberg.io/on-expire-applied" // set by the controller
+
+	// Lease phase and hibernate annotation keys
+	AnnPhase              = "object-lease-controller.ullberg.io/lease-phase" // set by the controller
+	AnnHibernateTTL       = "object-lease-controller.ullberg.io/hibernate-ttl"
+	AnnHibernatedReplicas = "object-lease-controller.ullberg.io/hibernated-replicas" // set by the controller
+
+	// Pre-expiry warning thresholds, e.g. "24h,1h,10m"
+	AnnExpiryWarnings = "object-lease-controller.ullberg.io/expiry-warnings"
+
+	// Dry-run marker annotation key
+	AnnWouldExpire = "object-lease-controller.ullberg.io/would-expire" // set by the controller in dry-run mode
+
+	// Lease pause annotation keys
+	AnnLeasePaused    = "object-lease-controller.ullberg.io/lease-paused"    // on the object or its Namespace
+	AnnPausedAt       = "object-lease-controller.ullberg.io/paused-at"       // set by the controller
+	AnnPausedDuration = "object-lease-controller.ullberg.io/paused-duration" // set by the controller
+
+	// Deletion propagation policy, Foreground, Background or Orphan
+	AnnDeletionPropagation = "object-lease-controller.ullberg.io/deletion-propagation"
+
+	// Lease group label and deletion order annotation keys
+	LabelLeaseGroup    = "object-lease-controller.ullberg.io/lease-group"
+	AnnLeaseGroupOrder = "object-lease-controller.ullberg.io/lease-group-order" // lower is deleted first
+
+	// Object the lease expires with, Kind.version.group/[namespace/]name
+	AnnExpireWith = "object-lease-controller.ullberg.io/expire-with"
+	// Owner an inherited lease was copied from, set by the controller
+	AnnInheritedFrom = "object-lease-controller.ullberg.io/inherited-from"
+
+	// Namespace leases: how long to wait for pods to drain, and when it started
+	AnnDrainTimeout   = "object-lease-controller.ullberg.io/drain-timeout"
+	AnnDrainStartedAt = "object-lease-controller.ullberg.io/drain-started-at"
+
+	// IANA timezone a deadline TTL like "until friday 18:00" is read in
+	AnnTimezone = "object-lease-controller.ullberg.io/lease-timezone"
+
+	// Lease renewal annotation keys
+	AnnRenew          = "object-lease-controller.ullberg.io/renew"           // a duration like "2h", anything else restarts the lease
+	AnnRenewCount     = "object-lease-controller.ullberg.io/renew-count"     // set by the controller
+	AnnLastRenewedBy  = "object-lease-controller.ullberg.io/last-renewed-by" // set by the controller
+	AnnLeaseExtension = "object-lease-controller.ullberg.io/lease-extension" // set by the controller
+	AnnMaxRenewals    = "object-lease-controller.ullberg.io/max-renewals"
+	AnnMaxLifetime    = "object-lease-controller.ullberg.io/max-lifetime" // from creation, e.g. "30d"
+
+	// Last lease transitions as a JSON list, set by the controller
+	AnnHistory = "object-lease-controller.ullberg.io/lease-history"
 
 	// Cleanup job annotation keys
 	AnnOnDeleteJob       = "object-lease-controller.ullberg.io/on-delete-job"
@@ -43,8 +109,15 @@ const (
 	AnnJobTTL            = "object-lease-controller.ullberg.io/job-ttl"
 	AnnJobBackoffLimit   = "object-lease-controller.ullberg.io/job-backoff-limit"
 	AnnJobEnvSecrets     = "object-lease-controller.ullberg.io/job-env-secrets"
+	AnnCleanupJobName    = "object-lease-controller.ullberg.io/cleanup-job-name" // set by the controller
 )
 
+// defaultMassExpiryWindow is how far ahead leases count as due for the mass expiry check
+const defaultMassExpiryWindow = 10 * time.Minute
+
+// defaultHistoryLimit is how many transitions the lease-history annotation keeps
+const defaultHistoryLimit = 20
+
 // ParseParams holds runtime configuration parsed from flags and environment.
 type ParseParams struct {
 	Group                   string
@@ -57,6 +130,49 @@ type ParseParams struct {
 	PprofBindAddress        string
 	LeaderElectionEnabled   bool
 	LeaderElectionNamespace string
+	// ExpiryWarnings is a comma separated list of pre-expiry warning thresholds
+	ExpiryWarnings string
+	// DryRun reports expiries without deleting objects or running cleanup jobs
+	DryRun bool
+	// KillSwitchConfigMap is the "namespace/name" of the ConfigMap that freezes deletions
+	KillSwitchConfigMap string
+	// BusinessHours and BusinessDays are when business-hours leases count down
+	BusinessHours string
+	BusinessDays  string
+	// HolidayConfigMap is the "namespace/name" of the ConfigMap that lists holidays
+	HolidayConfigMap string
+	// DeletionWindow is the cron-like schedule expiry actions wait for
+	DeletionWindow string
+	// MaxRenewals limits lease renewals, 0 is unlimited. MaxLifetime ends
+	// every lease that long after the object was created.
+	MaxRenewals int
+	MaxLifetime string
+	// HistoryLimit is how many transitions lease-history keeps, 0 turns it
+	// off. HistoryConfigMap names the ConfigMap deleted objects' histories
+	// are archived in.
+	HistoryLimit     int
+	HistoryConfigMap string
+	// DeletionRate and NamespaceDeletionRate limit expiry actions per minute, 0 is unlimited
+	DeletionRate          int
+	NamespaceDeletionRate int
+	// MassExpiryThreshold is the percentage of tracked leases that may be due
+	// within MassExpiryWindow before deletions are halted, 0 disables the check
+	MassExpiryThreshold int
+	MassExpiryWindow    time.Duration
+	// DeletionPropagation is the default propagation policy for deletions
+	DeletionPropagation string
+	// CleanupFinalizer runs cleanup jobs for manual deletes as well
+	CleanupFinalizer bool
+	// InheritLease lets objects without a lease inherit their owner's expiry
+	InheritLease bool
+	// OpsNamespace is where cleanup jobs for cluster-scoped objects run
+	OpsNamespace string
+	// OptInSelector is a label selector objects must match to be managed
+	OptInSelector string
+	// CalendarDurations adds TTL months and years as calendar months and years
+	CalendarDurations bool
+	// Timezone is the IANA timezone deadline TTLs are read in by default
+	Timezone string
 }
 
 var (
@@ -109,6 +225,114 @@ func run(params ParseParams) {
 		return
 	}
 
+	warnings, err := util.ParseDurationList(params.ExpiryWarnings)
+	if err != nil {
+		fmt.Printf("invalid expiry warnings: %v\n", err)
+		exitFn(1)
+		return
+	}
+
+	var killSwitchNS, killSwitchName string
+	if params.KillSwitchConfigMap != "" {
+		if killSwitchNS, killSwitchName, err = parseKillSwitchRef(params.KillSwitchConfigMap); err != nil {
+			fmt.Printf("%v\n", err)
+			exitFn(1)
+			return
+		}
+	}
+
+	businessClock, err := util.NewBusinessClock(params.BusinessHours, params.BusinessDays)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		exitFn(1)
+		return
+	}
+	var deletionWindow *util.DeletionWindow
+	if params.DeletionWindow != "" {
+		if deletionWindow, err = util.ParseDeletionWindow(params.DeletionWindow); err != nil {
+			fmt.Printf("%v\n", err)
+			exitFn(1)
+			return
+		}
+	}
+	if params.MaxRenewals < 0 {
+		fmt.Println("max renewals must not be negative")
+		exitFn(1)
+		return
+	}
+	var maxLifetime time.Duration
+	if params.MaxLifetime != "" {
+		if maxLifetime, err = util.ParseFlexibleDuration(params.MaxLifetime); err != nil || maxLifetime <= 0 {
+			fmt.Printf("invalid max lifetime %q: expected a positive duration\n", params.MaxLifetime)
+			exitFn(1)
+			return
+		}
+	}
+	if params.HistoryLimit < 0 {
+		fmt.Println("lease history limit must not be negative")
+		exitFn(1)
+		return
+	}
+	if params.HistoryConfigMap != "" {
+		if errs := validation.IsDNS1123Subdomain(params.HistoryConfigMap); len(errs) > 0 {
+			fmt.Printf("invalid history ConfigMap name %q: %s\n", params.HistoryConfigMap, strings.Join(errs, ", "))
+			exitFn(1)
+			return
+		}
+	}
+	var holidayNS, holidayName string
+	if params.HolidayConfigMap != "" {
+		if holidayNS, holidayName, err = parseConfigMapRef("holiday", params.HolidayConfigMap); err != nil {
+			fmt.Printf("%v\n", err)
+			exitFn(1)
+			return
+		}
+	}
+
+	propagation, err := util.ParsePropagationPolicy(params.DeletionPropagation)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		exitFn(1)
+		return
+	}
+
+	if params.OpsNamespace != "" {
+		if errs := validation.IsDNS1123Label(params.OpsNamespace); len(errs) > 0 {
+			fmt.Printf("invalid ops namespace %q: %s\n", params.OpsNamespace, strings.Join(errs, ", "))
+			exitFn(1)
+			return
+		}
+	}
+
+	var location *time.Location
+	if params.Timezone != "" {
+		if location, err = time.LoadLocation(params.Timezone); err != nil {
+			fmt.Printf("invalid timezone %q: %v\n", params.Timezone, err)
+			exitFn(1)
+			return
+		}
+	}
+
+	var selector labels.Selector
+	if params.OptInSelector != "" {
+		if selector, err = labels.Parse(params.OptInSelector); err != nil {
+			fmt.Printf("invalid opt-in selector: %v\n", err)
+			exitFn(1)
+			return
+		}
+	}
+
+	if params.DeletionRate < 0 || params.NamespaceDeletionRate < 0 {
+		fmt.Println("deletion rates must not be negative")
+		exitFn(1)
+		return
+	}
+	if params.MassExpiryThreshold < 0 || params.MassExpiryThreshold > 100 {
+		fmt.Println("mass expiry threshold must be a percentage between 0 and 100")
+		exitFn(1)
+		return
+	}
+
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
 	_ = batchv1.AddToScheme(scheme)
@@ -123,6 +347,14 @@ func run(params ParseParams) {
 	leaderElectionID := strings.ToLower(fmt.Sprintf("object-lease-controller-%s-%s-%s", params.Group, params.Version, params.Kind))
 
 	mgrOpts := buildManagerOptions(scheme, params.Group, params.Version, params.Kind, params.MetricsBindAddress, params.HealthProbeBindAddress, params.PprofBindAddress, enableLeaderElection, leaderElectionNamespace)
+	// Only the kill-switch and holiday ConfigMaps are watched, not every
+	// ConfigMap in the cluster
+	if cmCache, ok := configMapCache(
+		types.NamespacedName{Namespace: killSwitchNS, Name: killSwitchName},
+		types.NamespacedName{Namespace: holidayNS, Name: holidayName},
+	); ok {
+		mgrOpts.Cache.ByObject = map[client.Object]cache.ByObject{&corev1.ConfigMap{}: cmCache}
+	}
 
 	mgr, err := newManager(getConfig(), mgrOpts)
 	if err != nil {
@@ -132,6 +364,41 @@ func run(params ParseParams) {
 
 	// Create a LeaseWatcher for the specified GVK
 	lw := newLeaseWatcher(mgr, gvk, leaderElectionID)
+	lw.WarningThresholds = warnings
+	lw.DryRun = params.DryRun
+	lw.DefaultPropagation = propagation
+	lw.CleanupFinalizer = params.CleanupFinalizer
+	lw.InheritLease = params.InheritLease
+	lw.CalendarDurations = params.CalendarDurations
+	lw.Location = location
+	lw.BusinessClock = businessClock
+	lw.DeletionWindow = deletionWindow
+	lw.MaxRenewals = params.MaxRenewals
+	lw.MaxLifetime = maxLifetime
+	lw.HistoryLimit = params.HistoryLimit
+	lw.HistoryConfigMap = params.HistoryConfigMap
+	lw.OpsNamespace = params.OpsNamespace
+	lw.Selector = selector
+	// Cluster-scoped kinds have no namespace to opt in, they opt in by label
+	clusterScoped, err := isClusterScoped(mgr.GetRESTMapper(), gvk)
+	if err != nil {
+		setupLog.Error(err, "unable to determine the scope of the kind, assuming it is namespaced", "GVK", gvk)
+	}
+	lw.ClusterScoped = clusterScoped
+	if clusterScoped && params.OptInLabelKey != "" && (gvk.Group != "" || gvk.Kind != "Namespace") {
+		fmt.Printf("namespace opt-in does not apply to cluster-scoped %s, use --opt-in-selector instead\n", gvk.Kind)
+		exitFn(1)
+		return
+	}
+	if params.DeletionRate > 0 || params.NamespaceDeletionRate > 0 {
+		lw.Budget = util.NewDeletionBudget(params.DeletionRate, params.NamespaceDeletionRate)
+	}
+	if params.MassExpiryThreshold > 0 {
+		lw.Breaker = util.NewCircuitBreaker(float64(params.MassExpiryThreshold)/100, params.MassExpiryWindow)
+	}
+	if params.DryRun {
+		setupLog.Info("Dry-run mode enabled, expired objects will not be deleted", "GVK", gvk)
+	}
 
 	if tr, err := configureNamespaceReconciler(mgr, params.OptInLabelKey, params.OptInLabelValue, leaderElectionID); err != nil {
 		setupLog.Error(err, "unable to create controller", "GVK", gvk)
@@ -140,6 +407,36 @@ func run(params ParseParams) {
 		lw.Tracker = tr
 	}
 
+	if killSwitchName != "" {
+		// The freeze shows in the deletions_frozen gauge, events and its own
+		// check, not in readiness, which would take the metrics endpoint out
+		// of the Service
+		ks, err := configureKillSwitch(mgr, killSwitchNS, killSwitchName, lw.Metrics, lw.Recorder)
+		if err != nil {
+			setupLog.Error(err, "unable to create kill switch controller")
+			panic(err)
+		}
+		lw.KillSwitch = ks
+		if err := mgr.AddMetricsServerExtraHandler("/kill-switch", healthz.CheckHandler{Checker: newKillSwitchCheck(ks)}); err != nil {
+			setupLog.Error(err, "unable to set up kill switch check")
+			exitFn(1)
+			return
+		}
+	}
+
+	if holidayName != "" {
+		hr := &controllers.HolidayReconciler{
+			Client:    mgr.GetClient(),
+			Namespace: holidayNS,
+			Name:      holidayName,
+			Clock:     businessClock,
+		}
+		if err := hr.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create holiday controller")
+			panic(err)
+		}
+	}
+
 	// Register the LeaseWatcher with the manager
 	if err := lw.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "GVK", gvk)
@@ -202,6 +499,8 @@ func parseParameters() ParseParams {
 
 	flag.StringVar(&optInLabelKey, "opt-in-label-key", "", "The label key to opt-in namespaces")
 	flag.StringVar(&optInLabelValue, "opt-in-label-value", "", "The label value to opt-in namespaces")
+	var optInSelector string
+	flag.StringVar(&optInSelector, "opt-in-selector", "", "Label selector objects must match to be managed, e.g. lease.example.com/enabled=true. The opt-in for cluster-scoped kinds.")
 
 	var metricsAddr, probeAddr, pprofAddr string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metrics endpoint binds to. "+
@@ -218,6 +517,75 @@ func parseParameters() ParseParams {
 	flag.StringVar(&leaderElectionNamespace, "leader-elect-namespace", "",
 		"Namespace for leader election lock. Defaults to the namespace of the controller manager.")
 
+	var expiryWarnings string
+	flag.StringVar(&expiryWarnings, "expiry-warnings", "",
+		"Comma separated durations before expiry at which to warn lease owners (e.g. \"24h,1h,10m\").")
+
+	var dryRun bool
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Report expired objects with a would-expire annotation and event instead of deleting them or running cleanup jobs.")
+
+	var killSwitch string
+	flag.StringVar(&killSwitch, "kill-switch-configmap", "",
+		"Namespace/name of a ConfigMap that freezes all deletions while its freeze-deletions key is \"true\".")
+
+	var businessHours, businessDays, holidays string
+	flag.StringVar(&businessHours, "business-hours", util.DefaultBusinessHours,
+		"Hours business-hours leases count down in, HH:MM-HH:MM in the timezone of the lease.")
+	flag.StringVar(&businessDays, "business-days", util.DefaultBusinessDays,
+		"Days business-hours leases count down on, e.g. \"mon-fri\" or \"sun-thu\".")
+	flag.StringVar(&holidays, "holiday-configmap", "",
+		"Namespace/name of a ConfigMap whose keys are 2006-01-02 dates business-hours leases do not count down on.")
+
+	var deletionWindow string
+	flag.StringVar(&deletionWindow, "deletion-window", "",
+		"Cron-like schedule of the minutes expired objects may be deleted in, e.g. \"* 2-4 * * mon-fri\". Empty allows any time.")
+
+	var maxRenewals int
+	var maxLifetime string
+	flag.IntVar(&maxRenewals, "max-renewals", 0, "Maximum renewals of a lease through the renew annotation. 0 means unlimited.")
+	flag.StringVar(&maxLifetime, "max-lifetime", "",
+		"Longest an object may live from its creation however often its lease is renewed, e.g. \"30d\". Empty is no limit.")
+
+	var historyLimit int
+	var historyConfigMap string
+	flag.IntVar(&historyLimit, "lease-history-limit", defaultHistoryLimit,
+		"Number of lease transitions the lease-history annotation keeps. 0 turns the history off.")
+	flag.StringVar(&historyConfigMap, "history-configmap", "",
+		"Name of the ConfigMap the lease history of deleted objects is archived in, in their namespace or the ops namespace.")
+
+	var deletionRate, nsDeletionRate, massExpiryThreshold int
+	var massExpiryWindow time.Duration
+	flag.IntVar(&deletionRate, "deletion-rate", 0, "Maximum expiry actions per minute for this GVK. 0 means unlimited.")
+	flag.IntVar(&nsDeletionRate, "namespace-deletion-rate", 0, "Maximum expiry actions per minute in a single namespace. 0 means unlimited.")
+	flag.IntVar(&massExpiryThreshold, "mass-expiry-threshold", 0,
+		"Halt deletions when more than this percentage of tracked leases is due within the mass expiry window. 0 disables the check.")
+	flag.DurationVar(&massExpiryWindow, "mass-expiry-window", defaultMassExpiryWindow, "How far ahead leases count as due for the mass expiry check.")
+
+	var deletionPropagation string
+	flag.StringVar(&deletionPropagation, "deletion-propagation", "",
+		"Default deletion propagation policy: Foreground, Background or Orphan. Defaults to the API server default.")
+
+	var cleanupFinalizer bool
+	flag.BoolVar(&cleanupFinalizer, "cleanup-finalizer", false,
+		"Add a finalizer to leased objects with an on-delete-job so the job also runs when they are deleted by hand.")
+
+	var inheritLease bool
+	flag.BoolVar(&inheritLease, "inherit-lease", false,
+		"Give objects without a lease the expiry of their closest leased owner, found through ownerReferences.")
+
+	var calendarDurations bool
+	flag.BoolVar(&calendarDurations, "calendar-durations", false,
+		"Add the months and years of a TTL as calendar months and years from lease-start instead of 30 and 365 days.")
+
+	var timezone string
+	flag.StringVar(&timezone, "timezone", "",
+		"IANA timezone deadline TTLs like \"until friday 18:00\" are read in when the object has no lease-timezone. Default UTC.")
+
+	var opsNamespace string
+	flag.StringVar(&opsNamespace, "ops-namespace", "",
+		"Namespace that cleanup jobs for cluster-scoped objects, Namespaces included, run in.")
+
 	flag.Parse()
 
 	// Allow env vars as fallback
@@ -236,6 +604,9 @@ func parseParameters() ParseParams {
 	if optInLabelValue == "" {
 		optInLabelValue = os.Getenv("LEASE_OPT_IN_LABEL_VALUE")
 	}
+	if optInSelector == "" {
+		optInSelector = os.Getenv("LEASE_OPT_IN_SELECTOR")
+	}
 
 	// Leader election may be enabled via env var when not set via flags
 	if !enableLeaderElection {
@@ -251,17 +622,131 @@ func parseParameters() ParseParams {
 		leaderElectionNamespace = os.Getenv("LEASE_LEADER_ELECTION_NAMESPACE")
 	}
 
+	if expiryWarnings == "" {
+		expiryWarnings = os.Getenv("LEASE_EXPIRY_WARNINGS")
+	}
+
+	if !dryRun {
+		if dr := os.Getenv("LEASE_DRY_RUN"); strings.EqualFold(dr, "true") || dr == "1" {
+			dryRun = true
+		}
+	}
+
+	if killSwitch == "" {
+		killSwitch = os.Getenv("LEASE_KILL_SWITCH_CONFIGMAP")
+	}
+
+	if businessHours == util.DefaultBusinessHours {
+		if v := os.Getenv("LEASE_BUSINESS_HOURS"); v != "" {
+			businessHours = v
+		}
+	}
+	if businessDays == util.DefaultBusinessDays {
+		if v := os.Getenv("LEASE_BUSINESS_DAYS"); v != "" {
+			businessDays = v
+		}
+	}
+	if holidays == "" {
+		holidays = os.Getenv("LEASE_HOLIDAY_CONFIGMAP")
+	}
+	if deletionWindow == "" {
+		deletionWindow = os.Getenv("LEASE_DELETION_WINDOW")
+	}
+
+	if maxRenewals == 0 {
+		maxRenewals = envInt("LEASE_MAX_RENEWALS")
+	}
+	if maxLifetime == "" {
+		maxLifetime = os.Getenv("LEASE_MAX_LIFETIME")
+	}
+
+	if historyLimit == defaultHistoryLimit {
+		if v, err := strconv.Atoi(os.Getenv("LEASE_HISTORY_LIMIT")); err == nil {
+			historyLimit = v
+		}
+	}
+	if historyConfigMap == "" {
+		historyConfigMap = os.Getenv("LEASE_HISTORY_CONFIGMAP")
+	}
+
+	if deletionRate == 0 {
+		deletionRate = envInt("LEASE_DELETION_RATE")
+	}
+	if nsDeletionRate == 0 {
+		nsDeletionRate = envInt("LEASE_NAMESPACE_DELETION_RATE")
+	}
+	if massExpiryThreshold == 0 {
+		massExpiryThreshold = envInt("LEASE_MASS_EXPIRY_THRESHOLD")
+	}
+	if massExpiryWindow == defaultMassExpiryWindow {
+		if d, err := time.ParseDuration(os.Getenv("LEASE_MASS_EXPIRY_WINDOW")); err == nil {
+			massExpiryWindow = d
+		}
+	}
+
+	if deletionPropagation == "" {
+		deletionPropagation = os.Getenv("LEASE_DELETION_PROPAGATION")
+	}
+
+	if !cleanupFinalizer {
+		if cf := os.Getenv("LEASE_CLEANUP_FINALIZER"); strings.EqualFold(cf, "true") || cf == "1" {
+			cleanupFinalizer = true
+		}
+	}
+
+	if opsNamespace == "" {
+		opsNamespace = os.Getenv("LEASE_OPS_NAMESPACE")
+	}
+
+	if timezone == "" {
+		timezone = os.Getenv("LEASE_TIMEZONE")
+	}
+
+	if !inheritLease {
+		if il := os.Getenv("LEASE_INHERIT_LEASE"); strings.EqualFold(il, "true") || il == "1" {
+			inheritLease = true
+		}
+	}
+
+	if !calendarDurations {
+		if cd := os.Getenv("LEASE_CALENDAR_DURATIONS"); strings.EqualFold(cd, "true") || cd == "1" {
+			calendarDurations = true
+		}
+	}
+
 	return ParseParams{
 		Group:                   group,
 		Version:                 version,
 		Kind:                    kind,
 		OptInLabelKey:           optInLabelKey,
 		OptInLabelValue:         optInLabelValue,
+		OptInSelector:           optInSelector,
 		MetricsBindAddress:      metricsAddr,
 		HealthProbeBindAddress:  probeAddr,
 		PprofBindAddress:        pprofAddr,
 		LeaderElectionEnabled:   enableLeaderElection,
 		LeaderElectionNamespace: leaderElectionNamespace,
+		ExpiryWarnings:          expiryWarnings,
+		DryRun:                  dryRun,
+		KillSwitchConfigMap:     killSwitch,
+		BusinessHours:           businessHours,
+		BusinessDays:            businessDays,
+		HolidayConfigMap:        holidays,
+		DeletionWindow:          deletionWindow,
+		MaxRenewals:             maxRenewals,
+		MaxLifetime:             maxLifetime,
+		HistoryLimit:            historyLimit,
+		HistoryConfigMap:        historyConfigMap,
+		DeletionRate:            deletionRate,
+		NamespaceDeletionRate:   nsDeletionRate,
+		MassExpiryThreshold:     massExpiryThreshold,
+		MassExpiryWindow:        massExpiryWindow,
+		DeletionPropagation:     deletionPropagation,
+		CleanupFinalizer:        cleanupFinalizer,
+		InheritLease:            inheritLease,
+		OpsNamespace:            opsNamespace,
+		CalendarDurations:       calendarDurations,
+		Timezone:                timezone,
 	}
 }
 
@@ -310,11 +795,19 @@ func buildManagerOptions(scheme *runtime.Scheme, group, version, kind string, me
 		LeaderElectionReleaseOnCancel: true,
 		Metrics:                       metricsServerOptions,
 		HealthProbeBindAddress:        probeAddr,
+		// ConfigMaps other than the watched ones are not cached, read them live
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.ConfigMap{}}},
+		},
 		Cache: cache.Options{
 			DefaultTransform: util.MinimalObjectTransform(
-				AnnTTL, AnnLeaseStart, AnnExpireAt, AnnStatus,
+				AnnTTL, AnnLeaseStart, AnnExpireAt, AnnStatus, AnnStatusJSON, AnnDeleteAt, AnnLeaseMode, AnnHeartbeat, AnnHeartbeatMissed, AnnLeaseClock, AnnDeletionWindow,
+				AnnOnExpire, AnnOnExpireApplied, AnnPhase, AnnHibernateTTL, AnnHibernatedReplicas, AnnExpiryWarnings, AnnWouldExpire,
+				AnnLeasePaused, AnnPausedAt, AnnPausedDuration, AnnDeletionPropagation, AnnLeaseGroupOrder, AnnExpireWith, AnnInheritedFrom,
+				AnnDrainTimeout, AnnDrainStartedAt, AnnTimezone, AnnHistory,
+				AnnRenew, AnnRenewCount, AnnLastRenewedBy, AnnLeaseExtension, AnnMaxRenewals, AnnMaxLifetime,
 				AnnOnDeleteJob, AnnJobServiceAccount, AnnJobImage, AnnJobWait,
-				AnnJobTimeout, AnnJobTTL, AnnJobBackoffLimit,
+				AnnJobTimeout, AnnJobTTL, AnnJobBackoffLimit, AnnJobEnvSecrets, AnnCleanupJobName,
 			),
 		},
 	}
@@ -324,6 +817,36 @@ func buildManagerOptions(scheme *runtime.Scheme, group, version, kind string, me
 	return mgrOpts
 }
 
+// configMapCache limits the ConfigMap informer to the given ConfigMaps, by
+// namespace and, where a namespace holds only one of them, by name. Returns
+// false when no ConfigMap is watched.
+func configMapCache(refs ...types.NamespacedName) (cache.ByObject, bool) {
+	names := map[string]map[string]bool{}
+	for _, ref := range refs {
+		if ref.Namespace == "" || ref.Name == "" {
+			continue
+		}
+		if names[ref.Namespace] == nil {
+			names[ref.Namespace] = map[string]bool{}
+		}
+		names[ref.Namespace][ref.Name] = true
+	}
+	if len(names) == 0 {
+		return cache.ByObject{}, false
+	}
+	namespaces := map[string]cache.Config{}
+	for ns, inNS := range names {
+		config := cache.Config{FieldSelector: fields.Everything()}
+		if len(inNS) == 1 {
+			for name := range inNS {
+				config.FieldSelector = fields.OneTermEqualSelector("metadata.name", name)
+			}
+		}
+		namespaces[ns] = config
+	}
+	return cache.ByObject{Namespaces: namespaces}, true
+}
+
 // Create a LeaseWatcher attached to the given manager. The LeaseWatcher is initialized
 // with default annotations and metrics for the provided GVK. The function does not
 // call SetupWithManager - this is left to the caller.
@@ -333,18 +856,51 @@ func newLeaseWatcher(mgr ctrl.Manager, gvk schema.GroupVersionKind, leaderElecti
 		GVK:      gvk,
 		Recorder: mgr.GetEventRecorder(leaderElectionID),
 		Annotations: controllers.Annotations{
-			TTL:               AnnTTL,
-			LeaseStart:        AnnLeaseStart,
-			ExpireAt:          AnnExpireAt,
-			Status:            AnnStatus,
-			OnDeleteJob:       AnnOnDeleteJob,
-			JobServiceAccount: AnnJobServiceAccount,
-			JobImage:          AnnJobImage,
-			JobWait:           AnnJobWait,
-			JobTimeout:        AnnJobTimeout,
-			JobTTL:            AnnJobTTL,
-			JobBackoffLimit:   AnnJobBackoffLimit,
-			JobEnvSecrets:     AnnJobEnvSecrets,
+			TTL:                 AnnTTL,
+			LeaseStart:          AnnLeaseStart,
+			ExpireAt:            AnnExpireAt,
+			Status:              AnnStatus,
+			StatusJSON:          AnnStatusJSON,
+			DeleteAt:            AnnDeleteAt,
+			LeaseMode:           AnnLeaseMode,
+			Heartbeat:           AnnHeartbeat,
+			HeartbeatMissed:     AnnHeartbeatMissed,
+			LeaseClock:          AnnLeaseClock,
+			DeletionWindow:      AnnDeletionWindow,
+			OnExpire:            AnnOnExpire,
+			OnExpireApplied:     AnnOnExpireApplied,
+			Phase:               AnnPhase,
+			HibernateTTL:        AnnHibernateTTL,
+			HibernatedReplicas:  AnnHibernatedReplicas,
+			ExpiryWarnings:      AnnExpiryWarnings,
+			WouldExpire:         AnnWouldExpire,
+			LeasePaused:         AnnLeasePaused,
+			PausedAt:            AnnPausedAt,
+			PausedDuration:      AnnPausedDuration,
+			DeletionPropagation: AnnDeletionPropagation,
+			LeaseGroup:          LabelLeaseGroup,
+			LeaseGroupOrder:     AnnLeaseGroupOrder,
+			ExpireWith:          AnnExpireWith,
+			InheritedFrom:       AnnInheritedFrom,
+			DrainTimeout:        AnnDrainTimeout,
+			DrainStartedAt:      AnnDrainStartedAt,
+			Timezone:            AnnTimezone,
+			Renew:               AnnRenew,
+			RenewCount:          AnnRenewCount,
+			LastRenewedBy:       AnnLastRenewedBy,
+			LeaseExtension:      AnnLeaseExtension,
+			MaxRenewals:         AnnMaxRenewals,
+			MaxLifetime:         AnnMaxLifetime,
+			History:             AnnHistory,
+			OnDeleteJob:         AnnOnDeleteJob,
+			JobServiceAccount:   AnnJobServiceAccount,
+			JobImage:            AnnJobImage,
+			JobWait:             AnnJobWait,
+			JobTimeout:          AnnJobTimeout,
+			JobTTL:              AnnJobTTL,
+			JobBackoffLimit:     AnnJobBackoffLimit,
+			JobEnvSecrets:       AnnJobEnvSecrets,
+			CleanupJobName:      AnnCleanupJobName,
 		},
 		Metrics: ometrics.NewLeaseMetrics(gvk),
 	}
@@ -371,16 +927,80 @@ func configureNamespaceReconciler(mgr ctrl.Manager, optInLabelKey, optInLabelVal
 	return tracker, nil
 }
 
+// envInt reads an integer environment variable. Unset or invalid values read as 0.
+func envInt(key string) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// parseKillSwitchRef splits a "namespace/name" ConfigMap reference
+func parseKillSwitchRef(ref string) (string, string, error) {
+	return parseConfigMapRef("kill switch", ref)
+}
+
+// parseConfigMapRef splits the "namespace/name" reference of the ConfigMap
+// named in errors by what
+func parseConfigMapRef(what, ref string) (string, string, error) {
+	ns, name, ok := strings.Cut(ref, "/")
+	if !ok || ns == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid %s configmap %q: expected namespace/name", what, ref)
+	}
+	return ns, name, nil
+}
+
+// configureKillSwitch registers the reconciler that watches the kill-switch
+// ConfigMap and returns the KillSwitch it keeps up to date.
+func configureKillSwitch(mgr ctrl.Manager, namespace, name string, metrics *ometrics.LeaseMetrics, recorder events.EventRecorder) (*util.KillSwitch, error) {
+	ks := util.NewKillSwitch()
+	kr := &controllers.KillSwitchReconciler{
+		Client:    mgr.GetClient(),
+		Recorder:  recorder,
+		Namespace: namespace,
+		Name:      name,
+		Switch:    ks,
+		Metrics:   metrics,
+	}
+	if err := kr.SetupWithManager(mgr); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// newKillSwitchCheck returns a check that fails until the kill switch has been
+// read and while deletions are frozen
+func newKillSwitchCheck(ks *util.KillSwitch) healthz.Checker {
+	return func(req *http.Request) error {
+		if !ks.Synced() {
+			return fmt.Errorf("kill switch not read yet, deletions are held")
+		}
+		if frozen, reason := ks.Frozen(); frozen {
+			return fmt.Errorf("deletions frozen by kill switch: %s", reason)
+		}
+		return nil
+	}
+}
+
+// isClusterScoped asks the RESTMapper whether gvk is cluster-scoped
+func isClusterScoped(mapper apimeta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, fmt.Errorf("rest mapping failed for %s: %w", gvk.String(), err)
+	}
+	return mapping.Scope.Name() == apimeta.RESTScopeNameRoot, nil
+}
+
 // Health check: confirm GVK is discoverable and listable with minimal load
 func healthCheck(req *http.Request, mgr ctrl.Manager, gvk schema.GroupVersionKind) error {
 	ctx := req.Context()
 
 	// Resolve scope from RESTMapper
-	mapping, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	clusterScoped, err := isClusterScoped(mgr.GetRESTMapper(), gvk)
 	if err != nil {
-		return fmt.Errorf("rest mapping failed for %s: %w", gvk.String(), err)
+		return err
 	}
-	namespaced := mapping.Scope.Name() == apimeta.RESTScopeNameNamespace
 
 	// Build an unstructured list for the configured GVK
 	ul := &unstructured.UnstructuredList{}
@@ -393,7 +1013,7 @@ func healthCheck(req *http.Request, mgr ctrl.Manager, gvk schema.GroupVersionKin
 	// Cheap probe: limit to 1 item and namespace only if namespaced
 	var opts []client.ListOption
 	opts = append(opts, client.Limit(1))
-	if namespaced {
+	if !clusterScoped {
 		ns := "default"
 		if nsEnv := os.Getenv("LEASE_NAMESPACE"); nsEnv != "" {
 			ns = nsEnv
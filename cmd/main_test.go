@@ -9,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	// Test for building manager options
 	corev1 "k8s.io/api/core/v1"
@@ -16,6 +17,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 
 	logr "github.com/go-logr/logr"
 	"k8s.io/client-go/rest"
@@ -32,6 +34,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 	// ctrl alias not required; we use manager.Runnable from pkg/manager
+
+	"object-lease-controller/pkg/util"
 )
 
 // A small fake manager used to test healthCheck.
@@ -55,7 +59,7 @@ func (f *fakeManager) GetScheme() *runtime.Scheme           { return f.scheme }
 func (f *fakeManager) GetConfig() *rest.Config              { return &rest.Config{} }
 func (f *fakeManager) GetHTTPClient() *http.Client          { return &http.Client{} }
 func (f *fakeManager) GetCache() cache.Cache                { return nil }
-func (f *fakeManager) GetFieldIndexer() client.FieldIndexer { return nil }
+func (f *fakeManager) GetFieldIndexer() client.FieldIndexer { return nopIndexer{} }
 func (f *fakeManager) GetEventRecorderFor(name string) record.EventRecorder {
 	return record.NewFakeRecorder(10)
 }
@@ -80,6 +84,13 @@ func (f *fakeManager) GetEventRecorder(name string) events.EventRecorder {
 	return &fakeEventsRecorder{}
 }
 
+// nopIndexer implements client.FieldIndexer for tests.
+type nopIndexer struct{}
+
+func (nopIndexer) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	return nil
+}
+
 // fakeEventsRecorder implements events.EventRecorder for tests.
 type fakeEventsRecorder struct{}
 
@@ -90,7 +101,7 @@ func (r *fakeEventsRecorder) Eventf(regarding runtime.Object, related runtime.Ob
 func (t *testMgr) GetScheme() *runtime.Scheme                                           { return runtime.NewScheme() }
 func (t *testMgr) GetConfig() *rest.Config                                              { return &rest.Config{} }
 func (t *testMgr) GetHTTPClient() *http.Client                                          { return &http.Client{} }
-func (t *testMgr) GetFieldIndexer() client.FieldIndexer                                 { return nil }
+func (t *testMgr) GetFieldIndexer() client.FieldIndexer                                 { return nopIndexer{} }
 func (t *testMgr) GetEventRecorderFor(s string) record.EventRecorder                    { return nil }
 func (t *testMgr) GetCache() cache.Cache                                                { return nil }
 func (t *testMgr) Start(ctx context.Context) error                                      { return nil }
@@ -325,6 +336,237 @@ func TestRun_ParseLeaderElectionInvalid(t *testing.T) {
 	run(params)
 }
 
+func TestRun_InvalidExpiryWarningsExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "apps", Version: "v1", Kind: "ConfigMap", ExpiryWarnings: "1h,soon"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for invalid expiry warnings")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidKillSwitchExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "apps", Version: "v1", Kind: "ConfigMap", KillSwitchConfigMap: "no-namespace"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for invalid kill switch reference")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidMassExpiryThresholdExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "apps", Version: "v1", Kind: "ConfigMap", MassExpiryThreshold: 150}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for a threshold above 100")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidDeletionPropagationExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "apps", Version: "v1", Kind: "ConfigMap", DeletionPropagation: "cascade"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for an invalid deletion propagation")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidOpsNamespaceExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "", Version: "v1", Kind: "Namespace", OpsNamespace: "Lease_Ops"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for an invalid ops namespace")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidOptInSelectorExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "", Version: "v1", Kind: "ConfigMap", OptInSelector: "a=(b"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for an invalid opt-in selector")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidTimezoneExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "", Version: "v1", Kind: "ConfigMap", Timezone: "Mars/Olympus"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for an invalid timezone")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidBusinessHoursExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	for _, params := range []ParseParams{
+		{Group: "", Version: "v1", Kind: "ConfigMap", BusinessHours: "17:00-09:00"},
+		{Group: "", Version: "v1", Kind: "ConfigMap", BusinessDays: "weekdays"},
+		{Group: "", Version: "v1", Kind: "ConfigMap", HolidayConfigMap: "holidays"},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("expected exit via exitFn for %+v", params)
+				}
+			}()
+			run(params)
+		}()
+	}
+}
+
+func TestRun_InvalidDeletionWindowExits(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	params := ParseParams{Group: "", Version: "v1", Kind: "ConfigMap", DeletionWindow: "weekdays 02:00-05:00"}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected exit via exitFn for an invalid deletion window")
+		}
+	}()
+	run(params)
+}
+
+func TestRun_InvalidRenewalLimitsExit(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	for _, params := range []ParseParams{
+		{Group: "", Version: "v1", Kind: "ConfigMap", MaxRenewals: -1},
+		{Group: "", Version: "v1", Kind: "ConfigMap", MaxLifetime: "forever"},
+		{Group: "", Version: "v1", Kind: "ConfigMap", MaxLifetime: "0s"},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("expected exit via exitFn for %+v", params)
+				}
+			}()
+			run(params)
+		}()
+	}
+}
+
+func TestRun_InvalidHistorySettingsExit(t *testing.T) {
+	oldExit := exitFn
+	t.Cleanup(func() { exitFn = oldExit })
+
+	exitFn = func(code int) { panic(fmt.Sprintf("exited %d", code)) }
+
+	for _, params := range []ParseParams{
+		{Group: "", Version: "v1", Kind: "ConfigMap", HistoryLimit: -1},
+		{Group: "", Version: "v1", Kind: "ConfigMap", HistoryConfigMap: "Lease History"},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("expected exit via exitFn for %+v", params)
+				}
+			}()
+			run(params)
+		}()
+	}
+}
+
+func TestIsClusterScoped(t *testing.T) {
+	mapper := apimeta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "", Version: "v1"}})
+	node := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Node"}
+	cm := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	mapper.Add(node, apimeta.RESTScopeRoot)
+	mapper.Add(cm, apimeta.RESTScopeNamespace)
+
+	if scoped, err := isClusterScoped(mapper, node); err != nil || !scoped {
+		t.Fatalf("expected Node to be cluster-scoped, got %v (%v)", scoped, err)
+	}
+	if scoped, err := isClusterScoped(mapper, cm); err != nil || scoped {
+		t.Fatalf("expected ConfigMap to be namespaced, got %v (%v)", scoped, err)
+	}
+	if _, err := isClusterScoped(mapper, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}); err == nil {
+		t.Fatalf("expected an error for an unknown kind")
+	}
+}
+
+func TestParseKillSwitchRef(t *testing.T) {
+	ns, name, err := parseKillSwitchRef("ops/lease-kill-switch")
+	if err != nil || ns != "ops" || name != "lease-kill-switch" {
+		t.Fatalf("parseKillSwitchRef = (%q, %q, %v), want (ops, lease-kill-switch, nil)", ns, name, err)
+	}
+	for _, ref := range []string{"", "name-only", "/name", "ns/", "a/b/c"} {
+		if _, _, err := parseKillSwitchRef(ref); err == nil {
+			t.Errorf("expected error for %q", ref)
+		}
+	}
+}
+
+func TestKillSwitchCheck(t *testing.T) {
+	ks := util.NewKillSwitch()
+	check := newKillSwitchCheck(ks)
+	if err := check(new(http.Request)); err == nil || !strings.Contains(err.Error(), "not read yet") {
+		t.Fatalf("expected an error until the kill switch is read, got %v", err)
+	}
+	ks.Set(false, "")
+	if err := check(new(http.Request)); err != nil {
+		t.Fatalf("expected ok while deletions are allowed, got %v", err)
+	}
+	ks.Set(true, "INC-42")
+	if err := check(new(http.Request)); err == nil || !strings.Contains(err.Error(), "INC-42") {
+		t.Fatalf("expected frozen error mentioning the reason, got %v", err)
+	}
+}
+
 func TestRun_MetricsHealthReadyStartPaths(t *testing.T) {
 	oldNew := newManager
 	oldExit := exitFn
@@ -834,6 +1076,35 @@ func TestBuildManagerOptions(t *testing.T) {
 	if !strings.Contains(opts.LeaderElectionID, "apps") || !strings.Contains(opts.LeaderElectionID, "v1") || !strings.Contains(opts.LeaderElectionID, "deployment") {
 		t.Fatalf("leaderElectionID %q does not contain group/version/kind", opts.LeaderElectionID)
 	}
+	if opts.Client.Cache == nil || len(opts.Client.Cache.DisableFor) != 1 {
+		t.Fatalf("expected ConfigMaps to be read live, got %+v", opts.Client.Cache)
+	}
+}
+
+func TestConfigMapCache(t *testing.T) {
+	if _, ok := configMapCache(types.NamespacedName{}, types.NamespacedName{}); ok {
+		t.Fatalf("expected no ConfigMap cache without watched ConfigMaps")
+	}
+
+	byObject, ok := configMapCache(
+		types.NamespacedName{Namespace: "ops", Name: "kill-switch"},
+		types.NamespacedName{Namespace: "calendars", Name: "holidays"},
+	)
+	if !ok || len(byObject.Namespaces) != 2 {
+		t.Fatalf("expected one namespace per ConfigMap, got %+v", byObject.Namespaces)
+	}
+	if got := byObject.Namespaces["ops"].FieldSelector.String(); got != "metadata.name=kill-switch" {
+		t.Fatalf("ops field selector = %q", got)
+	}
+
+	// Two ConfigMaps in one namespace cannot share a name selector
+	byObject, _ = configMapCache(
+		types.NamespacedName{Namespace: "ops", Name: "kill-switch"},
+		types.NamespacedName{Namespace: "ops", Name: "holidays"},
+	)
+	if got := byObject.Namespaces["ops"].FieldSelector; len(byObject.Namespaces) != 1 || !got.Empty() {
+		t.Fatalf("expected the whole ops namespace to be cached, got %v", got)
+	}
 }
 
 func TestNewLeaseWatcher(t *testing.T) {
@@ -924,6 +1195,26 @@ func TestParseParameters_FromFlags(t *testing.T) {
 		"-pprof-bind-address=:6061",
 		"-leader-elect=true",
 		"-leader-elect-namespace=ldns",
+		"-dry-run",
+		"-deletion-rate=30",
+		"-namespace-deletion-rate=5",
+		"-mass-expiry-threshold=20",
+		"-mass-expiry-window=15m",
+		"-deletion-propagation=Foreground",
+		"-cleanup-finalizer",
+		"-inherit-lease",
+		"-calendar-durations",
+		"-timezone=Europe/Stockholm",
+		"-business-hours=08:00-16:00",
+		"-business-days=sun-thu",
+		"-holiday-configmap=ops/holidays",
+		"-deletion-window=* 2-4 * * mon-fri",
+		"-max-renewals=3",
+		"-max-lifetime=30d",
+		"-lease-history-limit=5",
+		"-history-configmap=lease-history",
+		"-ops-namespace=lease-ops",
+		"-opt-in-selector=lease=on",
 	}
 
 	params := parseParameters()
@@ -940,6 +1231,48 @@ func TestParseParameters_FromFlags(t *testing.T) {
 	if params.LeaderElectionEnabled != true || params.LeaderElectionNamespace != "ldns" {
 		t.Fatalf("unexpected leader election flags: enabled=%v ns=%s", params.LeaderElectionEnabled, params.LeaderElectionNamespace)
 	}
+	if !params.DryRun {
+		t.Fatalf("expected dry-run to be enabled by flag")
+	}
+	if params.DeletionRate != 30 || params.NamespaceDeletionRate != 5 {
+		t.Fatalf("unexpected deletion rates: %d %d", params.DeletionRate, params.NamespaceDeletionRate)
+	}
+	if params.MassExpiryThreshold != 20 || params.MassExpiryWindow != 15*time.Minute {
+		t.Fatalf("unexpected mass expiry settings: %d%% %v", params.MassExpiryThreshold, params.MassExpiryWindow)
+	}
+	if params.DeletionPropagation != "Foreground" {
+		t.Fatalf("unexpected deletion propagation: %q", params.DeletionPropagation)
+	}
+	if !params.CleanupFinalizer {
+		t.Fatalf("expected cleanup finalizer to be enabled by flag")
+	}
+	if !params.InheritLease {
+		t.Fatalf("expected lease inheritance to be enabled by flag")
+	}
+	if !params.CalendarDurations {
+		t.Fatalf("expected calendar durations to be enabled by flag")
+	}
+	if params.Timezone != "Europe/Stockholm" {
+		t.Fatalf("unexpected timezone: %q", params.Timezone)
+	}
+	if params.BusinessHours != "08:00-16:00" || params.BusinessDays != "sun-thu" || params.HolidayConfigMap != "ops/holidays" {
+		t.Fatalf("unexpected business hours: %q %q %q", params.BusinessHours, params.BusinessDays, params.HolidayConfigMap)
+	}
+	if params.DeletionWindow != "* 2-4 * * mon-fri" {
+		t.Fatalf("unexpected deletion window: %q", params.DeletionWindow)
+	}
+	if params.MaxRenewals != 3 || params.MaxLifetime != "30d" {
+		t.Fatalf("unexpected renewal limits: %d %q", params.MaxRenewals, params.MaxLifetime)
+	}
+	if params.HistoryLimit != 5 || params.HistoryConfigMap != "lease-history" {
+		t.Fatalf("unexpected history settings: %d %q", params.HistoryLimit, params.HistoryConfigMap)
+	}
+	if params.OpsNamespace != "lease-ops" {
+		t.Fatalf("unexpected ops namespace: %q", params.OpsNamespace)
+	}
+	if params.OptInSelector != "lease=on" {
+		t.Fatalf("unexpected opt-in selector: %q", params.OptInSelector)
+	}
 }
 
 func TestParseParameters_FromEnv(t *testing.T) {
@@ -952,6 +1285,23 @@ func TestParseParameters_FromEnv(t *testing.T) {
 		"LEASE_OPT_IN_LABEL_VALUE":        os.Getenv("LEASE_OPT_IN_LABEL_VALUE"),
 		"LEASE_LEADER_ELECTION":           os.Getenv("LEASE_LEADER_ELECTION"),
 		"LEASE_LEADER_ELECTION_NAMESPACE": os.Getenv("LEASE_LEADER_ELECTION_NAMESPACE"),
+		"LEASE_DRY_RUN":                   os.Getenv("LEASE_DRY_RUN"),
+		"LEASE_DELETION_RATE":             os.Getenv("LEASE_DELETION_RATE"),
+		"LEASE_MASS_EXPIRY_WINDOW":        os.Getenv("LEASE_MASS_EXPIRY_WINDOW"),
+		"LEASE_CLEANUP_FINALIZER":         os.Getenv("LEASE_CLEANUP_FINALIZER"),
+		"LEASE_INHERIT_LEASE":             os.Getenv("LEASE_INHERIT_LEASE"),
+		"LEASE_CALENDAR_DURATIONS":        os.Getenv("LEASE_CALENDAR_DURATIONS"),
+		"LEASE_TIMEZONE":                  os.Getenv("LEASE_TIMEZONE"),
+		"LEASE_BUSINESS_HOURS":            os.Getenv("LEASE_BUSINESS_HOURS"),
+		"LEASE_BUSINESS_DAYS":             os.Getenv("LEASE_BUSINESS_DAYS"),
+		"LEASE_HOLIDAY_CONFIGMAP":         os.Getenv("LEASE_HOLIDAY_CONFIGMAP"),
+		"LEASE_DELETION_WINDOW":           os.Getenv("LEASE_DELETION_WINDOW"),
+		"LEASE_MAX_RENEWALS":              os.Getenv("LEASE_MAX_RENEWALS"),
+		"LEASE_MAX_LIFETIME":              os.Getenv("LEASE_MAX_LIFETIME"),
+		"LEASE_HISTORY_LIMIT":             os.Getenv("LEASE_HISTORY_LIMIT"),
+		"LEASE_HISTORY_CONFIGMAP":         os.Getenv("LEASE_HISTORY_CONFIGMAP"),
+		"LEASE_OPS_NAMESPACE":             os.Getenv("LEASE_OPS_NAMESPACE"),
+		"LEASE_OPT_IN_SELECTOR":           os.Getenv("LEASE_OPT_IN_SELECTOR"),
 	}
 	t.Cleanup(func() {
 		for k, v := range old {
@@ -981,6 +1331,23 @@ func TestParseParameters_FromEnv(t *testing.T) {
 	os.Setenv("LEASE_OPT_IN_LABEL_VALUE", "true")
 	os.Setenv("LEASE_LEADER_ELECTION", "true")
 	os.Setenv("LEASE_LEADER_ELECTION_NAMESPACE", "envns")
+	os.Setenv("LEASE_DRY_RUN", "true")
+	os.Setenv("LEASE_DELETION_RATE", "12")
+	os.Setenv("LEASE_MASS_EXPIRY_WINDOW", "1h")
+	os.Setenv("LEASE_CLEANUP_FINALIZER", "1")
+	os.Setenv("LEASE_INHERIT_LEASE", "true")
+	os.Setenv("LEASE_CALENDAR_DURATIONS", "1")
+	os.Setenv("LEASE_TIMEZONE", "America/New_York")
+	os.Setenv("LEASE_BUSINESS_HOURS", "07:30-15:30")
+	os.Setenv("LEASE_BUSINESS_DAYS", "mon-thu")
+	os.Setenv("LEASE_HOLIDAY_CONFIGMAP", "env-ops/holidays")
+	os.Setenv("LEASE_DELETION_WINDOW", "0-29 3 * * sat,sun")
+	os.Setenv("LEASE_MAX_RENEWALS", "5")
+	os.Setenv("LEASE_MAX_LIFETIME", "720h")
+	os.Setenv("LEASE_HISTORY_LIMIT", "0")
+	os.Setenv("LEASE_HISTORY_CONFIGMAP", "audit")
+	os.Setenv("LEASE_OPS_NAMESPACE", "env-ops")
+	os.Setenv("LEASE_OPT_IN_SELECTOR", "tier in (dev)")
 
 	// Reset flags and args
 	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
@@ -997,6 +1364,42 @@ func TestParseParameters_FromEnv(t *testing.T) {
 	if params.LeaderElectionEnabled != true || params.LeaderElectionNamespace != "envns" {
 		t.Fatalf("unexpected leader from env: %v %s", params.LeaderElectionEnabled, params.LeaderElectionNamespace)
 	}
+	if !params.DryRun {
+		t.Fatalf("expected dry-run to be enabled from env")
+	}
+	if params.DeletionRate != 12 || params.MassExpiryWindow != time.Hour {
+		t.Fatalf("unexpected rate limit settings from env: %d %v", params.DeletionRate, params.MassExpiryWindow)
+	}
+	if !params.CleanupFinalizer {
+		t.Fatalf("expected cleanup finalizer to be enabled from env")
+	}
+	if !params.InheritLease {
+		t.Fatalf("expected lease inheritance to be enabled from env")
+	}
+	if !params.CalendarDurations {
+		t.Fatalf("expected calendar durations to be enabled from env")
+	}
+	if params.Timezone != "America/New_York" {
+		t.Fatalf("unexpected timezone from env: %q", params.Timezone)
+	}
+	if params.BusinessHours != "07:30-15:30" || params.BusinessDays != "mon-thu" || params.HolidayConfigMap != "env-ops/holidays" {
+		t.Fatalf("unexpected business hours from env: %q %q %q", params.BusinessHours, params.BusinessDays, params.HolidayConfigMap)
+	}
+	if params.DeletionWindow != "0-29 3 * * sat,sun" {
+		t.Fatalf("unexpected deletion window from env: %q", params.DeletionWindow)
+	}
+	if params.MaxRenewals != 5 || params.MaxLifetime != "720h" {
+		t.Fatalf("unexpected renewal limits from env: %d %q", params.MaxRenewals, params.MaxLifetime)
+	}
+	if params.HistoryLimit != 0 || params.HistoryConfigMap != "audit" {
+		t.Fatalf("unexpected history settings from env: %d %q", params.HistoryLimit, params.HistoryConfigMap)
+	}
+	if params.OpsNamespace != "env-ops" {
+		t.Fatalf("unexpected ops namespace from env: %q", params.OpsNamespace)
+	}
+	if params.OptInSelector != "tier in (dev)" {
+		t.Fatalf("unexpected opt-in selector from env: %q", params.OptInSelector)
+	}
 
 	// metrics defaults should be present when not specified via flags
 	if params.MetricsBindAddress == "" || params.HealthProbeBindAddress == "" || params.PprofBindAddress == "" {
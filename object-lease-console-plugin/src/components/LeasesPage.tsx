@@ -5,6 +5,20 @@ import { ResourceLink, Timestamp, useK8sWatchResource, useK8sWatchResources, Res
 const ANN_TTL = 'object-lease-controller.ullberg.io/ttl';
 const ANN_EXPIRE_AT = 'object-lease-controller.ullberg.io/expire-at';
 const ANN_STATUS = 'object-lease-controller.ullberg.io/lease-status';
+const ANN_STATUS_JSON = 'object-lease-controller.ullberg.io/lease-status-json';
+
+type LeaseStatus = { phase?: string; expireAt?: string; reason?: string; message?: string };
+
+// Prefer the structured status, older controllers only set the human one
+const leaseStatus = (anns: Record<string, string>): LeaseStatus => {
+  try {
+    const parsed = JSON.parse(anns[ANN_STATUS_JSON] || '');
+    if (parsed && typeof parsed === 'object') return parsed;
+  } catch {
+    // fall through
+  }
+  return { expireAt: anns[ANN_EXPIRE_AT], message: anns[ANN_STATUS] };
+};
 
 type GVK = { group: string; version: string; kind: string };
 type WatchCfg = { groupVersionKind: GVK; namespaced: boolean; isList: true; namespace?: string };
@@ -162,6 +176,7 @@ const LeasesPage = () => {
                 <th>Name</th>
                 <th>TTL</th>
                 <th>Expires</th>
+                <th>Phase</th>
                 <th>Status</th>
               </tr>
               </thead>
@@ -169,11 +184,12 @@ const LeasesPage = () => {
             <tbody>
               {rows.length === 0 ? (
                 <tr>
-                  <td colSpan={5}>No resources with lease annotations found for this GVK.</td>
+                  <td colSpan={6}>No resources with lease annotations found for this GVK.</td>
                 </tr>
               ) : (
                 rows.map(({ obj }) => {
                   const key = `${obj.metadata.namespace || 'cluster'}-${obj.metadata.name}`;
+                  const status = leaseStatus(obj?.metadata?.annotations || {});
                   return (
                     <tr key={key}>
                       <td>{obj.metadata.namespace || '-'}</td>
@@ -181,8 +197,9 @@ const LeasesPage = () => {
                         <ResourceLink groupVersionKind={gvk} name={obj.metadata.name} namespace={obj.metadata.namespace} />
                       </td>
                       <td>{obj?.metadata?.annotations?.[ANN_TTL] ?? '-'}</td>
-                      <td><Timestamp timestamp={obj?.metadata?.annotations?.[ANN_EXPIRE_AT]} /></td>
-                      <td>{obj?.metadata?.annotations?.[ANN_STATUS] ?? '-'}</td>
+                      <td><Timestamp timestamp={status.expireAt} /></td>
+                      <td>{status.phase ?? '-'}</td>
+                      <td title={status.reason}>{status.message ?? '-'}</td>
                     </tr>
                   );
                 })
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// BreakerRequeueInterval is how often an expired lease is rechecked while the
+// mass-expiry circuit breaker is open
+const BreakerRequeueInterval = time.Minute
+
+// admitExpiry checks the deletion window, the mass-expiry circuit breaker and
+// the deletion budget before an expiry action runs. When the action has to
+// wait it returns ok=false and the result to requeue with.
+func (r *LeaseWatcher) admitExpiry(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time) (controller_runtime.Result, bool) {
+	now := time.Now().UTC()
+
+	if res, wait := r.awaitDeletionWindow(ctx, obj, expireAt, now); wait {
+		return res, false
+	}
+
+	if r.Breaker != nil {
+		if !r.Breaker.Seeded() {
+			r.seedBreaker(ctx)
+		}
+		open, due, total, changed := r.Breaker.Evaluate(now)
+		if changed {
+			r.breakerChanged(ctx, obj, open, due, total)
+		}
+		if open {
+			leaseStatus := fmt.Sprintf("Lease expired. Deletion halted by mass-expiry circuit breaker, %d of %d leases are due.", due, total)
+			return r.deferExpiry(ctx, obj, expireAt, "DeletionHalted", leaseStatus, BreakerRequeueInterval), false
+		}
+	}
+
+	if r.Budget != nil {
+		if ok, wait := r.Budget.Take(obj.GetNamespace(), now); !ok {
+			if r.Metrics != nil {
+				r.Metrics.DeletionsRateLimited.Inc()
+			}
+			leaseStatus := "Lease expired. Deletion delayed by rate limit."
+			return r.deferExpiry(ctx, obj, expireAt, "DeletionRateLimited", leaseStatus, wait), false
+		}
+	}
+	return controller_runtime.Result{}, true
+}
+
+// untrackExpiry stops tracking a lease that was removed in the circuit breaker
+func (r *LeaseWatcher) untrackExpiry(obj *unstructured.Unstructured) {
+	if r.Breaker != nil {
+		r.Breaker.Forget(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	}
+}
+
+// expiryHandled tells the circuit breaker the expiry of obj was acted on. It
+// keeps counting towards a mass expiry for the breaker's window.
+func (r *LeaseWatcher) expiryHandled(obj *unstructured.Unstructured) {
+	if r.Breaker != nil {
+		r.Breaker.Handled(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, time.Now().UTC())
+	}
+}
+
+// seedBreaker records the expiry of every leased object in the cache, so the
+// first expiries are weighed against all leases and not only the ones
+// reconciled so far. Objects whose expiry was handled already are left out.
+func (r *LeaseWatcher) seedBreaker(ctx context.Context) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   r.GVK.Group,
+		Version: r.GVK.Version,
+		Kind:    r.GVK.Kind + "List",
+	})
+	if err := r.cached().List(ctx, list); err != nil {
+		logger.FromContext(ctx).Error(err, "Unable to list leases for the mass-expiry circuit breaker")
+		return
+	}
+	expires := map[types.NamespacedName]time.Time{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if expireAt, ok := r.knownExpiry(obj); ok {
+			expires[types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}] = expireAt
+		}
+	}
+	r.Breaker.Seed(expires)
+}
+
+// knownExpiry returns the expiry of a leased object as far as it can be told
+// without reconciling it, the recorded expire-at or else its lease-start and
+// TTL
+func (r *LeaseWatcher) knownExpiry(obj *unstructured.Unstructured) (time.Time, bool) {
+	anns := obj.GetAnnotations()
+	ns := obj.GetNamespace()
+	if r.isNamespaceLease() {
+		ns = obj.GetName()
+	}
+	switch {
+	case obj.GetDeletionTimestamp() != nil, !r.selected(obj), r.noTTL(obj):
+		return time.Time{}, false
+	case r.Tracker != nil && !r.isNamespaceTracked(ns):
+		return time.Time{}, false
+	case anns[r.Annotations.Phase] == PhaseHibernated:
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, anns[r.Annotations.ExpireAt]); err == nil {
+		if r.Annotations.OnExpireApplied != "" && anns[r.Annotations.OnExpireApplied] == anns[r.Annotations.ExpireAt] {
+			return time.Time{}, false
+		}
+		return t.UTC(), true
+	}
+	if t, err := time.Parse(time.RFC3339, anns[r.Annotations.DeleteAt]); err == nil {
+		return t.UTC(), true
+	}
+	start, err := time.Parse(time.RFC3339, anns[r.Annotations.LeaseStart])
+	if err != nil {
+		return time.Time{}, false
+	}
+	expiry, err := r.leaseExpiry(obj)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiry(start.UTC()), true
+}
+
+// breakerChanged reports the circuit breaker opening or closing
+func (r *LeaseWatcher) breakerChanged(ctx context.Context, obj *unstructured.Unstructured, open bool, due, total int) {
+	log := logger.FromContext(ctx)
+	if open {
+		log.Info("Mass expiry detected, halting deletions", "due", due, "total", total)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "MassExpiryDetected", "MassExpiryDetected", "%d of %d leases are due within %s, deletions halted", due, total, r.Breaker.Window)
+		}
+		if r.Metrics != nil {
+			r.Metrics.CircuitBreakerOpen.Set(1)
+			r.Metrics.CircuitBreakerTrips.Inc()
+		}
+		return
+	}
+	log.Info("Mass expiry cleared, resuming deletions", "due", due, "total", total)
+	if r.Metrics != nil {
+		r.Metrics.CircuitBreakerOpen.Set(0)
+	}
+}
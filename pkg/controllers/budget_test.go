@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ometrics "object-lease-controller/pkg/metrics"
+	"object-lease-controller/pkg/util"
+)
+
+func newExpiredObj(gvk schema.GroupVersionKind, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "1h",
+		defaultAnn().LeaseStart: time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+	})
+	return obj
+}
+
+func TestReconcile_DeletionBudgetDelaysExpiry(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	r, cl, _ := newWatcher(t, gvk, newExpiredObj(gvk, "first"), newExpiredObj(gvk, "second"))
+	withIsolatedRegistry(t)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	r.Budget = util.NewDeletionBudget(1, 0)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "first"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	res, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "second"}})
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if res.RequeueAfter <= 0 || res.RequeueAfter > time.Minute {
+		t.Fatalf("RequeueAfter = %v, want a wait of up to a minute", res.RequeueAfter)
+	}
+
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "first"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected first object to be deleted, got %v", err)
+	}
+	if got := get(t, cl, gvk, "default", "second").GetAnnotations()[defaultAnn().Status]; got != "Lease expired. Deletion delayed by rate limit." {
+		t.Fatalf("unexpected status %q", got)
+	}
+	if n := countEvents(rec, "DeletionRateLimited"); n != 1 {
+		t.Fatalf("expected one DeletionRateLimited event, got %d", n)
+	}
+}
+
+func TestReconcile_MassExpiryOpensCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	r, cl, _ := newWatcher(t, gvk, newExpiredObj(gvk, "victim"))
+	reg := withIsolatedRegistry(t)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	r.Breaker = util.NewCircuitBreaker(0.5, 10*time.Minute)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	// Nine more leases all overdue, as after a clock jump
+	for i := 0; i < 9; i++ {
+		r.Breaker.Observe(types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("other-%d", i)}, time.Now().Add(-time.Hour))
+	}
+
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "victim"}}
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if res.RequeueAfter != BreakerRequeueInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, BreakerRequeueInterval)
+	}
+	get(t, cl, gvk, "default", "victim")
+
+	events := map[string]int{}
+	for len(rec.Events) > 0 {
+		e := <-rec.Events
+		for _, reason := range []string{"MassExpiryDetected", "DeletionHalted"} {
+			if strings.Contains(e, reason) {
+				events[reason]++
+			}
+		}
+	}
+	if events["MassExpiryDetected"] != 1 || events["DeletionHalted"] != 1 {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if v := gaugeValue(t, reg, "object_lease_controller_circuit_breaker_open"); v != 1 {
+		t.Fatalf("circuit_breaker_open = %v, want 1", v)
+	}
+
+	// The other leases get renewed, the breaker closes and the deletion proceeds
+	for i := 0; i < 9; i++ {
+		r.Breaker.Observe(types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("other-%d", i)}, time.Now().Add(time.Hour))
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound once the breaker closed, got %v", err)
+	}
+	if v := gaugeValue(t, reg, "object_lease_controller_circuit_breaker_open"); v != 0 {
+		t.Fatalf("circuit_breaker_open = %v, want 0", v)
+	}
+}
+
+func TestReconcile_MassExpiryTripsWithoutObservedLeases(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	const n = 50
+	var objs []client.Object
+	for i := 0; i < n; i++ {
+		objs = append(objs, newExpiredObj(gvk, fmt.Sprintf("lease-%d", i)))
+	}
+	r, cl, _ := newWatcher(t, gvk, objs...)
+	withIsolatedRegistry(t)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	r.Breaker = util.NewCircuitBreaker(0.5, 10*time.Minute)
+
+	// The leases expired together, as after an outage, and are reconciled one
+	// after the other
+	for i := 0; i < n; i++ {
+		reconcileName(t, r, fmt.Sprintf("lease-%d", i))
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMapList"})
+	if err := cl.List(ctx, list); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.Items) != n {
+		t.Fatalf("expected the breaker to keep all %d leases, %d are left", n, len(list.Items))
+	}
+}
+
+func TestReconcile_HandledExpiriesKeepTheBreakerOpen(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	// Ten leases, one expired, the rest expire one after the other
+	objs := []client.Object{newExpiredObj(gvk, "lease-0")}
+	for i := 1; i < 10; i++ {
+		obj := &unstructured.Unstructured{}
+		setMeta(obj, gvk, "default", fmt.Sprintf("lease-%d", i))
+		obj.SetAnnotations(map[string]string{
+			defaultAnn().TTL:        "1h",
+			defaultAnn().LeaseStart: time.Now().UTC().Add(-30 * time.Minute).Format(time.RFC3339),
+		})
+		objs = append(objs, obj)
+	}
+	r, cl, _ := newWatcher(t, gvk, objs...)
+	withIsolatedRegistry(t)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	r.Breaker = util.NewCircuitBreaker(0.5, 10*time.Minute)
+
+	deleted := 0
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("lease-%d", i)
+		if i > 0 {
+			// The lease expires just now
+			u := get(t, cl, gvk, "default", name)
+			anns := u.GetAnnotations()
+			anns[defaultAnn().LeaseStart] = time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339)
+			u.SetAnnotations(anns)
+			if err := cl.Update(ctx, u); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+		}
+		reconcileName(t, r, name)
+		out := &unstructured.Unstructured{}
+		out.SetGroupVersionKind(gvk)
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, out); apierrors.IsNotFound(err) {
+			deleted++
+		}
+	}
+	if deleted != 5 {
+		t.Fatalf("expected deletions to halt after half the leases, %d were deleted", deleted)
+	}
+}
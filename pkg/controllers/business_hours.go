@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"object-lease-controller/pkg/util"
+)
+
+// leaseClock returns the lease clock requested by the object, falling back to the wall clock
+func (r *LeaseWatcher) leaseClock(obj *unstructured.Unstructured) util.LeaseClock {
+	if r.Annotations.LeaseClock == "" {
+		return util.LeaseClockWall
+	}
+	clock, _ := util.ParseLeaseClock(obj.GetAnnotations()[r.Annotations.LeaseClock])
+	return clock
+}
+
+func (r *LeaseWatcher) businessClock() *util.BusinessClock {
+	if r.BusinessClock != nil {
+		return r.BusinessClock
+	}
+	return util.DefaultBusinessClock()
+}
+
+// businessExpiry returns the expiry of a TTL counted in business hours in loc
+func (r *LeaseWatcher) businessExpiry(ttl string, loc *time.Location) (func(time.Time) time.Time, error) {
+	d, err := util.ParseFlexibleDuration(ttl)
+	if err != nil {
+		return nil, err
+	}
+	clock := r.businessClock()
+	return func(start time.Time) time.Time { return clock.Add(start, d, loc) }, nil
+}
+
+// remainingOnClock returns the time left until expireAt on the lease clock of
+// obj, and the function that converts a wait on that clock to wall-clock time.
+// Business-hours leases wake up each time business hours open or close, so
+// holidays added or removed in the meantime are taken into account.
+func (r *LeaseWatcher) remainingOnClock(obj *unstructured.Unstructured, expireAt, now time.Time) (time.Duration, func(time.Duration) time.Duration) {
+	loc, err := r.leaseLocation(obj)
+	if r.leaseClock(obj) != util.LeaseClockBusinessHours || err != nil || util.IsDeadlineExpression(obj.GetAnnotations()[r.Annotations.TTL]) {
+		return expireAt.Sub(now), func(d time.Duration) time.Duration { return d }
+	}
+	clock := r.businessClock()
+	return clock.Between(now, expireAt, loc), func(d time.Duration) time.Duration {
+		// No business time left before a delete-at outside business hours
+		if d <= 0 {
+			return expireAt.Sub(now)
+		}
+		wake := clock.Add(now, d, loc)
+		if change := clock.NextChange(now, loc); change.Before(wake) {
+			wake = change
+		}
+		return wake.Sub(now)
+	}
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"object-lease-controller/pkg/util"
+)
+
+const testLeaseClock = "object-lease-controller.ullberg.io/lease-clock"
+
+func TestReconcile_BusinessHoursLeaseSkipsNightsWeekendsAndHolidays(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	// Friday 16:00, with Monday off
+	business := &unstructured.Unstructured{}
+	setMeta(business, gvk, "default", "business")
+	business.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "2h",
+		defaultAnn().LeaseStart: "2099-01-09T16:00:00Z",
+		testLeaseClock:          "business-hours",
+	})
+	wall := &unstructured.Unstructured{}
+	setMeta(wall, gvk, "default", "wall")
+	wall.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "2h",
+		defaultAnn().LeaseStart: "2099-01-09T16:00:00Z",
+		testLeaseClock:          "office",
+	})
+	r, cl, _ := newWatcher(t, gvk, business, wall)
+	r.Annotations.LeaseClock = testLeaseClock
+	clock, err := util.NewBusinessClock(util.DefaultBusinessHours, util.DefaultBusinessDays)
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	clock.SetHolidays([]string{"2099-01-12"})
+	r.BusinessClock = clock
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	reconcileName(t, r, "business")
+	if got := get(t, cl, gvk, "default", "business").GetAnnotations()[defaultAnn().ExpireAt]; got != "2099-01-13T10:00:00Z" {
+		t.Fatalf("expire-at = %q, want Tuesday 10:00", got)
+	}
+	reconcileName(t, r, "wall")
+	if got := get(t, cl, gvk, "default", "wall").GetAnnotations()[defaultAnn().ExpireAt]; got != "2099-01-09T18:00:00Z" {
+		t.Fatalf("expire-at = %q, want the wall clock fallback", got)
+	}
+	if n := countEvents(rec, "InvalidLeaseClock"); n != 1 {
+		t.Fatalf("expected 1 InvalidLeaseClock event, got %d", n)
+	}
+}
+
+func TestRemainingOnClock_BusinessHoursWakeAtEachChange(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, _ := newWatcher(t, gvk)
+	r.Annotations.LeaseClock = testLeaseClock
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "business")
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "16h", testLeaseClock: "business-hours"})
+
+	// Friday 10:00 until Tuesday 10:00 is 16 business hours
+	now := time.Date(2099, time.January, 9, 10, 0, 0, 0, time.UTC)
+	expireAt := time.Date(2099, time.January, 13, 10, 0, 0, 0, time.UTC)
+	remaining, toWall := r.remainingOnClock(obj, expireAt, now)
+	if remaining != 16*time.Hour {
+		t.Fatalf("remaining = %s, want 16h", remaining)
+	}
+	// The next wake-up is when business hours close on Friday
+	if got := toWall(remaining); got != 7*time.Hour {
+		t.Fatalf("requeue = %s, want 7h", got)
+	}
+
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "16h"})
+	if remaining, toWall := r.remainingOnClock(obj, expireAt, now); remaining != 96*time.Hour || toWall(time.Hour) != time.Hour {
+		t.Fatalf("expected wall-clock time for a wall-clock lease, got %s", remaining)
+	}
+}
+
+func TestReconcile_BusinessDeadlineFollowsTheBusinessClock(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	// Friday 18:00, with Monday off
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "cob")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "end of business day",
+		defaultAnn().LeaseStart: "2099-01-09T18:00:00Z",
+	})
+	r, cl, _ := newWatcher(t, gvk, obj)
+	clock, err := util.NewBusinessClock("08:00-16:00", util.DefaultBusinessDays)
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	clock.SetHolidays([]string{"2099-01-12"})
+	r.BusinessClock = clock
+
+	reconcileName(t, r, "cob")
+	if got := get(t, cl, gvk, "default", "cob").GetAnnotations()[defaultAnn().ExpireAt]; got != "2099-01-13T16:00:00Z" {
+		t.Fatalf("expire-at = %q, want Tuesday 16:00", got)
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+
+	"object-lease-controller/pkg/util"
+)
+
+// maxHolidaysSkipped bounds how many holidays are skipped while looking for
+// the next deletion window
+const maxHolidaysSkipped = 1000
+
+// awaitDeletionWindow holds an expired object until its deletion window is
+// open. Returns true while the expiry action has to wait.
+func (r *LeaseWatcher) awaitDeletionWindow(ctx context.Context, obj *unstructured.Unstructured, expireAt, now time.Time) (controller_runtime.Result, bool) {
+	window := r.DeletionWindow
+	if spec := obj.GetAnnotations()[r.Annotations.DeletionWindow]; r.Annotations.DeletionWindow != "" && spec != "" {
+		w, err := util.ParseDeletionWindow(spec)
+		if err != nil {
+			r.markInvalid(ctx, obj, "InvalidDeletionWindow", fmt.Sprintf("Invalid deletion-window: %v", err))
+			return controller_runtime.Result{}, true
+		}
+		window = w
+	}
+	if window == nil {
+		return controller_runtime.Result{}, false
+	}
+
+	loc, err := r.leaseLocation(obj)
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidTimezone", fmt.Sprintf("Invalid timezone: %v", err))
+		return controller_runtime.Result{}, true
+	}
+	opens, ok := r.nextDeletionWindow(window, now, loc)
+	if !ok {
+		r.markInvalid(ctx, obj, "InvalidDeletionWindow", "Invalid deletion-window: it never opens outside holidays")
+		return controller_runtime.Result{}, true
+	}
+	if !opens.After(now) {
+		return controller_runtime.Result{}, false
+	}
+	leaseStatus := fmt.Sprintf("Lease expired. Deletion pending until the deletion window opens at %s UTC.", opens.Format(time.RFC3339))
+	return r.deferExpiry(ctx, obj, expireAt, "ExpiredPendingWindow", leaseStatus, opens.Sub(now)), true
+}
+
+// nextDeletionWindow returns when window is next open from now on, skipping
+// holidays
+func (r *LeaseWatcher) nextDeletionWindow(window *util.DeletionWindow, now time.Time, loc *time.Location) (time.Time, bool) {
+	clock := r.businessClock()
+	from := now
+	for i := 0; i < maxHolidaysSkipped; i++ {
+		opens, ok := window.Next(from, loc)
+		if !ok || !clock.IsHoliday(opens, loc) {
+			return opens, ok
+		}
+		y, m, d := opens.In(loc).Date()
+		from = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"object-lease-controller/pkg/util"
+)
+
+const testDeletionWindow = "object-lease-controller.ullberg.io/deletion-window"
+
+func setDeletionWindow(obj *unstructured.Unstructured, spec string) {
+	anns := obj.GetAnnotations()
+	anns[testDeletionWindow] = spec
+	obj.SetAnnotations(anns)
+}
+
+func TestReconcile_DeletionWindowDefersExpiry(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	// A window two hours from now is closed, one that is always open is not
+	closed := newExpiredObj(gvk, "closed")
+	setDeletionWindow(closed, fmt.Sprintf("* %d * * *", (time.Now().UTC().Hour()+2)%24))
+	open := newExpiredObj(gvk, "open")
+	setDeletionWindow(open, "* * * * *")
+	invalid := newExpiredObj(gvk, "invalid")
+	setDeletionWindow(invalid, "weekdays 02:00-05:00")
+	r, cl := newStatusWatcher(t, closed, open, invalid)
+	r.Annotations.DeletionWindow = testDeletionWindow
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	res := reconcileName(t, r, "closed")
+	if res.RequeueAfter <= time.Hour || res.RequeueAfter > 2*time.Hour {
+		t.Fatalf("RequeueAfter = %v, want the wait for the window", res.RequeueAfter)
+	}
+	status := leaseStatusOf(t, cl, "closed")
+	if status.Reason != "ExpiredPendingWindow" || !strings.Contains(status.Message, "deletion window opens") {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if n := countEvents(rec, "ExpiredPendingWindow"); n != 1 {
+		t.Fatalf("expected one ExpiredPendingWindow event, got %d", n)
+	}
+	reconcileName(t, r, "closed")
+	if n := countEvents(rec, "ExpiredPendingWindow"); n != 0 {
+		t.Fatalf("expected no repeated event, got %d", n)
+	}
+
+	reconcileName(t, r, "open")
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "open"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the object to be deleted inside its window, got %v", err)
+	}
+
+	reconcileName(t, r, "invalid")
+	if status := leaseStatusOf(t, cl, "invalid"); status.Reason != "InvalidDeletionWindow" {
+		t.Fatalf("reason = %q, want InvalidDeletionWindow", status.Reason)
+	}
+}
+
+func TestReconcile_ControllerDeletionWindow(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl, _ := newWatcher(t, gvk, newExpiredObj(gvk, "kept"))
+	window, err := util.ParseDeletionWindow(fmt.Sprintf("* %d * * *", (time.Now().UTC().Hour()+2)%24))
+	if err != nil {
+		t.Fatalf("ParseDeletionWindow error: %v", err)
+	}
+	r.DeletionWindow = window
+
+	reconcileName(t, r, "kept")
+	if got := get(t, cl, gvk, "default", "kept").GetAnnotations()[defaultAnn().Status]; !strings.HasPrefix(got, "Lease expired. Deletion pending") {
+		t.Fatalf("unexpected status %q", got)
+	}
+}
+
+func TestNextDeletionWindow_SkipsHolidays(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, _ := newWatcher(t, gvk)
+	clock, err := util.NewBusinessClock("", "")
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	clock.SetHolidays([]string{"2025-06-13", "2025-06-16"})
+	r.BusinessClock = clock
+	window, err := util.ParseDeletionWindow("* 2-4 * * mon-fri")
+	if err != nil {
+		t.Fatalf("ParseDeletionWindow error: %v", err)
+	}
+
+	// Thursday after the window, Friday and Monday are holidays
+	now := time.Date(2025, time.June, 12, 6, 0, 0, 0, time.UTC)
+	opens, ok := r.nextDeletionWindow(window, now, time.UTC)
+	if want := time.Date(2025, time.June, 17, 2, 0, 0, 0, time.UTC); !ok || !opens.Equal(want) {
+		t.Fatalf("next window = (%s, %v), want %s", opens, ok, want)
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"object-lease-controller/pkg/util"
+)
+
+// ExpireWithIndex indexes leased objects by the object they expire with
+const ExpireWithIndex = "metadata.annotations.expire-with"
+
+// dependencyWatches starts one watch per kind referenced by expire-with,
+// and per owner kind leases are inherited from, once the controller is running
+type dependencyWatches struct {
+	mu         sync.Mutex
+	controller controller.Controller
+	cache      cache.Cache
+	watched    map[dependencyWatch]bool
+}
+
+// dependencyWatch is a watched kind and whether it is watched as an owner
+type dependencyWatch struct {
+	gvk   schema.GroupVersionKind
+	owner bool
+}
+
+// expireWithRef returns the object obj expires with, if it names one
+func (r *LeaseWatcher) expireWithRef(obj client.Object) (util.ObjectRef, bool, error) {
+	v := obj.GetAnnotations()[r.Annotations.ExpireWith]
+	if r.Annotations.ExpireWith == "" || v == "" {
+		return util.ObjectRef{}, false, nil
+	}
+	ref, err := util.ParseObjectRef(v, obj.GetNamespace())
+	return ref, true, err
+}
+
+// expireWithIndexValue extracts the ExpireWithIndex key of a leased object
+func (r *LeaseWatcher) expireWithIndexValue(obj client.Object) []string {
+	ref, ok, err := r.expireWithRef(obj)
+	if !ok || err != nil {
+		return nil
+	}
+	return []string{ref.String()}
+}
+
+// dependencyExpiry ends the lease early when the object it expires with is
+// gone or being deleted. Returns false if expire-with is invalid, in which
+// case the object is marked and left alone.
+func (r *LeaseWatcher) dependencyExpiry(ctx context.Context, obj *unstructured.Unstructured, expireAt, now time.Time) (time.Time, bool, error) {
+	ref, ok, err := r.expireWithRef(obj)
+	if !ok {
+		return expireAt, true, nil
+	}
+	if err == nil {
+		err = r.watchDependency(ref.GVK)
+	}
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidExpireWith", fmt.Sprintf("Invalid expire-with: %v", err))
+		return expireAt, false, nil
+	}
+
+	dep := &unstructured.Unstructured{}
+	dep.SetGroupVersionKind(ref.GVK)
+	err = r.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, dep)
+	switch {
+	case meta.IsNoMatchError(err):
+		r.markInvalid(ctx, obj, "InvalidExpireWith", fmt.Sprintf("Invalid expire-with: %v", err))
+		return expireAt, false, nil
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return expireAt, false, err
+	case dep.GetDeletionTimestamp() == nil:
+		return expireAt, true, nil
+	}
+
+	// The expiry recorded when the dependency was first found gone is kept, so
+	// the expiry stays the same across reconciles
+	goneAt := now.Truncate(time.Second)
+	if t, err := time.Parse(time.RFC3339, obj.GetAnnotations()[r.Annotations.ExpireAt]); err == nil && !t.After(now) {
+		goneAt = t.UTC()
+	}
+	if !goneAt.Before(expireAt) {
+		return expireAt, true, nil
+	}
+	if expireAt.After(now) && r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "DependencyGone", "DependencyGone", "%s is gone, the lease expires with it", ref)
+	}
+	return goneAt, true, nil
+}
+
+// watchDependency makes sure deletions of gvk objects are mapped back to the
+// leased objects that expire with them
+func (r *LeaseWatcher) watchDependency(gvk schema.GroupVersionKind) error {
+	return r.dependencies.watch(dependencyWatch{gvk: gvk}, handler.EnqueueRequestsFromMapFunc(r.dependentsOf), dependencyGone())
+}
+
+// watch starts the watch w unless it is already running
+func (d *dependencyWatches) watch(w dependencyWatch, h handler.EventHandler, p predicate.Predicate) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.controller == nil || d.watched[w] {
+		return nil
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(w.gvk)
+	src := source.Kind(d.cache, client.Object(obj), h, p)
+	if err := d.controller.Watch(src); err != nil {
+		return err
+	}
+	if d.watched == nil {
+		d.watched = map[dependencyWatch]bool{}
+	}
+	d.watched[w] = true
+	return nil
+}
+
+// dependencyGone lets deletions of referenced objects through
+func dependencyGone() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetDeletionTimestamp() == nil && e.ObjectNew.GetDeletionTimestamp() != nil
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	}
+}
+
+// dependentsOf maps a referenced object to the leased objects that expire with it
+func (r *LeaseWatcher) dependentsOf(ctx context.Context, obj client.Object) []reconcile.Request {
+	key := util.ObjectRef{GVK: obj.GetObjectKind().GroupVersionKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   r.GVK.Group,
+		Version: r.GVK.Version,
+		Kind:    r.GVK.Kind + "List",
+	})
+	// Only the informer cache has the index, the API server rejects the field
+	if err := r.cached().List(ctx, list, client.MatchingFields{ExpireWithIndex: key.String()}); err != nil {
+		logger.FromContext(ctx).Error(err, "Unable to list objects that expire with", "object", key.String())
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return reqs
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testExpireWith = "object-lease-controller.ullberg.io/expire-with"
+
+func newDependencyWatcher(t *testing.T, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, scheme := newWatcher(t, gvk)
+	r.Annotations.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	r.Annotations.ExpireWith = testExpireWith
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r.Client = cl
+	// Like the API server, the client has no expire-with index, only the
+	// informer cache has
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	cached := make([]client.Object, len(objs))
+	for i, o := range objs {
+		cached[i] = o.DeepCopyObject().(client.Object)
+	}
+	r.reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cached...).
+		WithIndex(obj, ExpireWithIndex, r.expireWithIndexValue).Build()
+	return r, cl
+}
+
+func expiresWith(gvk schema.GroupVersionKind, name, ref string) *unstructured.Unstructured {
+	a := defaultAnn()
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	obj.SetAnnotations(map[string]string{
+		a.TTL:          "1h",
+		a.LeaseStart:   time.Now().UTC().Format(time.RFC3339),
+		testExpireWith: ref,
+	})
+	return obj
+}
+
+func TestReconcile_ExpiresWithDeletedDependency(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl := newDependencyWatcher(t, expiresWith(gvk, "child", "ConfigMap.v1/parent"))
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	reconcileName(t, r, "child")
+
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "child"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected child to be deleted with its dependency, got %v", err)
+	}
+	if n := countEvents(rec, "DependencyGone"); n != 1 {
+		t.Fatalf("expected one DependencyGone event, got %d", n)
+	}
+}
+
+func TestReconcile_ExistingDependencyKeepsLease(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	parent := &unstructured.Unstructured{}
+	setMeta(parent, gvk, "default", "parent")
+	r, cl := newDependencyWatcher(t, parent, expiresWith(gvk, "child", "ConfigMap.v1/default/parent"))
+
+	res := reconcileName(t, r, "child")
+	if res.RequeueAfter <= 0 {
+		t.Fatalf("expected a requeue for the lease expiry, got %v", res.RequeueAfter)
+	}
+	if phase := get(t, cl, gvk, "default", "child").GetAnnotations()[r.Annotations.Phase]; phase != PhaseActive {
+		t.Fatalf("phase = %q, want %q", phase, PhaseActive)
+	}
+}
+
+func TestReconcile_InvalidExpireWithIsReported(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl := newDependencyWatcher(t, expiresWith(gvk, "child", "parent"))
+
+	reconcileName(t, r, "child")
+
+	got := get(t, cl, gvk, "default", "child").GetAnnotations()
+	if !strings.Contains(got[r.Annotations.Status], "Invalid expire-with") {
+		t.Fatalf("unexpected status %q", got[r.Annotations.Status])
+	}
+}
+
+func TestDependentsOf_ReturnsObjectsThatExpireWith(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _ := newDependencyWatcher(t,
+		expiresWith(gvk, "a", "ConfigMap.v1/parent"),
+		expiresWith(gvk, "b", "ConfigMap.v1/default/parent"),
+		expiresWith(gvk, "c", "ConfigMap.v1/other"),
+	)
+
+	parent := &unstructured.Unstructured{}
+	setMeta(parent, gvk, "default", "parent")
+	reqs := r.dependentsOf(context.Background(), parent)
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 dependents, got %v", reqs)
+	}
+	for _, req := range reqs {
+		if req.Name != "a" && req.Name != "b" {
+			t.Fatalf("unexpected dependent %v", req.NamespacedName)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// wouldExpire records what would have happened to an expired object when the
+// controller runs in dry-run mode. The object is left untouched apart from
+// its lease annotations, and no cleanup job is created.
+func (r *LeaseWatcher) wouldExpire(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time, actionName string) {
+	// Report each expiry once
+	if r.Annotations.WouldExpire != "" && obj.GetAnnotations()[r.Annotations.WouldExpire] == expireAt.Format(time.RFC3339) {
+		return
+	}
+
+	leaseStatus := fmt.Sprintf("Lease expired. Dry run, would apply on-expire action %s.", actionName)
+	r.setStatus(ctx, obj, "LeaseWouldExpire", leaseStatus, map[string]string{
+		r.Annotations.ExpireAt:    expireAt.Format(time.RFC3339),
+		r.Annotations.WouldExpire: expireAt.Format(time.RFC3339),
+	})
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseWouldExpire", "LeaseWouldExpire", "%s", leaseStatus)
+	}
+	r.recordHistory(ctx, obj, "LeaseWouldExpire", leaseStatus, "")
+	if r.Metrics != nil {
+		r.Metrics.LeasesWouldExpire.Inc()
+	}
+}
+
+// clearWouldExpire removes the dry-run marker once the lease has been renewed
+func (r *LeaseWatcher) clearWouldExpire(ctx context.Context, obj *unstructured.Unstructured) {
+	r.removeAnnotations(ctx, obj, r.Annotations.WouldExpire)
+}
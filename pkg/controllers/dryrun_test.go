@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ometrics "object-lease-controller/pkg/metrics"
+)
+
+func dryRunAnn() Annotations {
+	a := defaultAnn()
+	a.WouldExpire = "object-lease-controller.ullberg.io/would-expire"
+	a.OnDeleteJob = testOnDeleteJob
+	return a
+}
+
+func TestReconcile_DryRunDoesNotDeleteOrRunJobs(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := dryRunAnn()
+
+	start := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Second)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "dry")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:         "1h",
+		a.LeaseStart:  start.Format(time.RFC3339),
+		a.OnDeleteJob: "scripts-cm/cleanup.sh",
+	})
+
+	r, _, scheme := newWatcher(t, gvk, obj)
+	_ = batchv1.AddToScheme(scheme)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	r.Client = cl
+	r.Annotations = a
+	r.DryRun = true
+	reg := withIsolatedRegistry(t)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "dry"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile error: %v", err)
+		}
+	}
+
+	got := get(t, cl, gvk, "default", "dry").GetAnnotations()
+	if want := start.Add(time.Hour).Format(time.RFC3339); got[a.WouldExpire] != want {
+		t.Fatalf("would-expire = %q, want %q", got[a.WouldExpire], want)
+	}
+	if !strings.Contains(got[a.Status], "Dry run") {
+		t.Fatalf("unexpected status %q", got[a.Status])
+	}
+	if n := countEvents(rec, "LeaseWouldExpire"); n != 1 {
+		t.Fatalf("expected one LeaseWouldExpire event, got %d", n)
+	}
+
+	jl := &batchv1.JobList{}
+	if err := cl.List(ctx, jl, client.InNamespace("default")); err != nil {
+		t.Fatalf("list jobs failed: %v", err)
+	}
+	if len(jl.Items) != 0 {
+		t.Fatalf("expected no cleanup jobs in dry-run mode, got %d", len(jl.Items))
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, mf := range mfs {
+		switch mf.GetName() {
+		case "object_lease_controller_leases_would_expire_total":
+			if v := mf.Metric[0].GetCounter().GetValue(); v != 1 {
+				t.Fatalf("leases_would_expire_total = %v, want 1", v)
+			}
+		case "object_lease_controller_leases_expired_total":
+			if v := mf.Metric[0].GetCounter().GetValue(); v != 0 {
+				t.Fatalf("leases_expired_total = %v, want 0 in dry-run mode", v)
+			}
+		}
+	}
+}
+
+func TestReconcile_DryRunMarkerClearedOnRenew(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := dryRunAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "renewed")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:         "1h",
+		a.LeaseStart:  time.Now().UTC().Format(time.RFC3339),
+		a.WouldExpire: time.Now().UTC().Add(-time.Hour).Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = a
+	r.DryRun = true
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "renewed"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if _, ok := get(t, cl, gvk, "default", "renewed").GetAnnotations()[a.WouldExpire]; ok {
+		t.Fatalf("expected would-expire to be removed after renewal")
+	}
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"object-lease-controller/pkg/util"
+)
+
+const (
+	// CleanupFinalizerName holds back the deletion of a leased object until its
+	// cleanup job has run
+	CleanupFinalizerName = "object-lease-controller.ullberg.io/cleanup"
+	// CleanupPollInterval is how often a running cleanup job is checked
+	CleanupPollInterval = 5 * time.Second
+)
+
+// hasCleanupFinalizer reports whether obj carries the cleanup finalizer
+func hasCleanupFinalizer(obj *unstructured.Unstructured) bool {
+	return controllerutil.ContainsFinalizer(obj, CleanupFinalizerName)
+}
+
+// syncCleanupFinalizer adds the cleanup finalizer to leased objects with an
+// on-delete-job and removes it once the lease or the job is gone.
+func (r *LeaseWatcher) syncCleanupFinalizer(ctx context.Context, obj *unstructured.Unstructured) error {
+	want := r.CleanupFinalizer && !r.DryRun && r.Annotations.CleanupJobName != "" &&
+		!r.noTTL(obj) && r.Annotations.OnDeleteJob != "" && obj.GetAnnotations()[r.Annotations.OnDeleteJob] != ""
+	if want == hasCleanupFinalizer(obj) {
+		return nil
+	}
+	base := obj.DeepCopy()
+	if want {
+		controllerutil.AddFinalizer(obj, CleanupFinalizerName)
+	} else {
+		controllerutil.RemoveFinalizer(obj, CleanupFinalizerName)
+	}
+	return r.patchFinalizers(ctx, obj, base)
+}
+
+// patchFinalizers writes the finalizers of obj. The optimistic lock keeps
+// finalizers that others changed in the meantime.
+func (r *LeaseWatcher) patchFinalizers(ctx context.Context, obj, base *unstructured.Unstructured) error {
+	if err := r.Patch(ctx, obj, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+		obj.SetFinalizers(base.GetFinalizers())
+		return client.IgnoreNotFound(err)
+	}
+	return nil
+}
+
+// finalizeCleanup runs the cleanup job for an object that is being deleted,
+// by the lease or by hand. The finalizer is released when the job has
+// finished or job-timeout has passed since the deletion started.
+func (r *LeaseWatcher) finalizeCleanup(ctx context.Context, obj *unstructured.Unstructured) (controller_runtime.Result, error) {
+	log := logger.FromContext(ctx)
+	anns := obj.GetAnnotations()
+
+	config, err := r.cleanupJobConfig(obj)
+	if err != nil {
+		log.Error(err, "Invalid cleanup job configuration")
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "CleanupJobConfigInvalid", "CleanupJobConfigInvalid", "Invalid cleanup job config: %v", err)
+		}
+		return r.releaseCleanupFinalizer(ctx, obj)
+	}
+	if config == nil || r.DryRun || r.Annotations.CleanupJobName == "" {
+		return r.releaseCleanupFinalizer(ctx, obj)
+	}
+
+	jobName := anns[r.Annotations.CleanupJobName]
+	if jobName == "" {
+		job, err := r.createCleanupJob(ctx, obj, config, r.deletedLeaseExpiry(obj))
+		if err != nil {
+			log.Error(err, "Cleanup job execution failed")
+			r.cleanupJobFailed(obj, err)
+			return r.releaseCleanupFinalizer(ctx, obj)
+		}
+		r.setStatus(ctx, obj, "CleanupJobRunning", fmt.Sprintf("Object deleted. Waiting for cleanup job %s.", job.Name), map[string]string{
+			r.Annotations.CleanupJobName: job.Name,
+		})
+		return controller_runtime.Result{RequeueAfter: CleanupPollInterval}, nil
+	}
+
+	// A job that is not found yet may still be on its way into the cache, it
+	// is treated as running until the timeout
+	job := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: config.Namespace, Name: jobName}, job)
+	if client.IgnoreNotFound(err) != nil {
+		return controller_runtime.Result{}, err
+	}
+	if !apierrors.IsNotFound(err) {
+		if done, jobErr := util.JobFinished(job); done {
+			if jobErr != nil {
+				r.cleanupJobFailed(obj, jobErr)
+			} else {
+				log.Info("Cleanup job completed successfully", "job", job.Name)
+				if r.Recorder != nil {
+					r.Recorder.Eventf(obj, nil, "Normal", "CleanupJobCompleted", "CleanupJobCompleted", "Cleanup job completed: %s", job.Name)
+				}
+				if r.Metrics != nil {
+					r.Metrics.CleanupJobsCompleted.Inc()
+					r.Metrics.CleanupJobDuration.Observe(time.Since(job.CreationTimestamp.Time).Seconds())
+				}
+			}
+			return r.releaseCleanupFinalizer(ctx, obj)
+		}
+	}
+
+	if waited := time.Since(obj.GetDeletionTimestamp().Time); waited >= config.Timeout {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "CleanupJobTimeout", "CleanupJobTimeout", "Cleanup job %s did not complete within %s, releasing the finalizer", jobName, util.FormatFlexibleDuration(config.Timeout))
+		}
+		if r.Metrics != nil {
+			r.Metrics.CleanupJobsFailed.Inc()
+		}
+		return r.releaseCleanupFinalizer(ctx, obj)
+	}
+	return controller_runtime.Result{RequeueAfter: CleanupPollInterval}, nil
+}
+
+// releaseCleanupFinalizer removes the cleanup finalizer so the deletion can
+// finish, then keeps following deletions the controller started
+func (r *LeaseWatcher) releaseCleanupFinalizer(ctx context.Context, obj *unstructured.Unstructured) (controller_runtime.Result, error) {
+	base := obj.DeepCopy()
+	controllerutil.RemoveFinalizer(obj, CleanupFinalizerName)
+	if err := r.patchFinalizers(ctx, obj, base); err != nil {
+		return controller_runtime.Result{}, err
+	}
+	if len(obj.GetFinalizers()) == 0 {
+		// Nothing holds the object any more, it is gone
+		return controller_runtime.Result{}, nil
+	}
+	return r.awaitDeletion(ctx, obj), nil
+}
+
+func (r *LeaseWatcher) cleanupJobFailed(obj *unstructured.Unstructured, err error) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", "CleanupJobFailed", "CleanupJobFailed", "Cleanup job failed: %v", err)
+	}
+	if r.Metrics != nil {
+		r.Metrics.CleanupJobsFailed.Inc()
+	}
+}
+
+// deletedLeaseExpiry is the expiry passed to the cleanup job of a deleted
+// object: the recorded expire-at, or the deletion time for manual deletes
+// before the lease expired
+func (r *LeaseWatcher) deletedLeaseExpiry(obj *unstructured.Unstructured) time.Time {
+	deletedAt := obj.GetDeletionTimestamp().UTC()
+	if t, err := time.Parse(time.RFC3339, obj.GetAnnotations()[r.Annotations.ExpireAt]); err == nil && t.Before(deletedAt) {
+		return t.UTC()
+	}
+	return deletedAt
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"object-lease-controller/pkg/util"
+)
+
+func finalizerAnn() Annotations {
+	a := defaultAnn()
+	a.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	a.OnDeleteJob = testOnDeleteJob
+	a.JobTimeout = "object-lease-controller.ullberg.io/job-timeout"
+	a.CleanupJobName = "object-lease-controller.ullberg.io/cleanup-job-name"
+	return a
+}
+
+func newFinalizerWatcher(t *testing.T, obj *unstructured.Unstructured) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	r, _, scheme := newWatcher(t, obj.GroupVersionKind())
+	_ = batchv1.AddToScheme(scheme)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	r.Client = cl
+	r.Annotations = finalizerAnn()
+	r.CleanupFinalizer = true
+	return r, cl
+}
+
+func leasedWithJob(gvk schema.GroupVersionKind, name string) *unstructured.Unstructured {
+	a := finalizerAnn()
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	obj.SetAnnotations(map[string]string{
+		a.TTL:         "1h",
+		a.LeaseStart:  time.Now().UTC().Format(time.RFC3339),
+		a.OnDeleteJob: "scripts-cm/cleanup.sh",
+		a.JobTimeout:  "10m",
+	})
+	return obj
+}
+
+func listJobs(t *testing.T, cl client.Client) []batchv1.Job {
+	t.Helper()
+	jl := &batchv1.JobList{}
+	if err := cl.List(context.Background(), jl, client.InNamespace("default")); err != nil {
+		t.Fatalf("list jobs failed: %v", err)
+	}
+	return jl.Items
+}
+
+func TestReconcile_CleanupFinalizerRunsJobOnManualDelete(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := finalizerAnn()
+
+	r, cl := newFinalizerWatcher(t, leasedWithJob(gvk, "manual"))
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "manual"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	cur := get(t, cl, gvk, "default", "manual")
+	if fs := cur.GetFinalizers(); len(fs) != 1 || fs[0] != CleanupFinalizerName {
+		t.Fatalf("expected the cleanup finalizer, got %v", fs)
+	}
+
+	// Deleted by hand long before the lease expires
+	if err := cl.Delete(ctx, cur); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if res.RequeueAfter != CleanupPollInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, CleanupPollInterval)
+	}
+	jobs := listJobs(t, cl)
+	if len(jobs) != 1 {
+		t.Fatalf("expected one cleanup job, got %d", len(jobs))
+	}
+	if got := get(t, cl, gvk, "default", "manual").GetAnnotations()[a.CleanupJobName]; got != jobs[0].Name {
+		t.Fatalf("cleanup-job-name = %q, want %q", got, jobs[0].Name)
+	}
+
+	// Still running, the object is held and no second job is created
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if n := len(listJobs(t, cl)); n != 1 {
+		t.Fatalf("expected a single cleanup job, got %d", n)
+	}
+
+	job := jobs[0]
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := cl.Status().Update(ctx, &job); err != nil {
+		t.Fatalf("update job error: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the object to be gone once the job completed, got %v", err)
+	}
+	if n := countEvents(rec, "CleanupJobCompleted"); n != 1 {
+		t.Fatalf("expected one CleanupJobCompleted event, got %d", n)
+	}
+}
+
+func TestReconcile_CleanupFinalizerReleasedAfterTimeout(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := finalizerAnn()
+
+	obj := leasedWithJob(gvk, "slow")
+	anns := obj.GetAnnotations()
+	anns[a.JobTimeout] = "0s"
+	obj.SetAnnotations(anns)
+	obj.SetFinalizers([]string{CleanupFinalizerName})
+	r, cl := newFinalizerWatcher(t, obj)
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "slow"}}
+
+	if err := cl.Delete(ctx, get(t, cl, gvk, "default", "slow")); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+	// Creates the job, then gives up on it
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile error: %v", err)
+		}
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the finalizer to be released after the timeout, got %v", err)
+	}
+	if n := countEvents(rec, "CleanupJobTimeout"); n != 1 {
+		t.Fatalf("expected one CleanupJobTimeout event, got %d", n)
+	}
+}
+
+func TestReconcile_ExpiryWithCleanupFinalizerRunsJobOnce(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := newExpiredObj(gvk, "expired")
+	anns := obj.GetAnnotations()
+	anns[testOnDeleteJob] = "scripts-cm/cleanup.sh"
+	obj.SetAnnotations(anns)
+	r, cl := newFinalizerWatcher(t, obj)
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "expired"}}
+
+	// Adds the finalizer and deletes, the job waits for the finalizer
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if n := len(listJobs(t, cl)); n != 0 {
+		t.Fatalf("expected the job to wait for the finalizer, got %d jobs", n)
+	}
+	if get(t, cl, gvk, "default", "expired").GetDeletionTimestamp() == nil {
+		t.Fatalf("expected the expired object to be terminating")
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if n := len(listJobs(t, cl)); n != 1 {
+		t.Fatalf("expected one cleanup job, got %d", n)
+	}
+}
+
+func TestReconcile_RemoveTTLReleasesCleanupFinalizer(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "unleased")
+	obj.SetAnnotations(map[string]string{testOnDeleteJob: "scripts-cm/cleanup.sh"})
+	obj.SetFinalizers([]string{"example.com/other", CleanupFinalizerName})
+	r, cl := newFinalizerWatcher(t, obj)
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "unleased"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if fs := get(t, cl, gvk, "default", "unleased").GetFinalizers(); len(fs) != 1 || fs[0] != "example.com/other" {
+		t.Fatalf("expected only the other finalizer to remain, got %v", fs)
+	}
+}
+
+func TestReconcile_UnmanagedDeletionReleasesCleanupFinalizer(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	for name, unmanage := range map[string]func(r *LeaseWatcher){
+		"untracked": func(r *LeaseWatcher) { r.Tracker = util.NewNamespaceTracker() },
+		"unselected": func(r *LeaseWatcher) {
+			r.Selector = labels.SelectorFromSet(labels.Set{"lease": "on"})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			obj := leasedWithJob(gvk, "gone")
+			obj.SetFinalizers([]string{CleanupFinalizerName})
+			r, cl := newFinalizerWatcher(t, obj)
+			unmanage(r)
+
+			if err := cl.Delete(ctx, get(t, cl, gvk, "default", "gone")); err != nil {
+				t.Fatalf("delete error: %v", err)
+			}
+			reconcileName(t, r, "gone")
+			out := &unstructured.Unstructured{}
+			out.SetGroupVersionKind(gvk)
+			if err := cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "gone"}, out); !apierrors.IsNotFound(err) {
+				t.Fatalf("expected the finalizer to be released, got %v", err)
+			}
+			if n := len(listJobs(t, cl)); n != 0 {
+				t.Fatalf("expected no cleanup job for an unmanaged object, got %d", n)
+			}
+		})
+	}
+}
+
+func TestOnlyWithTTLAnnotation_DeletionWithCleanupFinalizerOutsideSelector(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _ := newFinalizerWatcher(t, leasedWithJob(gvk, "x"))
+	r.Selector = labels.SelectorFromSet(labels.Set{"lease": "on"})
+	p := r.onlyWithTTLAnnotation()
+
+	oldObj := leasedWithJob(gvk, "x")
+	oldObj.SetFinalizers([]string{CleanupFinalizerName})
+	newObj := oldObj.DeepCopy()
+	ts := metav1.Now()
+	newObj.SetDeletionTimestamp(&ts)
+
+	if !p.UpdateFunc(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}) {
+		t.Fatalf("expected a deletion held by the cleanup finalizer to be reconciled")
+	}
+	if !p.CreateFunc(event.CreateEvent{Object: newObj}) {
+		t.Fatalf("expected a deleting object with the cleanup finalizer to be reconciled on startup")
+	}
+}
+
+func TestSyncCleanupFinalizer_DisabledDoesNotAdd(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl := newFinalizerWatcher(t, leasedWithJob(gvk, "off"))
+	r.CleanupFinalizer = false
+
+	obj := get(t, cl, gvk, "default", "off")
+	if err := r.syncCleanupFinalizer(context.Background(), obj); err != nil {
+		t.Fatalf("syncCleanupFinalizer error: %v", err)
+	}
+	if fs := get(t, cl, gvk, "default", "off").GetFinalizers(); len(fs) != 0 {
+		t.Fatalf("expected no finalizers, got %v", fs)
+	}
+}
+
+func TestDeletedLeaseExpiry(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := finalizerAnn()
+	r := &LeaseWatcher{Annotations: a}
+
+	deletedAt := time.Now().UTC().Truncate(time.Second)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "x")
+	ts := metav1.NewTime(deletedAt)
+	obj.SetDeletionTimestamp(&ts)
+
+	if got := r.deletedLeaseExpiry(obj); !got.Equal(deletedAt) {
+		t.Fatalf("manual delete: got %v, want %v", got, deletedAt)
+	}
+	expired := deletedAt.Add(-time.Hour)
+	obj.SetAnnotations(map[string]string{a.ExpireAt: expired.Format(time.RFC3339)})
+	if got := r.deletedLeaseExpiry(obj); !got.Equal(expired) {
+		t.Fatalf("expired lease: got %v, want %v", got, expired)
+	}
+}
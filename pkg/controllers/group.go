@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"object-lease-controller/pkg/util"
+)
+
+// GroupOrderRequeueInterval is how often an expired group member checks
+// whether the members before it are gone
+const GroupOrderRequeueInterval = 10 * time.Second
+
+// leaseGroup returns the lease group obj belongs to, or "" if none
+func (r *LeaseWatcher) leaseGroup(obj *unstructured.Unstructured) string {
+	if r.Annotations.LeaseGroup == "" {
+		return ""
+	}
+	return obj.GetLabels()[r.Annotations.LeaseGroup]
+}
+
+// groupMembers lists the objects in obj's namespace that carry the same
+// lease-group label, obj included
+func (r *LeaseWatcher) groupMembers(ctx context.Context, obj *unstructured.Unstructured, group string) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   r.GVK.Group,
+		Version: r.GVK.Version,
+		Kind:    r.GVK.Kind + "List",
+	})
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace()), client.MatchingLabels{r.Annotations.LeaseGroup: group}); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// groupLease gives every member of a lease group one lease: the most recent
+// lease-start of any member, so renewing one member renews them all, and the
+// latest expiry computed from it, renewal extensions included. Members with
+// an older lease-start are moved to the shared start.
+func (r *LeaseWatcher) groupLease(ctx context.Context, obj *unstructured.Unstructured, group string, startAt, expireAt time.Time) (time.Time, time.Time) {
+	members, err := r.groupMembers(ctx, obj, group)
+	if err != nil {
+		logger.FromContext(ctx).Error(err, "Unable to list lease group members", "group", group)
+		return startAt, expireAt
+	}
+
+	groupStart := startAt
+	for i := range members {
+		if t, ok := r.memberLeaseStart(&members[i]); ok && t.After(groupStart) {
+			groupStart = t
+		}
+	}
+
+	groupExpiry := expireAt
+	if groupStart.After(startAt) {
+		if t, ok := r.memberExpiry(obj, groupStart); ok {
+			groupExpiry = t
+		}
+		r.renewFromGroup(ctx, obj, group, groupStart)
+	}
+	for i := range members {
+		m := &members[i]
+		if m.GetName() == obj.GetName() || m.GetDeletionTimestamp() != nil || r.noTTL(m) {
+			continue
+		}
+		if t, ok := r.memberExpiry(m, groupStart); ok && t.After(groupExpiry) {
+			groupExpiry = t
+		}
+		if t, ok := r.memberLeaseStart(m); ok && t.Before(groupStart) {
+			r.renewFromGroup(ctx, m, group, groupStart)
+		}
+	}
+	return groupStart, groupExpiry
+}
+
+// renewFromGroup moves a member's lease-start to the group's start
+func (r *LeaseWatcher) renewFromGroup(ctx context.Context, member *unstructured.Unstructured, group string, groupStart time.Time) {
+	r.updateAnnotations(ctx, member, map[string]string{r.Annotations.LeaseStart: groupStart.Format(time.RFC3339)})
+	if r.leaseMode(member) == util.LeaseModeSliding {
+		// The group renewal is not an edit of the member
+		r.activity.record(member)
+	}
+	if r.Recorder != nil {
+		r.Recorder.Eventf(member, nil, "Normal", "LeaseGroupRenewed", "LeaseGroupRenewed", "Lease renewed with lease group %s", group)
+	}
+}
+
+// memberLeaseStart returns a member's lease-start, if it has a valid one
+func (r *LeaseWatcher) memberLeaseStart(m *unstructured.Unstructured) (time.Time, bool) {
+	if r.noTTL(m) || m.GetDeletionTimestamp() != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, m.GetAnnotations()[r.Annotations.LeaseStart])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+// memberExpiry computes a member's expiry from the group's lease start,
+// extended by the member's renewals. Members with invalid annotations are
+// left out, they report it themselves.
+func (r *LeaseWatcher) memberExpiry(m *unstructured.Unstructured, start time.Time) (time.Time, bool) {
+	anns := m.GetAnnotations()
+	if r.Annotations.DeleteAt != "" && anns[r.Annotations.DeleteAt] != "" {
+		t, err := time.Parse(time.RFC3339, anns[r.Annotations.DeleteAt])
+		return t.UTC().Add(r.leaseExtension(m)), err == nil
+	}
+	expiry, err := r.leaseExpiry(m)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiry(start).Add(r.leaseExtension(m)), true
+}
+
+// groupOrder returns the position of obj in its group's deletion order
+func (r *LeaseWatcher) groupOrder(obj *unstructured.Unstructured) (int, error) {
+	v := obj.GetAnnotations()[r.Annotations.LeaseGroupOrder]
+	if r.Annotations.LeaseGroupOrder == "" || v == "" {
+		return 0, nil
+	}
+	order, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("lease-group-order must be an integer, got %q", v)
+	}
+	return order, nil
+}
+
+// awaitGroupOrder holds an expired group member while members with a lower
+// lease-group-order are still there. Returns false once it is obj's turn.
+func (r *LeaseWatcher) awaitGroupOrder(ctx context.Context, obj *unstructured.Unstructured, group string, expireAt time.Time) (controller_runtime.Result, bool) {
+	order, err := r.groupOrder(obj)
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidLeaseGroupOrder", fmt.Sprintf("Invalid lease-group-order: %v", err))
+		return controller_runtime.Result{}, true
+	}
+	members, err := r.groupMembers(ctx, obj, group)
+	if err != nil {
+		logger.FromContext(ctx).Error(err, "Unable to list lease group members", "group", group)
+		return controller_runtime.Result{RequeueAfter: GroupOrderRequeueInterval}, true
+	}
+
+	// Members share obj's namespace, read its pause once for all of them
+	nsPaused := r.namespacePaused(ctx, obj.GetNamespace())
+	var before []string
+	for i := range members {
+		m := &members[i]
+		if m.GetName() == obj.GetName() || r.noTTL(m) || m.GetAnnotations()[r.Annotations.Phase] == PhaseExpired {
+			// Members without a lease or with a non-delete action applied stay
+			continue
+		}
+		if r.isHibernated(m) || nsPaused || r.objectPaused(m) {
+			// Nor do members that wait out their hibernation or are paused
+			continue
+		}
+		if o, err := r.groupOrder(m); err == nil && o < order {
+			before = append(before, m.GetName())
+		}
+	}
+	if len(before) == 0 {
+		return controller_runtime.Result{}, false
+	}
+
+	sort.Strings(before)
+	leaseStatus := fmt.Sprintf("Lease group %s expired. Waiting for %s to be deleted first.", group, strings.Join(before, ", "))
+	if obj.GetAnnotations()[r.Annotations.Status] != leaseStatus && r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseGroupWaiting", "LeaseGroupWaiting", "%s", leaseStatus)
+	}
+	r.setStatus(ctx, obj, "LeaseGroupWaiting", leaseStatus, map[string]string{
+		r.Annotations.ExpireAt: expireAt.Format(time.RFC3339),
+	})
+	return controller_runtime.Result{RequeueAfter: GroupOrderRequeueInterval}, true
+}
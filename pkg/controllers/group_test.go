@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func groupAnn() Annotations {
+	a := defaultAnn()
+	a.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	a.LeaseGroup = "object-lease-controller.ullberg.io/lease-group"
+	a.LeaseGroupOrder = "object-lease-controller.ullberg.io/lease-group-order"
+	return a
+}
+
+func groupMember(gvk schema.GroupVersionKind, name, ttl string, start time.Time, extra map[string]string) *unstructured.Unstructured {
+	a := groupAnn()
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	obj.SetLabels(map[string]string{a.LeaseGroup: "preview"})
+	anns := map[string]string{
+		a.TTL:        ttl,
+		a.LeaseStart: start.UTC().Format(time.RFC3339),
+	}
+	for k, v := range extra {
+		anns[k] = v
+	}
+	obj.SetAnnotations(anns)
+	return obj
+}
+
+func newGroupWatcher(t *testing.T, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	gvk := objs[0].GetObjectKind().GroupVersionKind()
+	r, cl, _ := newWatcher(t, gvk, objs...)
+	r.Annotations = groupAnn()
+	return r, cl
+}
+
+func reconcileName(t *testing.T, r *LeaseWatcher, name string) controller_runtime.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+	if err != nil {
+		t.Fatalf("reconcile %s error: %v", name, err)
+	}
+	return res
+}
+
+func TestReconcile_RenewingOneGroupMemberRenewsAll(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	renewed := groupMember(gvk, "api", "1h", now, nil)
+	stale := groupMember(gvk, "db", "1h", now.Add(-50*time.Minute), nil)
+	r, cl := newGroupWatcher(t, renewed, stale)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	reconcileName(t, r, "api")
+
+	got := get(t, cl, gvk, "default", "db").GetAnnotations()
+	if got[a.LeaseStart] != now.Format(time.RFC3339) {
+		t.Fatalf("db lease-start = %q, want the group start %q", got[a.LeaseStart], now.Format(time.RFC3339))
+	}
+	if n := countEvents(rec, "LeaseGroupRenewed"); n != 1 {
+		t.Fatalf("expected one LeaseGroupRenewed event, got %d", n)
+	}
+
+	// db now runs from the shared start and is not close to expiry
+	reconcileName(t, r, "db")
+	got = get(t, cl, gvk, "default", "db").GetAnnotations()
+	if got[a.ExpireAt] != now.Add(time.Hour).Format(time.RFC3339) {
+		t.Fatalf("db expire-at = %q, want %q", got[a.ExpireAt], now.Add(time.Hour).Format(time.RFC3339))
+	}
+}
+
+func TestReconcile_GroupExpiryIsTheLatestMemberExpiry(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+	start := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Second)
+
+	// On its own the short member would have expired an hour ago
+	short := groupMember(gvk, "short", "1h", start, nil)
+	long := groupMember(gvk, "long", "3h", start, nil)
+	r, cl := newGroupWatcher(t, short, long)
+
+	reconcileName(t, r, "short")
+
+	got := get(t, cl, gvk, "default", "short").GetAnnotations()
+	if got[a.ExpireAt] != start.Add(3*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q, want the group expiry %q", got[a.ExpireAt], start.Add(3*time.Hour).Format(time.RFC3339))
+	}
+	if got[a.Phase] != PhaseActive {
+		t.Fatalf("phase = %q, want %q", got[a.Phase], PhaseActive)
+	}
+}
+
+func TestReconcile_GroupMembersAreDeletedInOrder(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+	start := time.Now().UTC().Add(-2 * time.Hour)
+
+	first := groupMember(gvk, "app", "1h", start, map[string]string{a.LeaseGroupOrder: "0"})
+	second := groupMember(gvk, "db", "1h", start, map[string]string{a.LeaseGroupOrder: "10"})
+	r, cl := newGroupWatcher(t, first, second)
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+
+	// db waits for app
+	res := reconcileName(t, r, "db")
+	if res.RequeueAfter != GroupOrderRequeueInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, GroupOrderRequeueInterval)
+	}
+	if status := get(t, cl, gvk, "default", "db").GetAnnotations()[a.Status]; !strings.Contains(status, "Waiting for app") {
+		t.Fatalf("unexpected status %q", status)
+	}
+	if n := countEvents(rec, "LeaseGroupWaiting"); n != 1 {
+		t.Fatalf("expected one LeaseGroupWaiting event, got %d", n)
+	}
+
+	reconcileName(t, r, "app")
+	reconcileName(t, r, "db")
+	for _, name := range []string{"app", "db"} {
+		out := &unstructured.Unstructured{}
+		out.SetGroupVersionKind(gvk)
+		if err := cl.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, out); !apierrors.IsNotFound(err) {
+			t.Fatalf("expected %s to be deleted, got %v", name, err)
+		}
+	}
+}
+
+func TestReconcile_RenewalExtensionExtendsTheGroup(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+	a.LeaseExtension = "object-lease-controller.ullberg.io/lease-extension"
+	start := time.Now().UTC().Add(-50 * time.Minute).Truncate(time.Second)
+
+	// api was renewed by 2h, db was not
+	api := groupMember(gvk, "api", "1h", start, map[string]string{a.LeaseExtension: "2h"})
+	db := groupMember(gvk, "db", "1h", start, nil)
+	r, cl := newGroupWatcher(t, api, db)
+	r.Annotations = a
+
+	reconcileName(t, r, "db")
+	if got := get(t, cl, gvk, "default", "db").GetAnnotations()[a.ExpireAt]; got != start.Add(3*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("db expire-at = %q, want the renewed group expiry %q", got, start.Add(3*time.Hour).Format(time.RFC3339))
+	}
+}
+
+func TestReconcile_PausedOrHibernatedMembersDoNotHoldUpTheGroup(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+	a.LeasePaused = "object-lease-controller.ullberg.io/lease-paused"
+	start := time.Now().UTC().Add(-2 * time.Hour)
+
+	paused := groupMember(gvk, "paused", "1h", start, map[string]string{a.LeaseGroupOrder: "0", a.LeasePaused: "true"})
+	hibernated := groupMember(gvk, "hibernated", "1h", start, map[string]string{a.LeaseGroupOrder: "0", a.Phase: PhaseHibernated})
+	last := groupMember(gvk, "last", "1h", start, map[string]string{a.LeaseGroupOrder: "10"})
+	r, cl := newGroupWatcher(t, paused, hibernated, last)
+	r.Annotations = a
+
+	reconcileName(t, r, "last")
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "last"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected last to be deleted without waiting, got %v", err)
+	}
+}
+
+func TestAwaitGroupOrder_ReadsNamespacePauseOnce(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+	a.LeasePaused = "object-lease-controller.ullberg.io/lease-paused"
+	start := time.Now().UTC().Add(-2 * time.Hour)
+
+	a1 := groupMember(gvk, "a1", "1h", start, map[string]string{a.LeaseGroupOrder: "0"})
+	a2 := groupMember(gvk, "a2", "1h", start, map[string]string{a.LeaseGroupOrder: "0"})
+	a3 := groupMember(gvk, "a3", "1h", start, map[string]string{a.LeaseGroupOrder: "0"})
+	last := groupMember(gvk, "last", "1h", start, map[string]string{a.LeaseGroupOrder: "10"})
+	r, cl := newGroupWatcher(t, a1, a2, a3, last)
+	r.Annotations = a
+
+	nsGets := 0
+	r.Client = interceptor.NewClient(cl.(client.WithWatch), interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*corev1.Namespace); ok {
+				nsGets++
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	})
+
+	_, waiting := r.awaitGroupOrder(context.Background(), last, "preview", start.Add(time.Hour))
+	if !waiting {
+		t.Fatalf("expected last to wait for a1, a2 and a3")
+	}
+	if nsGets != 1 {
+		t.Fatalf("namespace read %d times, want once", nsGets)
+	}
+}
+
+func TestReconcile_InvalidGroupOrderIsReported(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := groupAnn()
+
+	obj := groupMember(gvk, "bad", "1h", time.Now().Add(-2*time.Hour), map[string]string{a.LeaseGroupOrder: "first"})
+	r, cl := newGroupWatcher(t, obj)
+
+	reconcileName(t, r, "bad")
+	if status := get(t, cl, gvk, "default", "bad").GetAnnotations()[a.Status]; !strings.Contains(status, "Invalid lease-group-order") {
+		t.Fatalf("unexpected status %q", status)
+	}
+}
+
+func TestOnlyWithTTLAnnotation_LeaseGroupChangeTriggers(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, _ := newWatcher(t, gvk)
+	r.Annotations = groupAnn()
+
+	oldObj := groupMember(gvk, "x", "1h", time.Now(), nil)
+	newObj := oldObj.DeepCopy()
+	newObj.SetLabels(map[string]string{r.Annotations.LeaseGroup: "other"})
+	if !r.onlyWithTTLAnnotation().UpdateFunc(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}) {
+		t.Fatalf("expected a lease-group change to trigger a reconcile")
+	}
+}
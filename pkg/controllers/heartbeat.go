@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+)
+
+// heartbeatStart returns the effective lease start for a heartbeat lease: the
+// later of lease-start and the last heartbeat. Returns false if the heartbeat
+// annotation is invalid, in which case the object is marked and left alone.
+func (r *LeaseWatcher) heartbeatStart(ctx context.Context, obj *unstructured.Unstructured, startAt time.Time) (time.Time, bool) {
+	v := obj.GetAnnotations()[r.Annotations.Heartbeat]
+	if r.Annotations.Heartbeat == "" || v == "" {
+		// No heartbeat yet, the lease runs from lease-start
+		return startAt, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidHeartbeat", fmt.Sprintf("Invalid heartbeat: %v", err))
+		return startAt, false
+	}
+	if t.Before(startAt) {
+		return startAt, true
+	}
+	return t.UTC(), true
+}
+
+// checkHeartbeat warns once the heartbeat is overdue by half the lease window,
+// and otherwise makes sure the object is requeued by that half-way point.
+func (r *LeaseWatcher) checkHeartbeat(ctx context.Context, obj *unstructured.Unstructured, lastBeat, expireAt, now time.Time, res controller_runtime.Result) controller_runtime.Result {
+	halfway := lastBeat.Add(expireAt.Sub(lastBeat) / 2)
+	if now.Before(halfway) {
+		if until := halfway.Sub(now); until < res.RequeueAfter {
+			res.RequeueAfter = until
+		}
+		return res
+	}
+	// heartbeat-missed records which window the warning was already sent for
+	missed := lastBeat.Format(time.RFC3339)
+	if r.Annotations.HeartbeatMissed != "" {
+		if obj.GetAnnotations()[r.Annotations.HeartbeatMissed] == missed {
+			return res
+		}
+		r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.HeartbeatMissed: missed})
+	}
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", "HeartbeatMissed", "HeartbeatMissed", "No heartbeat since %s, lease expires at %s", lastBeat.Format(time.RFC3339), expireAt.Format(time.RFC3339))
+	}
+	if r.Metrics != nil {
+		r.Metrics.HeartbeatsMissed.Inc()
+	}
+	return res
+}
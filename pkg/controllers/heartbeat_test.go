@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+)
+
+func heartbeatAnn() Annotations {
+	a := defaultAnn()
+	a.LeaseMode = "object-lease-controller.ullberg.io/lease-mode"
+	a.Heartbeat = "object-lease-controller.ullberg.io/heartbeat"
+	a.HeartbeatMissed = "object-lease-controller.ullberg.io/heartbeat-missed"
+	return a
+}
+
+func newHeartbeatObj(gvk schema.GroupVersionKind, name string, leaseStart, beat time.Time) *unstructured.Unstructured {
+	a := heartbeatAnn()
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	obj.SetAnnotations(map[string]string{
+		a.TTL:        "1h",
+		a.LeaseMode:  "heartbeat",
+		a.LeaseStart: leaseStart.Format(time.RFC3339),
+		a.Heartbeat:  beat.Format(time.RFC3339),
+	})
+	return obj
+}
+
+func TestReconcile_HeartbeatExtendsExpiry(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	beat := now.Add(-10 * time.Minute)
+	obj := newHeartbeatObj(gvk, "hb1", now.Add(-5*time.Hour), beat)
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = heartbeatAnn()
+
+	res, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hb1"}})
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	want := beat.Add(time.Hour).Format(time.RFC3339)
+	if got := get(t, cl, gvk, "default", "hb1").GetAnnotations()[r.Annotations.ExpireAt]; got != want {
+		t.Fatalf("expire-at = %q, want %q", got, want)
+	}
+	// Requeue at the half-way point, 20 minutes from now
+	if res.RequeueAfter <= 15*time.Minute || res.RequeueAfter > 21*time.Minute {
+		t.Fatalf("RequeueAfter = %v, want about 20m", res.RequeueAfter)
+	}
+}
+
+func TestReconcile_HeartbeatMissedEmitsWarning(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	obj := newHeartbeatObj(gvk, "hb2", now.Add(-5*time.Hour), now.Add(-45*time.Minute))
+
+	r, _, _ := newWatcher(t, gvk, obj)
+	r.Annotations = heartbeatAnn()
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	res, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hb2"}})
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if res.RequeueAfter <= 10*time.Minute || res.RequeueAfter > 16*time.Minute {
+		t.Fatalf("RequeueAfter = %v, want about 15m until expiry", res.RequeueAfter)
+	}
+	found := false
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, "HeartbeatMissed") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected HeartbeatMissed event")
+	}
+}
+
+func TestReconcile_HeartbeatMissedWarnsOncePerWindow(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	obj := newHeartbeatObj(gvk, "hb5", now.Add(-5*time.Hour), now.Add(-45*time.Minute))
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = heartbeatAnn()
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	reconcileName(t, r, "hb5")
+	reconcileName(t, r, "hb5")
+	if n := countEvents(rec, "HeartbeatMissed"); n != 1 {
+		t.Fatalf("expected 1 HeartbeatMissed event, got %d", n)
+	}
+	if got, want := get(t, cl, gvk, "default", "hb5").GetAnnotations()[r.Annotations.HeartbeatMissed], now.Add(-45*time.Minute).Format(time.RFC3339); got != want {
+		t.Fatalf("heartbeat-missed = %q, want %q", got, want)
+	}
+
+	// A heartbeat that is missed again opens a new window
+	cur := get(t, cl, gvk, "default", "hb5")
+	anns := cur.GetAnnotations()
+	anns[r.Annotations.Heartbeat] = now.Add(-40 * time.Minute).Format(time.RFC3339)
+	cur.SetAnnotations(anns)
+	if err := cl.Update(context.Background(), cur); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	reconcileName(t, r, "hb5")
+	if n := countEvents(rec, "HeartbeatMissed"); n != 1 {
+		t.Fatalf("expected 1 HeartbeatMissed event for the new window, got %d", n)
+	}
+}
+
+func TestReconcile_HeartbeatExpiredDeletesObject(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	now := time.Now().UTC()
+	obj := newHeartbeatObj(gvk, "hb3", now.Add(-5*time.Hour), now.Add(-2*time.Hour))
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = heartbeatAnn()
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hb3"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "hb3"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound after missed heartbeat, got %v", err)
+	}
+}
+
+func TestReconcile_InvalidHeartbeatDoesNotDelete(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := newHeartbeatObj(gvk, "hb4", time.Now().UTC().Add(-5*time.Hour), time.Now().UTC())
+	anns := obj.GetAnnotations()
+	anns[heartbeatAnn().Heartbeat] = "yesterday-ish"
+	obj.SetAnnotations(anns)
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = heartbeatAnn()
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hb4"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "hb4").GetAnnotations()
+	if !strings.Contains(got[r.Annotations.Status], "Invalid heartbeat") {
+		t.Fatalf("lease-status should mention Invalid heartbeat, got %q", got[r.Annotations.Status])
+	}
+}
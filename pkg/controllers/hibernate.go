@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"object-lease-controller/pkg/util"
+)
+
+// Lease phases recorded in the phase annotation
+const (
+	PhaseActive     = "active"
+	PhasePaused     = "paused"
+	PhaseHibernated = "hibernated"
+	PhaseExpired    = "expired"
+	PhaseDeleted    = "deleted"
+)
+
+// DefaultHibernateTTL is how long a hibernated object is kept before deletion
+// when the hibernate-ttl annotation is not set.
+const DefaultHibernateTTL = 24 * time.Hour
+
+func (r *LeaseWatcher) isHibernated(obj *unstructured.Unstructured) bool {
+	return r.Annotations.Phase != "" && obj.GetAnnotations()[r.Annotations.Phase] == PhaseHibernated
+}
+
+// hibernate runs the first stage of a two-stage expiry: scale the workload to
+// zero and keep it for the hibernate grace period. Returns done=false once the
+// grace period is over and the object should be deleted.
+func (r *LeaseWatcher) hibernate(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time) (res controller_runtime.Result, done bool, err error) {
+	anns := obj.GetAnnotations()
+	grace := DefaultHibernateTTL
+	if v := anns[r.Annotations.HibernateTTL]; r.Annotations.HibernateTTL != "" && v != "" {
+		if grace, err = util.ParseFlexibleDuration(v); err != nil {
+			r.markInvalid(ctx, obj, "InvalidHibernateTTL", fmt.Sprintf("Invalid hibernate-ttl: %v", err))
+			return controller_runtime.Result{}, true, nil
+		}
+	}
+	deleteAt := expireAt.Add(grace)
+	now := time.Now().UTC()
+
+	if r.isHibernated(obj) {
+		if now.Before(deleteAt) {
+			return controller_runtime.Result{RequeueAfter: deleteAt.Sub(now)}, true, nil
+		}
+		return controller_runtime.Result{}, false, nil
+	}
+
+	replicas, err := util.GetReplicas(ctx, r.Client, obj)
+	if util.IsScaleUnsupported(err) {
+		// Retrying does not help a kind that cannot be scaled
+		r.markInvalid(ctx, obj, "InvalidOnExpire", fmt.Sprintf("Invalid on-expire: %s needs a scale subresource: %v", util.ExpiryActionHibernate, err))
+		return controller_runtime.Result{}, true, nil
+	}
+	if err != nil {
+		return controller_runtime.Result{}, true, fmt.Errorf("failed to read replicas: %w", err)
+	}
+	if err := util.SetReplicas(ctx, r.Client, obj, 0); err != nil {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "ExpiryActionFailed", "ExpiryActionFailed", "On-expire action %s failed: %v", util.ExpiryActionHibernate, err)
+		}
+		return controller_runtime.Result{}, true, err
+	}
+
+	r.setStatus(ctx, obj, "LeaseHibernated", fmt.Sprintf("Lease expired. Hibernated, deleting at %s UTC unless renewed.", deleteAt.Format(time.RFC3339)), map[string]string{
+		r.Annotations.ExpireAt:           expireAt.Format(time.RFC3339),
+		r.Annotations.Phase:              PhaseHibernated,
+		r.Annotations.HibernatedReplicas: strconv.FormatInt(replicas, 10),
+	})
+	message := fmt.Sprintf("Scaled to zero from %d replicas, deleting at %s unless renewed", replicas, deleteAt.Format(time.RFC3339))
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseHibernated", "LeaseHibernated", "%s", message)
+	}
+	r.recordHistory(ctx, obj, "LeaseHibernated", message, "")
+	if r.Metrics != nil {
+		r.Metrics.LeasesHibernated.Inc()
+	}
+	return controller_runtime.Result{RequeueAfter: deleteAt.Sub(now)}, true, nil
+}
+
+// resume restores the replica count saved when the object was hibernated
+func (r *LeaseWatcher) resume(ctx context.Context, obj *unstructured.Unstructured) error {
+	replicas, err := strconv.ParseInt(obj.GetAnnotations()[r.Annotations.HibernatedReplicas], 10, 64)
+	if err != nil {
+		replicas = 1
+	}
+	if err := util.SetReplicas(ctx, r.Client, obj, replicas); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to restore replicas: %w", err)
+	}
+
+	base := obj.DeepCopy()
+	anns := obj.GetAnnotations()
+	delete(anns, r.Annotations.HibernatedReplicas)
+	anns[r.Annotations.Phase] = PhaseActive
+	obj.SetAnnotations(anns)
+	_ = r.Patch(ctx, obj, client.MergeFrom(base))
+
+	message := fmt.Sprintf("Lease renewed, restored %d replicas", replicas)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseResumed", "LeaseResumed", "%s", message)
+	}
+	r.recordHistory(ctx, obj, "LeaseResumed", message, "")
+	if r.Metrics != nil {
+		r.Metrics.LeasesResumed.Inc()
+	}
+	return nil
+}
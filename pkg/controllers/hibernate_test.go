@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func hibernateAnn() Annotations {
+	a := defaultAnn()
+	a.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	a.HibernateTTL = "object-lease-controller.ullberg.io/hibernate-ttl"
+	a.HibernatedReplicas = "object-lease-controller.ullberg.io/hibernated-replicas"
+	return a
+}
+
+// scaleRecorder fakes the scale subresource, which the fake client does not
+// support for unstructured objects
+type scaleRecorder struct {
+	current int64
+	set     []int64
+}
+
+func (s *scaleRecorder) funcs() interceptor.Funcs {
+	return interceptor.Funcs{
+		SubResourceGet: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+			u := subResource.(*unstructured.Unstructured)
+			return unstructured.SetNestedField(u.Object, s.current, "spec", "replicas")
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			var body struct {
+				Spec struct {
+					Replicas int64 `json:"replicas"`
+				} `json:"spec"`
+			}
+			data, err := patch.Data(obj)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &body); err != nil {
+				return err
+			}
+			s.current = body.Spec.Replicas
+			s.set = append(s.set, body.Spec.Replicas)
+			return nil
+		},
+	}
+}
+
+func newHibernateWatcher(t *testing.T, obj *unstructured.Unstructured, sr *scaleRecorder) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	r, cl, _ := newWatcher(t, obj.GroupVersionKind(), obj)
+	cl = interceptor.NewClient(cl.(client.WithWatch), sr.funcs())
+	r.Client = cl
+	r.Annotations = hibernateAnn()
+	return r, cl
+}
+
+func TestReconcile_HibernateScalesToZeroThenDeletes(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	a := hibernateAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "web")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:          "1h",
+		a.LeaseStart:   time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+		a.OnExpire:     "hibernate",
+		a.HibernateTTL: "2h",
+	})
+
+	sr := &scaleRecorder{current: 3}
+	r, cl := newHibernateWatcher(t, obj, sr)
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "web").GetAnnotations()
+	if got[a.Phase] != PhaseHibernated {
+		t.Fatalf("phase = %q, want %q", got[a.Phase], PhaseHibernated)
+	}
+	if got[a.HibernatedReplicas] != "3" {
+		t.Fatalf("hibernated-replicas = %q, want 3", got[a.HibernatedReplicas])
+	}
+	if len(sr.set) != 1 || sr.set[0] != 0 {
+		t.Fatalf("expected a single scale to 0, got %v", sr.set)
+	}
+	// Expired an hour ago with a 2h grace, so about an hour left
+	if res.RequeueAfter < 50*time.Minute || res.RequeueAfter > 61*time.Minute {
+		t.Fatalf("RequeueAfter = %v, want about 1h", res.RequeueAfter)
+	}
+
+	// Still within the grace period, nothing more happens
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if len(sr.set) != 1 {
+		t.Fatalf("expected no further scaling, got %v", sr.set)
+	}
+
+	// Shorten the grace period so it is over, the object is deleted
+	cur := get(t, cl, gvk, "default", "web")
+	anns := cur.GetAnnotations()
+	anns[a.HibernateTTL] = "1m"
+	cur.SetAnnotations(anns)
+	if err := cl.Update(ctx, cur); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound after grace period, got %v", err)
+	}
+}
+
+func TestReconcile_RenewWhileHibernatedRestoresReplicas(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	a := hibernateAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "db")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:                "1h",
+		a.LeaseStart:         time.Now().UTC().Format(time.RFC3339),
+		a.OnExpire:           "hibernate",
+		a.Phase:              PhaseHibernated,
+		a.HibernatedReplicas: "5",
+	})
+
+	sr := &scaleRecorder{current: 0}
+	r, cl := newHibernateWatcher(t, obj, sr)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "db"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if len(sr.set) != 1 || sr.set[0] != 5 {
+		t.Fatalf("expected replicas restored to 5, got %v", sr.set)
+	}
+	got := get(t, cl, gvk, "default", "db").GetAnnotations()
+	if got[a.Phase] != PhaseActive {
+		t.Fatalf("phase = %q, want %q", got[a.Phase], PhaseActive)
+	}
+	if _, ok := got[a.HibernatedReplicas]; ok {
+		t.Fatalf("expected hibernated-replicas to be removed")
+	}
+}
+
+func TestReconcile_HibernateWithoutScaleIsInvalid(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := hibernateAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "settings")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:        "1h",
+		a.LeaseStart: time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+		a.OnExpire:   "hibernate",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Client = interceptor.NewClient(cl.(client.WithWatch), interceptor.Funcs{
+		SubResourceGet: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps/scale"}, obj.GetName())
+		},
+	})
+	r.Annotations = hibernateAnn()
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	res, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "settings"}})
+	if err != nil {
+		t.Fatalf("expected no retry for a kind without scale, got %v", err)
+	}
+	if res.RequeueAfter != 0 {
+		t.Fatalf("RequeueAfter = %v, want none", res.RequeueAfter)
+	}
+	got := get(t, cl, gvk, "default", "settings").GetAnnotations()
+	if got[a.Phase] == PhaseHibernated {
+		t.Fatalf("object without scale should not be marked hibernated")
+	}
+	if n := countEvents(rec, "InvalidOnExpire"); n != 1 {
+		t.Fatalf("expected 1 InvalidOnExpire event, got %d", n)
+	}
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"object-lease-controller/pkg/util"
+)
+
+// HistoryArchiveLimit bounds how many deleted objects one history ConfigMap
+// keeps, the ones deleted longest ago are dropped first
+const HistoryArchiveLimit = 100
+
+// HistoryArchiveMaxBytes bounds the data of one history ConfigMap, below the
+// 1 MiB the API server accepts to leave room for its metadata
+const HistoryArchiveMaxBytes = 900 * 1024
+
+// HistoryEntry is one lease transition in the lease-history annotation
+type HistoryEntry struct {
+	Time    string `json:"time"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// By is the field manager that caused the transition, when known
+	By string `json:"by,omitempty"`
+	// TTL is the ttl of the lease at the time
+	TTL string `json:"ttl,omitempty"`
+}
+
+// leaseHistory returns the recorded transitions of obj, oldest first
+func (r *LeaseWatcher) leaseHistory(obj *unstructured.Unstructured) []HistoryEntry {
+	var history []HistoryEntry
+	if raw := obj.GetAnnotations()[r.Annotations.History]; r.Annotations.History != "" && raw != "" {
+		// A history that cannot be read starts over
+		_ = json.Unmarshal([]byte(raw), &history)
+	}
+	return history
+}
+
+// recordHistory appends a transition to the lease-history of obj, keeping
+// the last HistoryLimit. A transition that repeats the last one is dropped,
+// so an invalid setting is only recorded once.
+func (r *LeaseWatcher) recordHistory(ctx context.Context, obj *unstructured.Unstructured, reason, message, by string) {
+	if r.Annotations.History == "" || r.HistoryLimit <= 0 {
+		return
+	}
+	history := r.leaseHistory(obj)
+	if n := len(history); n > 0 && history[n-1].Reason == reason && history[n-1].Message == message {
+		return
+	}
+	history = append(history, HistoryEntry{
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		Reason:  reason,
+		Message: message,
+		By:      by,
+		TTL:     obj.GetAnnotations()[r.Annotations.TTL],
+	})
+	if len(history) > r.HistoryLimit {
+		history = history[len(history)-r.HistoryLimit:]
+	}
+	b, err := json.Marshal(history)
+	if err != nil {
+		return
+	}
+	r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.History: string(b)})
+}
+
+// trackTTLChange records a changed ttl, compared to the ttl of the last
+// recorded transition
+func (r *LeaseWatcher) trackTTLChange(ctx context.Context, obj *unstructured.Unstructured) {
+	history := r.leaseHistory(obj)
+	ttl := obj.GetAnnotations()[r.Annotations.TTL]
+	if len(history) == 0 || ttl == "" {
+		return
+	}
+	last := history[len(history)-1].TTL
+	if last == "" || last == ttl {
+		return
+	}
+	message := fmt.Sprintf("TTL changed from %s to %s", last, ttl)
+	by := util.AnnotationManager(obj, r.Annotations.TTL)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseTTLChanged", "LeaseTTLChanged", "%s", message)
+	}
+	r.recordHistory(ctx, obj, "LeaseTTLChanged", message, by)
+}
+
+// archiveHistory copies the lease-history of an object that is about to be
+// deleted into the history ConfigMap, in the namespace of the object or,
+// for cluster-scoped objects and Namespaces, the ops namespace. The key is
+// the lower case kind and the name of the object.
+func (r *LeaseWatcher) archiveHistory(ctx context.Context, obj *unstructured.Unstructured) error {
+	history := r.leaseHistory(obj)
+	if r.HistoryConfigMap == "" || len(history) == 0 {
+		return nil
+	}
+	namespace := obj.GetNamespace()
+	if r.ClusterScoped || r.isNamespaceLease() {
+		namespace = r.OpsNamespace
+	}
+	if namespace == "" {
+		return fmt.Errorf("the history of cluster-scoped objects needs an ops namespace")
+	}
+	b, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	key := strings.ToLower(r.GVK.Kind) + "." + obj.GetName()
+
+	// Objects in one namespace expire close together, read the ConfigMap
+	// fresh for each attempt so a conflicting update is retried
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := r.live().Get(ctx, client.ObjectKey{Namespace: namespace, Name: r.HistoryConfigMap}, cm)
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: r.HistoryConfigMap},
+				Data:       map[string]string{key: string(b)},
+			}
+			err = r.Create(ctx, cm)
+			if apierrors.IsAlreadyExists(err) {
+				// Created in the meantime, update it on the next attempt
+				return apierrors.NewConflict(corev1.Resource("configmaps"), r.HistoryConfigMap, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(b)
+		pruneHistoryArchive(cm.Data, HistoryArchiveLimit, HistoryArchiveMaxBytes)
+		return r.Update(ctx, cm)
+	})
+}
+
+// pruneHistoryArchive drops the histories that ended longest ago until at
+// most limit are left and they fit in maxBytes
+func pruneHistoryArchive(data map[string]string, limit, maxBytes int) {
+	size := 0
+	for k, v := range data {
+		size += len(k) + len(v)
+	}
+	if len(data) <= limit && size <= maxBytes {
+		return
+	}
+	ended := make(map[string]string, len(data))
+	keys := make([]string, 0, len(data))
+	for k, v := range data {
+		var history []HistoryEntry
+		if json.Unmarshal([]byte(v), &history) == nil && len(history) > 0 {
+			ended[k] = history[len(history)-1].Time
+		}
+		keys = append(keys, k)
+	}
+	// RFC3339 UTC times sort as strings, unreadable histories go first
+	sort.Slice(keys, func(i, j int) bool {
+		if ended[keys[i]] != ended[keys[j]] {
+			return ended[keys[i]] < ended[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		if len(data) <= limit && size <= maxBytes {
+			return
+		}
+		size -= len(k) + len(data[k])
+		delete(data, k)
+	}
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const testHistory = "object-lease-controller.ullberg.io/lease-history"
+
+func historyOf(t *testing.T, u *unstructured.Unstructured) []HistoryEntry {
+	t.Helper()
+	var history []HistoryEntry
+	if err := json.Unmarshal([]byte(u.GetAnnotations()[testHistory]), &history); err != nil {
+		t.Fatalf("invalid history %q: %v", u.GetAnnotations()[testHistory], err)
+	}
+	return history
+}
+
+func reasonsOf(history []HistoryEntry) []string {
+	reasons := make([]string, len(history))
+	for i, e := range history {
+		reasons[i] = e.Reason
+	}
+	return reasons
+}
+
+func TestReconcile_HistoryRecordsTransitions(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "audited")
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "1h"})
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations.History = testHistory
+	r.HistoryLimit = 10
+
+	reconcileName(t, r, "audited")
+	history := historyOf(t, get(t, cl, gvk, "default", "audited"))
+	if len(history) != 1 || history[0].Reason != "LeaseStarted" || history[0].TTL != "1h" {
+		t.Fatalf("unexpected history after the start: %+v", history)
+	}
+
+	// Shorten the TTL so the lease expires
+	u := get(t, cl, gvk, "default", "audited")
+	anns := u.GetAnnotations()
+	anns[defaultAnn().TTL] = "1s"
+	anns[defaultAnn().LeaseStart] = time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	anns[defaultAnn().OnExpire] = "label:expired=true"
+	u.SetAnnotations(anns)
+	if err := cl.Update(context.Background(), u); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	reconcileName(t, r, "audited")
+	reconcileName(t, r, "audited")
+
+	history = historyOf(t, get(t, cl, gvk, "default", "audited"))
+	want := []string{"LeaseStarted", "LeaseTTLChanged", "LeaseExpired", "ExpiryActionApplied"}
+	if fmt.Sprint(reasonsOf(history)) != fmt.Sprint(want) {
+		t.Fatalf("history reasons = %v, want %v", reasonsOf(history), want)
+	}
+	if history[1].Message != "TTL changed from 1h to 1s" {
+		t.Fatalf("unexpected TTL change message %q", history[1].Message)
+	}
+}
+
+func TestRecordHistory_BoundedAndDeduplicated(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "bounded")
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "soon"})
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations.History = testHistory
+	r.HistoryLimit = 3
+
+	// An invalid TTL is only recorded once
+	reconcileName(t, r, "bounded")
+	reconcileName(t, r, "bounded")
+	u := get(t, cl, gvk, "default", "bounded")
+	if got := reasonsOf(historyOf(t, u)); fmt.Sprint(got) != "[LeaseStarted InvalidTTL]" {
+		t.Fatalf("history reasons = %v", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		r.recordHistory(context.Background(), u, "Test", fmt.Sprintf("entry %d", i), "")
+	}
+	history := historyOf(t, get(t, cl, gvk, "default", "bounded"))
+	if len(history) != 3 || history[0].Message != "entry 2" || history[2].Message != "entry 4" {
+		t.Fatalf("expected the last 3 entries, got %+v", history)
+	}
+}
+
+func TestReconcile_DeletedObjectHistoryIsArchived(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	obj := newExpiredObj(gvk, "gone")
+	anns := obj.GetAnnotations()
+	anns[testHistory] = `[{"time":"2026-01-01T00:00:00Z","reason":"LeaseStarted","message":"Lease started","by":"kubectl","ttl":"1h"}]`
+	obj.SetAnnotations(anns)
+
+	r, _, scheme := newWatcher(t, gvk)
+	scheme.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.ConfigMap{}, &corev1.ConfigMapList{})
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	r.Client = cl
+	r.Annotations.History = testHistory
+	r.HistoryLimit = 10
+	r.HistoryConfigMap = "lease-history"
+
+	reconcileName(t, r, "gone")
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "lease-history"}, cm); err != nil {
+		t.Fatalf("expected the history ConfigMap: %v", err)
+	}
+	var history []HistoryEntry
+	if err := json.Unmarshal([]byte(cm.Data["widget.gone"]), &history); err != nil {
+		t.Fatalf("invalid archived history %q: %v", cm.Data["widget.gone"], err)
+	}
+	if got := reasonsOf(history); fmt.Sprint(got) != "[LeaseStarted LeaseExpired]" || history[0].By != "kubectl" {
+		t.Fatalf("unexpected archived history %+v", history)
+	}
+}
+
+func TestPruneHistoryArchive(t *testing.T) {
+	entry := func(at string) string {
+		return fmt.Sprintf(`[{"time":"2026-01-01T00:00:00Z","reason":"LeaseStarted","message":""},{"time":%q,"reason":"LeaseExpired","message":""}]`, at)
+	}
+	data := map[string]string{
+		"widget.new":    entry("2026-03-01T00:00:00Z"),
+		"widget.old":    entry("2026-01-02T00:00:00Z"),
+		"widget.middle": entry("2026-02-01T00:00:00Z"),
+		"widget.broken": "not json",
+	}
+	pruneHistoryArchive(data, 2, HistoryArchiveMaxBytes)
+	if _, ok := data["widget.new"]; !ok || len(data) != 2 {
+		t.Fatalf("expected the newest histories to be kept, got %v", data)
+	}
+	if _, ok := data["widget.middle"]; !ok {
+		t.Fatalf("expected widget.middle to be kept, got %v", data)
+	}
+}
+
+func TestPruneHistoryArchive_BySize(t *testing.T) {
+	entry := func(at string) string {
+		return fmt.Sprintf(`[{"time":%q,"reason":"LeaseExpired","message":%q}]`, at, strings.Repeat("x", 400))
+	}
+	data := map[string]string{
+		"widget.old": entry("2026-01-01T00:00:00Z"),
+		"widget.mid": entry("2026-02-01T00:00:00Z"),
+		"widget.new": entry("2026-03-01T00:00:00Z"),
+	}
+	maxBytes := len("widget.new") + len(data["widget.new"]) + len("widget.mid") + len(data["widget.mid"])
+	pruneHistoryArchive(data, HistoryArchiveLimit, maxBytes)
+	if _, ok := data["widget.old"]; ok || len(data) != 2 {
+		t.Fatalf("expected the oldest history to make room, got %v keys", len(data))
+	}
+}
+
+func TestReconcile_HistoryArchiveRetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	objs := []client.Object{}
+	for _, name := range []string{"first", "second"} {
+		obj := newExpiredObj(gvk, name)
+		anns := obj.GetAnnotations()
+		anns[testHistory] = `[{"time":"2026-01-01T00:00:00Z","reason":"LeaseStarted","message":"Lease started"}]`
+		obj.SetAnnotations(anns)
+		objs = append(objs, obj)
+	}
+	archive := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "lease-history"}}
+	objs = append(objs, archive)
+
+	r, _, scheme := newWatcher(t, gvk)
+	scheme.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.ConfigMap{}, &corev1.ConfigMapList{})
+	// Each update first loses against another controller
+	conflicts := 0
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*corev1.ConfigMap); ok {
+				if conflicts++; conflicts%2 == 1 {
+					return apierrors.NewConflict(corev1.Resource("configmaps"), obj.GetName(), fmt.Errorf("stale"))
+				}
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+	r.Client = cl
+	r.Annotations.History = testHistory
+	r.HistoryLimit = 10
+	r.HistoryConfigMap = "lease-history"
+
+	reconcileName(t, r, "first")
+	reconcileName(t, r, "second")
+	cm := &corev1.ConfigMap{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: "lease-history"}, cm); err != nil {
+		t.Fatalf("get history ConfigMap: %v", err)
+	}
+	if _, ok := cm.Data["widget.first"]; !ok {
+		t.Fatalf("expected the first history to be archived, got keys %v", cm.Data)
+	}
+	if _, ok := cm.Data["widget.second"]; !ok {
+		t.Fatalf("expected the second history to be archived, got keys %v", cm.Data)
+	}
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"object-lease-controller/pkg/util"
+)
+
+// HolidayReconciler watches the holiday ConfigMap and updates the holidays of
+// the shared BusinessClock. Each key of the ConfigMap is a 2006-01-02 date,
+// its value a description.
+type HolidayReconciler struct {
+	client.Client
+	Namespace string
+	Name      string
+	Clock     *util.BusinessClock
+}
+
+func (r *HolidayReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return builder.ControllerManagedBy(mgr).
+		Named("holidays").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return o.GetNamespace() == r.Namespace && o.GetName() == r.Name
+		}))).
+		Complete(r)
+}
+
+func (r *HolidayReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx).WithValues("configmap", req.NamespacedName)
+
+	var dates []string
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, req.NamespacedName, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		// A missing ConfigMap means there are no holidays
+	} else {
+		for key := range cm.Data {
+			if _, err := time.Parse("2006-01-02", key); err != nil {
+				log.Info("Ignoring holiday that is not a date", "key", key)
+				continue
+			}
+			dates = append(dates, key)
+		}
+	}
+
+	if r.Clock.SetHolidays(dates) {
+		log.Info("Holidays updated", "holidays", r.Clock.Holidays())
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"object-lease-controller/pkg/util"
+)
+
+func TestHolidayReconciler_FollowsConfigMap(t *testing.T) {
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "holidays", Namespace: "ops"},
+		Data: map[string]string{
+			"2025-12-25": "Christmas Day",
+			"2025-12-24": "Christmas Eve",
+			"christmas":  "not a date",
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(cm).Build()
+	clock, err := util.NewBusinessClock(util.DefaultBusinessHours, util.DefaultBusinessDays)
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	r := &HolidayReconciler{Client: cl, Namespace: "ops", Name: "holidays", Clock: clock}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ops", Name: "holidays"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if got, want := clock.Holidays(), []string{"2025-12-24", "2025-12-25"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Holidays() = %v, want %v", got, want)
+	}
+
+	// Deleting the ConfigMap removes the holidays
+	if err := cl.Delete(ctx, cm); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if got := clock.Holidays(); len(got) != 0 {
+		t.Fatalf("expected no holidays without the ConfigMap, got %v", got)
+	}
+}
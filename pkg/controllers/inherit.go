@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"object-lease-controller/pkg/util"
+)
+
+const (
+	// MaxInheritanceDepth bounds how many owners are walked to find a lease
+	MaxInheritanceDepth = 10
+	// InheritRecheckInterval is how often an inherited lease is compared with
+	// the owner's, so renewals and new deadlines reach the children
+	InheritRecheckInterval = time.Minute
+	// OwnerIndex indexes objects by the UID of the owner they inherit from
+	OwnerIndex = "metadata.ownerReferences.inherit"
+)
+
+// inheritance is the outcome of looking for a leased owner
+type inheritance int
+
+const (
+	// notInherited means no owner up the chain has a lease
+	notInherited inheritance = iota
+	// inherited means a leased owner with an expire-at was found
+	inherited
+	// inheritPending means a leased owner has no expire-at yet
+	inheritPending
+	// ownerGone means an owner up the chain no longer exists, or obj lost its
+	// owner references
+	ownerGone
+)
+
+// inheritsLease reports whether obj has no lease of its own and may take
+// one from its owners
+func (r *LeaseWatcher) inheritsLease(obj *unstructured.Unstructured) bool {
+	if !r.InheritLease || r.Annotations.InheritedFrom == "" || !r.noTTL(obj) {
+		return false
+	}
+	return len(obj.GetOwnerReferences()) > 0 || obj.GetAnnotations()[r.Annotations.InheritedFrom] != ""
+}
+
+// inheritLease gives obj the expiry of its closest leased owner. A child
+// whose owner was deleted without cascading keeps the expiry it copied last,
+// so orphans are cleaned up as well.
+func (r *LeaseWatcher) inheritLease(ctx context.Context, obj *unstructured.Unstructured) (controller_runtime.Result, error) {
+	now := time.Now().UTC()
+	ref, expireAt, found, err := r.leasedOwner(ctx, obj)
+	if err != nil {
+		return controller_runtime.Result{}, err
+	}
+
+	anns := obj.GetAnnotations()
+	switch found {
+	case inherited:
+		if anns[r.Annotations.InheritedFrom] != ref.String() {
+			r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.InheritedFrom: ref.String()})
+			if r.Recorder != nil {
+				r.Recorder.Eventf(obj, nil, "Normal", "LeaseInherited", "LeaseInherited", "Lease inherited from %s", ref)
+			}
+		}
+	case inheritPending:
+		return controller_runtime.Result{RequeueAfter: InheritRecheckInterval}, nil
+	case ownerGone:
+		t, err := time.Parse(time.RFC3339, anns[r.Annotations.ExpireAt])
+		if anns[r.Annotations.InheritedFrom] != "" && err == nil {
+			expireAt = t.UTC()
+			break
+		}
+		r.untrackExpiry(obj)
+		r.cleanupLeaseAnnotations(ctx, obj)
+		return controller_runtime.Result{}, nil
+	default:
+		// The owner watch brings obj back when an owner gets a lease
+		r.untrackExpiry(obj)
+		r.cleanupLeaseAnnotations(ctx, obj)
+		return controller_runtime.Result{}, nil
+	}
+
+	if r.Breaker != nil {
+		r.Breaker.Observe(client.ObjectKeyFromObject(obj), expireAt)
+	}
+	if !now.Before(expireAt) {
+		return r.handleExpired(ctx, obj, expireAt)
+	}
+	res := r.setActive(ctx, obj, expireAt, now)
+	if found == inherited && res.RequeueAfter > InheritRecheckInterval {
+		res.RequeueAfter = InheritRecheckInterval
+	}
+	return res, nil
+}
+
+// leasedOwner walks the owner references of obj up to the closest owner with
+// a lease and returns that owner's expiry. The controller owner is followed
+// when there is one, otherwise the first owner.
+func (r *LeaseWatcher) leasedOwner(ctx context.Context, obj *unstructured.Unstructured) (util.ObjectRef, time.Time, inheritance, error) {
+	cur := obj
+	for depth := 0; depth < MaxInheritanceDepth; depth++ {
+		owner := ownerOf(cur)
+		if owner == nil && depth == 0 {
+			// Orphaning deletes remove the owner references of the children
+			return util.ObjectRef{}, time.Time{}, ownerGone, nil
+		}
+		if owner == nil {
+			return util.ObjectRef{}, time.Time{}, notInherited, nil
+		}
+		ref := util.ObjectRef{
+			GVK:       schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind),
+			Namespace: obj.GetNamespace(),
+			Name:      owner.Name,
+		}
+		if err := r.watchOwner(ref.GVK); err != nil {
+			return ref, time.Time{}, notInherited, err
+		}
+		parent := &unstructured.Unstructured{}
+		parent.SetGroupVersionKind(ref.GVK)
+		err := r.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, parent)
+		if apierrors.IsNotFound(err) {
+			return ref, time.Time{}, ownerGone, nil
+		}
+		if err != nil {
+			return ref, time.Time{}, notInherited, err
+		}
+		if parent.GetUID() != owner.UID {
+			// Replaced by a new object with the same name
+			return ref, time.Time{}, ownerGone, nil
+		}
+
+		anns := parent.GetAnnotations()
+		if r.hasLeaseAnnotation(anns) || anns[r.Annotations.InheritedFrom] != "" {
+			t, err := time.Parse(time.RFC3339, anns[r.Annotations.ExpireAt])
+			if err != nil {
+				return ref, time.Time{}, inheritPending, nil
+			}
+			return ref, t.UTC(), inherited, nil
+		}
+		cur = parent
+	}
+	return util.ObjectRef{}, time.Time{}, notInherited, nil
+}
+
+// watchOwner makes sure lease changes of gvk owners are mapped back to the
+// objects that inherit from them
+func (r *LeaseWatcher) watchOwner(gvk schema.GroupVersionKind) error {
+	return r.dependencies.watch(dependencyWatch{gvk: gvk, owner: true}, handler.EnqueueRequestsFromMapFunc(r.inheritorsOf), r.ownerLeaseChanged())
+}
+
+// ownerLeaseChanged lets owner updates through that change the lease passed on
+func (r *LeaseWatcher) ownerLeaseChanged() predicate.Funcs {
+	keys := []string{r.Annotations.TTL, r.Annotations.DeleteAt, r.Annotations.ExpireAt, r.Annotations.InheritedFrom}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			old, new := e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations()
+			for _, k := range keys {
+				if k != "" && old[k] != new[k] {
+					return true
+				}
+			}
+			return false
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	}
+}
+
+// ownerIndexValue extracts the OwnerIndex key of an object
+func ownerIndexValue(obj client.Object) []string {
+	owner := ownerOf(obj)
+	if owner == nil {
+		return nil
+	}
+	return []string{string(owner.UID)}
+}
+
+// inheritorsOf maps an owner to the objects that follow it for their lease
+func (r *LeaseWatcher) inheritorsOf(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   r.GVK.Group,
+		Version: r.GVK.Version,
+		Kind:    r.GVK.Kind + "List",
+	})
+	// Only the informer cache has the index, the API server rejects the field
+	if err := r.cached().List(ctx, list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{OwnerIndex: string(obj.GetUID())}); err != nil {
+		logger.FromContext(ctx).Error(err, "Unable to list objects that inherit from", "owner", obj.GetName())
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(list.Items))
+	for i := range list.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+	}
+	return reqs
+}
+
+// ownerOf returns the owner reference to follow, or nil without owners
+func ownerOf(obj metav1.Object) *metav1.OwnerReference {
+	if ref := metav1.GetControllerOf(obj); ref != nil {
+		return ref
+	}
+	if owners := obj.GetOwnerReferences(); len(owners) > 0 {
+		return &owners[0]
+	}
+	return nil
+}
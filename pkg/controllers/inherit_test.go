@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const testInheritedFrom = "object-lease-controller.ullberg.io/inherited-from"
+
+func newInheritWatcher(t *testing.T, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl, scheme := newWatcher(t, gvk, objs...)
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	cached := make([]client.Object, len(objs))
+	for i, o := range objs {
+		cached[i] = o.DeepCopyObject().(client.Object)
+	}
+	r.reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cached...).
+		WithIndex(obj, OwnerIndex, ownerIndexValue).Build()
+	r.Annotations.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	r.Annotations.InheritedFrom = testInheritedFrom
+	r.InheritLease = true
+	return r, cl
+}
+
+func leasedParent(gvk schema.GroupVersionKind, name string, anns map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	obj.SetUID(types.UID(name + "-uid"))
+	obj.SetAnnotations(anns)
+	return obj
+}
+
+func ownedBy(gvk schema.GroupVersionKind, name string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", name)
+	controller := true
+	obj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: owner.GetAPIVersion(),
+		Kind:       owner.GetKind(),
+		Name:       owner.GetName(),
+		UID:        owner.GetUID(),
+		Controller: &controller,
+	}})
+	return obj
+}
+
+func TestReconcile_ChildInheritsLeaseFromAncestor(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := defaultAnn()
+	expireAt := time.Now().UTC().Add(2 * time.Hour).Truncate(time.Second)
+
+	root := leasedParent(gvk, "cronjob", map[string]string{a.TTL: "3h", a.ExpireAt: expireAt.Format(time.RFC3339)})
+	// The middle owner has no lease, the walk continues past it
+	job := ownedBy(gvk, "job", root)
+	job.SetUID("job-uid")
+	pod := ownedBy(gvk, "pod", job)
+	r, cl := newInheritWatcher(t, root, job, pod)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	res := reconcileName(t, r, "pod")
+	if res.RequeueAfter <= 0 || res.RequeueAfter > InheritRecheckInterval {
+		t.Fatalf("RequeueAfter = %v, want at most %v", res.RequeueAfter, InheritRecheckInterval)
+	}
+	got := get(t, cl, gvk, "default", "pod").GetAnnotations()
+	if got[a.ExpireAt] != expireAt.Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q, want %q", got[a.ExpireAt], expireAt.Format(time.RFC3339))
+	}
+	if got[testInheritedFrom] != "ConfigMap.v1/default/cronjob" {
+		t.Fatalf("inherited-from = %q", got[testInheritedFrom])
+	}
+	if n := countEvents(rec, "LeaseInherited"); n != 1 {
+		t.Fatalf("expected one LeaseInherited event, got %d", n)
+	}
+}
+
+func TestReconcile_OrphanedChildExpiresWithCopiedLease(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := defaultAnn()
+
+	// The owner was deleted with the Orphan policy, which removed the owner reference
+	orphan := &unstructured.Unstructured{}
+	setMeta(orphan, gvk, "default", "orphan")
+	orphan.SetAnnotations(map[string]string{
+		a.ExpireAt:        time.Now().UTC().Add(-time.Minute).Format(time.RFC3339),
+		testInheritedFrom: "ConfigMap.v1/default/gone",
+	})
+	r, cl := newInheritWatcher(t, orphan)
+
+	reconcileName(t, r, "orphan")
+
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "orphan"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the orphan to be deleted, got %v", err)
+	}
+}
+
+func TestReconcile_UnleasedOwnerIsNotInherited(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := defaultAnn()
+
+	parent := leasedParent(gvk, "parent", nil)
+	child := ownedBy(gvk, "child", parent)
+	child.SetAnnotations(map[string]string{
+		a.ExpireAt:        time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+		testInheritedFrom: "ConfigMap.v1/default/parent",
+	})
+	r, cl := newInheritWatcher(t, parent, child)
+
+	// The owner watch brings the child back when the owner gets a lease
+	if res := reconcileName(t, r, "child"); res.RequeueAfter != 0 {
+		t.Fatalf("RequeueAfter = %v, want no requeue", res.RequeueAfter)
+	}
+	got := get(t, cl, gvk, "default", "child").GetAnnotations()
+	if _, ok := got[testInheritedFrom]; ok {
+		t.Fatalf("expected inherited-from to be removed, got %v", got)
+	}
+	if _, ok := got[a.ExpireAt]; ok {
+		t.Fatalf("expected expire-at to be removed, got %v", got)
+	}
+}
+
+func TestReconcile_OwnerLeasedLaterIsInherited(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := defaultAnn()
+
+	parent := leasedParent(gvk, "parent", nil)
+	r, cl := newInheritWatcher(t, parent, ownedBy(gvk, "child", parent))
+	if res := reconcileName(t, r, "child"); res.RequeueAfter != 0 {
+		t.Fatalf("RequeueAfter = %v, want no requeue", res.RequeueAfter)
+	}
+
+	// The parent gets a lease after the child was created
+	expireAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second).Format(time.RFC3339)
+	old := get(t, cl, gvk, "default", "parent")
+	p := old.DeepCopy()
+	p.SetAnnotations(map[string]string{a.TTL: "1h", a.ExpireAt: expireAt})
+	if err := cl.Update(context.Background(), p); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !r.ownerLeaseChanged().UpdateFunc(event.UpdateEvent{ObjectOld: old, ObjectNew: p}) {
+		t.Fatalf("expected the owner's new lease to be passed on")
+	}
+	reqs := r.inheritorsOf(context.Background(), p)
+	if len(reqs) != 1 || reqs[0].Name != "child" {
+		t.Fatalf("expected the owner to map to the child, got %v", reqs)
+	}
+	reconcileName(t, r, "child")
+	got := get(t, cl, gvk, "default", "child").GetAnnotations()
+	if got[a.ExpireAt] != expireAt || got[testInheritedFrom] == "" {
+		t.Fatalf("expected the parent's lease to be inherited, got %v", got)
+	}
+}
+
+func TestReconcile_InheritWaitsForOwnerExpiry(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := defaultAnn()
+
+	parent := leasedParent(gvk, "parent", map[string]string{a.TTL: "1h"})
+	r, _ := newInheritWatcher(t, parent, ownedBy(gvk, "child", parent))
+
+	if res := reconcileName(t, r, "child"); res.RequeueAfter != InheritRecheckInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, InheritRecheckInterval)
+	}
+}
+
+func TestOwnerLeaseChanged(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := defaultAnn()
+	r, _ := newInheritWatcher(t)
+	p := r.ownerLeaseChanged()
+
+	old := leasedParent(gvk, "parent", map[string]string{a.TTL: "1h"})
+	relabeled := old.DeepCopy()
+	relabeled.SetLabels(map[string]string{"app": "x"})
+	if p.UpdateFunc(event.UpdateEvent{ObjectOld: old, ObjectNew: relabeled}) {
+		t.Fatalf("expected owner changes outside the lease to be ignored")
+	}
+	renewed := old.DeepCopy()
+	renewed.SetAnnotations(map[string]string{a.TTL: "1h", a.ExpireAt: time.Now().UTC().Format(time.RFC3339)})
+	if !p.UpdateFunc(event.UpdateEvent{ObjectOld: old, ObjectNew: renewed}) {
+		t.Fatalf("expected a new owner expire-at to be passed on")
+	}
+	if p.CreateFunc(event.CreateEvent{Object: old}) || p.DeleteFunc(event.DeleteEvent{Object: old}) {
+		t.Fatalf("expected owner creates and deletes to be ignored")
+	}
+}
+
+func TestOnlyWithTTLAnnotation_OwnedObjectsWithInheritance(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _ := newInheritWatcher(t)
+	child := ownedBy(gvk, "child", leasedParent(gvk, "parent", nil))
+
+	if !r.onlyWithTTLAnnotation().CreateFunc(event.CreateEvent{Object: child}) {
+		t.Fatalf("expected owned objects to be reconciled with inheritance on")
+	}
+	r.InheritLease = false
+	if r.onlyWithTTLAnnotation().CreateFunc(event.CreateEvent{Object: child}) {
+		t.Fatalf("expected owned objects without a lease to be skipped with inheritance off")
+	}
+}
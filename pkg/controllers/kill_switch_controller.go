@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ometrics "object-lease-controller/pkg/metrics"
+	"object-lease-controller/pkg/util"
+)
+
+// Keys read from the kill-switch ConfigMap
+const (
+	KillSwitchFreezeKey = "freeze-deletions"
+	KillSwitchReasonKey = "reason"
+)
+
+// FrozenRequeueInterval is how often an expired lease is rechecked while deletions are frozen
+const FrozenRequeueInterval = time.Minute
+
+// KillSwitchSyncRequeueInterval is how often an expired lease is rechecked
+// until the kill-switch ConfigMap was read
+const KillSwitchSyncRequeueInterval = 5 * time.Second
+
+// KillSwitchReconciler watches the kill-switch ConfigMap and updates the shared KillSwitch
+type KillSwitchReconciler struct {
+	client.Client
+	// Recorder, when set, reports engaging and releasing the switch on the ConfigMap
+	Recorder  events.EventRecorder
+	Namespace string
+	Name      string
+	Switch    *util.KillSwitch
+	Metrics   *ometrics.LeaseMetrics
+}
+
+func (r *KillSwitchReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return builder.ControllerManagedBy(mgr).
+		Named("kill-switch").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return o.GetNamespace() == r.Namespace && o.GetName() == r.Name
+		}))).
+		// Read the ConfigMap once at start, also when it does not exist
+		WatchesRawSource(source.Func(func(ctx context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: r.Namespace, Name: r.Name}})
+			return nil
+		})).
+		Complete(r)
+}
+
+func (r *KillSwitchReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx).WithValues("configmap", req.NamespacedName)
+
+	frozen, reason := false, ""
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, req.NamespacedName, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		// A missing ConfigMap means deletions are allowed
+	} else {
+		frozen, _ = strconv.ParseBool(cm.Data[KillSwitchFreezeKey])
+		reason = cm.Data[KillSwitchReasonKey]
+	}
+
+	if r.Switch.Set(frozen, reason) {
+		if frozen {
+			log.Info("Kill switch engaged, deletions are frozen", "reason", reason)
+		} else {
+			log.Info("Kill switch released, deletions resume")
+		}
+		// A deleted ConfigMap has nothing to report on
+		if r.Recorder != nil && cm.Name != "" {
+			if frozen {
+				r.Recorder.Eventf(cm, nil, "Warning", "DeletionsFrozen", "DeletionsFrozen", "Deletions frozen: %s", reason)
+			} else {
+				r.Recorder.Eventf(cm, nil, "Normal", "DeletionsResumed", "DeletionsResumed", "Deletions resumed")
+			}
+		}
+	}
+	if r.Metrics != nil {
+		if frozen {
+			r.Metrics.DeletionsFrozen.Set(1)
+		} else {
+			r.Metrics.DeletionsFrozen.Set(0)
+		}
+	}
+	return ctrl.Result{}, nil
+}
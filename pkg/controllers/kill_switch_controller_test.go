@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ometrics "object-lease-controller/pkg/metrics"
+	"object-lease-controller/pkg/util"
+)
+
+func TestKillSwitchReconciler_FollowsConfigMap(t *testing.T) {
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kill-switch", Namespace: "ops"},
+		Data:       map[string]string{KillSwitchFreezeKey: "true", KillSwitchReasonKey: "INC-42"},
+	}
+	cl := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(cm).Build()
+	reg := withIsolatedRegistry(t)
+	rec := newFakeEventsRecorder(10)
+	r := &KillSwitchReconciler{
+		Client:    cl,
+		Recorder:  rec,
+		Namespace: "ops",
+		Name:      "kill-switch",
+		Switch:    util.NewKillSwitch(),
+		Metrics:   ometrics.NewLeaseMetrics(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ops", Name: "kill-switch"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if frozen, reason := r.Switch.Frozen(); !frozen || reason != "INC-42" {
+		t.Fatalf("Frozen() = (%v, %q), want (true, INC-42)", frozen, reason)
+	}
+	if v := gaugeValue(t, reg, "object_lease_controller_deletions_frozen"); v != 1 {
+		t.Fatalf("deletions_frozen = %v, want 1", v)
+	}
+	if n := countEvents(rec, "DeletionsFrozen"); n != 1 {
+		t.Fatalf("expected one DeletionsFrozen event, got %d", n)
+	}
+
+	// Deleting the ConfigMap releases the freeze
+	if err := cl.Delete(ctx, cm); err != nil {
+		t.Fatalf("delete error: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if frozen, _ := r.Switch.Frozen(); frozen {
+		t.Fatalf("expected deletions to be allowed without the ConfigMap")
+	}
+	if v := gaugeValue(t, reg, "object_lease_controller_deletions_frozen"); v != 0 {
+		t.Fatalf("deletions_frozen = %v, want 0", v)
+	}
+}
+
+func gaugeValue(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == name {
+			return mf.Metric[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return 0
+}
+
+func TestReconcile_KillSwitchDefersDeletion(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "frozen")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "1h",
+		defaultAnn().LeaseStart: time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.KillSwitch = util.NewKillSwitch()
+	r.KillSwitch.Set(true, "INC-42")
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "frozen"}}
+
+	for i := 0; i < 2; i++ {
+		res, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("reconcile error: %v", err)
+		}
+		if res.RequeueAfter != FrozenRequeueInterval {
+			t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, FrozenRequeueInterval)
+		}
+	}
+	status := get(t, cl, gvk, "default", "frozen").GetAnnotations()[defaultAnn().Status]
+	if status != "Lease expired. Deletion frozen by kill switch: INC-42." {
+		t.Fatalf("unexpected status %q", status)
+	}
+	if n := countEvents(rec, "DeletionFrozen"); n != 1 {
+		t.Fatalf("expected one DeletionFrozen event, got %d", n)
+	}
+
+	// Releasing the switch lets the deletion go ahead
+	r.KillSwitch.Set(false, "")
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound after releasing kill switch, got %v", err)
+	}
+}
+
+func TestReconcile_UnreadKillSwitchHoldsDeletion(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl, _ := newWatcher(t, gvk, newExpiredObj(gvk, "early"))
+	// The controller restarted and has not read the kill switch yet
+	r.KillSwitch = util.NewKillSwitch()
+
+	res := reconcileName(t, r, "early")
+	if res.RequeueAfter != KillSwitchSyncRequeueInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, KillSwitchSyncRequeueInterval)
+	}
+	get(t, cl, gvk, "default", "early")
+
+	// The ConfigMap is missing, deletions are allowed
+	r.KillSwitch.Set(false, "")
+	reconcileName(t, r, "early")
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "early"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound once the kill switch was read, got %v", err)
+	}
+}
@@ -6,13 +6,18 @@ import (
 	"reflect"
 	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/events"
 	controller_runtime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -33,6 +38,65 @@ type LeaseWatcher struct {
 	eventChan   chan util.NamespaceChangeEvent
 	Annotations Annotations
 	Metrics     *ometrics.LeaseMetrics
+	// WarningThresholds are the remaining durations, longest first, at which a
+	// LeaseExpiringSoon warning is sent. Objects can override them.
+	WarningThresholds []time.Duration
+	// DryRun reports expiries with the would-expire annotation and a
+	// LeaseWouldExpire event instead of deleting objects or running jobs
+	DryRun bool
+	// KillSwitch, when engaged, defers all expiry handling
+	KillSwitch *util.KillSwitch
+	// Budget rate limits expiry actions per GVK and per namespace
+	Budget *util.DeletionBudget
+	// Breaker halts expiry actions when too many leases are due at once
+	Breaker *util.CircuitBreaker
+	// DefaultPropagation is the deletion propagation used when the object does
+	// not set one. Empty uses the API server default.
+	DefaultPropagation metav1.DeletionPropagation
+	// CleanupFinalizer adds a finalizer to leased objects with an on-delete-job
+	// so the job also runs when the object is deleted by hand
+	CleanupFinalizer bool
+	// InheritLease gives objects without a lease of their own the expiry of
+	// their closest leased owner
+	InheritLease bool
+	// ClusterScoped is set when the watched kind is cluster-scoped
+	ClusterScoped bool
+	// Selector, when set, limits the controller to objects whose labels
+	// match. It is the opt-in for cluster-scoped kinds.
+	Selector labels.Selector
+	// OpsNamespace is where cleanup jobs for cluster-scoped objects, Namespaces
+	// included, run
+	OpsNamespace string
+	// CalendarDurations adds the months and years of a TTL as calendar months
+	// and years instead of 30 and 365 days
+	CalendarDurations bool
+	// Location is the timezone deadline TTLs are read in when the object does
+	// not set one. Nil is UTC.
+	Location *time.Location
+	// BusinessClock counts the TTL of business-hours leases. Nil uses the
+	// default business hours without holidays.
+	BusinessClock *util.BusinessClock
+	// DeletionWindow, when set, holds expiry actions of objects without a
+	// deletion-window of their own until the window is open
+	DeletionWindow *util.DeletionWindow
+	// MaxRenewals limits how often a lease can be renewed, zero is unlimited.
+	// MaxLifetime, when set, ends every lease that long after the object was
+	// created, however often it was renewed. Objects can only lower them.
+	MaxRenewals int
+	MaxLifetime time.Duration
+	// HistoryLimit is how many transitions the lease-history of an object
+	// keeps, zero turns the history off. HistoryConfigMap, when set, names
+	// the ConfigMap the history of deleted objects is archived in.
+	HistoryLimit     int
+	HistoryConfigMap string
+
+	activity     activityTracker
+	dependencies dependencyWatches
+	// reader reads leased objects from the informer cache of the manager, nil
+	// reads through the client
+	reader client.Reader
+	// apiReader reads from the API server, nil reads through the client
+	apiReader client.Reader
 }
 
 type Annotations struct {
@@ -40,6 +104,68 @@ type Annotations struct {
 	LeaseStart string
 	ExpireAt   string
 	Status     string
+	// StatusJSON is the structured counterpart of Status
+	StatusJSON string
+	// DeleteAt is an optional RFC3339 absolute deadline. When set it takes
+	// precedence over TTL.
+	DeleteAt string
+	// LeaseMode selects fixed (default), sliding or heartbeat leases
+	LeaseMode string
+	// Heartbeat is an RFC3339 timestamp refreshed by an external system for heartbeat leases
+	Heartbeat string
+	// HeartbeatMissed records the heartbeat a HeartbeatMissed warning was sent for
+	HeartbeatMissed string
+	// LeaseClock selects the wall clock (default) or business hours
+	LeaseClock string
+	// DeletionWindow is a cron-like schedule that expiry actions wait for
+	DeletionWindow string
+
+	// Expiry action annotations
+	OnExpire        string
+	OnExpireApplied string
+
+	// Phase records the lease phase (active, hibernated, expired, deleted)
+	Phase string
+	// Two-stage hibernate-then-delete annotations
+	HibernateTTL       string
+	HibernatedReplicas string
+	// ExpiryWarnings overrides the controller's pre-expiry warning thresholds
+	ExpiryWarnings string
+	// WouldExpire is set in dry-run mode to the expiry that would have been enforced
+	WouldExpire string
+	// LeasePaused freezes the lease when "true" on the object or its Namespace
+	LeasePaused string
+	// PausedAt and PausedDuration record the current pause and the total
+	// time spent paused, which is added to the expiry
+	PausedAt       string
+	PausedDuration string
+	// DeletionPropagation selects Foreground, Background or Orphan deletion
+	DeletionPropagation string
+	// LeaseGroup is the label that puts objects in a namespace into one lease
+	// group, LeaseGroupOrder the annotation that orders their deletion
+	LeaseGroup      string
+	LeaseGroupOrder string
+	// ExpireWith references another object the lease ends with
+	ExpireWith string
+	// InheritedFrom records the owner an inherited lease was copied from
+	InheritedFrom string
+	// DrainTimeout bounds how long an expired Namespace waits for its pods to
+	// drain, DrainStartedAt records when the drain started
+	DrainTimeout   string
+	DrainStartedAt string
+	// Timezone is the IANA timezone deadline TTLs of the object are read in
+	Timezone string
+	// Renew is consumed by the controller to renew the lease, by a duration
+	// or from now. RenewCount, LastRenewedBy and LeaseExtension record the
+	// renewals, MaxRenewals and MaxLifetime limit them.
+	Renew          string
+	RenewCount     string
+	LastRenewedBy  string
+	LeaseExtension string
+	MaxRenewals    string
+	MaxLifetime    string
+	// History records the last lease transitions of the object
+	History string
 
 	// Cleanup job annotations
 	OnDeleteJob       string
@@ -50,6 +176,8 @@ type Annotations struct {
 	JobTTL            string
 	JobBackoffLimit   string
 	JobEnvSecrets     string
+	// CleanupJobName records the cleanup job started by the finalizer
+	CleanupJobName string
 }
 
 var (
@@ -59,7 +187,7 @@ var (
 // Only trigger reconcile when relevant annotations change
 func leaseRelevantAnns(u *unstructured.Unstructured, annotations Annotations) map[string]string {
 	anns := u.GetAnnotations()
-	keys := []string{annotations.TTL, annotations.LeaseStart}
+	keys := []string{annotations.TTL, annotations.LeaseStart, annotations.DeleteAt, annotations.LeaseMode, annotations.Heartbeat, annotations.HibernateTTL, annotations.ExpiryWarnings, annotations.LeasePaused, annotations.OnDeleteJob, annotations.ExpireWith, annotations.Timezone, annotations.LeaseClock, annotations.DeletionWindow, annotations.Renew, annotations.MaxRenewals, annotations.MaxLifetime}
 	result := map[string]string{}
 	for _, k := range keys {
 		if v, ok := anns[k]; ok {
@@ -76,9 +204,15 @@ func (r *LeaseWatcher) onlyWithTTLAnnotation() predicate.Funcs {
 			if !ok {
 				return false
 			}
-			anns := obj.GetAnnotations()
-			_, has := anns[r.Annotations.TTL]
-			return has
+			// The cleanup finalizer is released even when the object is no
+			// longer selected
+			if obj.GetDeletionTimestamp() != nil && hasCleanupFinalizer(obj) {
+				return true
+			}
+			if !r.selected(obj) {
+				return false
+			}
+			return r.hasLeaseAnnotation(obj.GetAnnotations()) || r.InheritLease && len(obj.GetOwnerReferences()) > 0
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			oldObj, ok1 := e.ObjectOld.(*unstructured.Unstructured)
@@ -86,9 +220,25 @@ func (r *LeaseWatcher) onlyWithTTLAnnotation() predicate.Funcs {
 			if !ok1 || !ok2 {
 				return false
 			}
+			// A deletion that starts is followed up by the finalizer, also
+			// for objects that left the selector
+			deleting := oldObj.GetDeletionTimestamp() == nil && newObj.GetDeletionTimestamp() != nil
+			if deleting && hasCleanupFinalizer(newObj) {
+				return true
+			}
+			// Objects leaving the selector are left alone, objects joining it
+			// are picked up
+			if !r.selected(newObj) {
+				return false
+			}
+			if !r.selected(oldObj) {
+				return true
+			}
 			old := leaseRelevantAnns(oldObj, r.Annotations)
 			new := leaseRelevantAnns(newObj, r.Annotations)
-			return !reflect.DeepEqual(old, new)
+			regrouped := r.leaseGroup(oldObj) != r.leaseGroup(newObj)
+			reowned := r.InheritLease && !reflect.DeepEqual(oldObj.GetOwnerReferences(), newObj.GetOwnerReferences())
+			return !reflect.DeepEqual(old, new) || r.slidingUpdate(oldObj, newObj) || deleting || regrouped || reowned
 		},
 		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 		GenericFunc: func(e event.GenericEvent) bool { return false },
@@ -108,40 +258,169 @@ func (r *LeaseWatcher) Reconcile(ctx context.Context, req controller_runtime.Req
 	}()
 	log.Info("reconciling lease")
 
-	// Namespace filter
-	if r.Tracker != nil && !r.isNamespaceTracked(req.Namespace) {
-		log.Info("namespace not tracked, skipping", "namespace", req.Namespace)
-		return controller_runtime.Result{}, nil
+	// Namespace filter. A Namespace lease belongs to the namespace itself.
+	ns := req.Namespace
+	if r.isNamespaceLease() {
+		ns = req.Name
 	}
+	tracked := r.Tracker == nil || r.isNamespaceTracked(ns)
 
 	// Get object
 	obj, err := r.getObject(ctx, req.NamespacedName)
 	if err != nil {
+		r.activity.forget(req.NamespacedName)
+		if r.Breaker != nil {
+			r.Breaker.Forget(req.NamespacedName)
+		}
 		// not found is not an error
 		return controller_runtime.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// If no TTL, clean and exit
+	// Already being deleted, run the cleanup job the finalizer holds the
+	// object for. Objects no longer managed are let go without the job, the
+	// deletion would hang on the finalizer otherwise.
+	if obj.GetDeletionTimestamp() != nil && hasCleanupFinalizer(obj) {
+		if !tracked || !r.selected(obj) {
+			log.Info("object no longer managed, releasing the cleanup finalizer")
+			return r.releaseCleanupFinalizer(ctx, obj)
+		}
+		return r.finalizeCleanup(ctx, obj)
+	}
+
+	if !tracked {
+		log.Info("namespace not tracked, skipping", "namespace", ns)
+		return controller_runtime.Result{}, nil
+	}
+
+	// Follow up on deletions the controller started
+	if obj.GetDeletionTimestamp() != nil {
+		return r.awaitDeletion(ctx, obj), nil
+	}
+
+	if !r.selected(obj) {
+		log.Info("object does not match the opt-in selector, skipping")
+		return controller_runtime.Result{}, nil
+	}
+
+	if err := r.syncCleanupFinalizer(ctx, obj); err != nil {
+		return controller_runtime.Result{}, err
+	}
+
+	// Objects without a lease of their own may inherit one from their owners
+	if r.inheritsLease(obj) {
+		return r.inheritLease(ctx, obj)
+	}
+
+	// If no TTL or delete-at, clean and exit
 	if r.noTTL(obj) {
+		if r.isHibernated(obj) {
+			if err := r.resume(ctx, obj); err != nil {
+				return controller_runtime.Result{}, err
+			}
+		}
+		r.untrackExpiry(obj)
 		r.cleanupLeaseAnnotations(ctx, obj)
 		return controller_runtime.Result{}, nil
 	}
 
+	// A lease of its own replaces an inherited one
+	r.removeAnnotations(ctx, obj, r.Annotations.InheritedFrom)
+
 	now := time.Now().UTC()
 	startAt := r.ensureLeaseStart(ctx, obj, now)
+	r.trackTTLChange(ctx, obj)
 
-	ttl, err := util.ParseFlexibleDuration(obj.GetAnnotations()[r.Annotations.TTL])
-	if err != nil {
-		r.markInvalidTTL(ctx, obj, err)
+	if _, err := util.ParseLeaseMode(obj.GetAnnotations()[r.Annotations.LeaseMode]); err != nil && r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", "InvalidLeaseMode", "InvalidLeaseMode", "%v, using fixed lease", err)
+	}
+	if _, err := util.ParseLeaseClock(obj.GetAnnotations()[r.Annotations.LeaseClock]); err != nil && r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", "InvalidLeaseClock", "InvalidLeaseClock", "%v, using the wall clock", err)
+	}
+
+	mode := r.leaseMode(obj)
+	switch mode {
+	case util.LeaseModeSliding:
+		// Sliding leases restart whenever the object is edited by someone else
+		defer r.activity.record(obj)
+		startAt = r.slideLeaseStart(ctx, obj, startAt, now)
+	case util.LeaseModeHeartbeat:
+		// Heartbeat leases run from the last heartbeat
+		var ok bool
+		if startAt, ok = r.heartbeatStart(ctx, obj, startAt); !ok {
+			return controller_runtime.Result{}, nil
+		}
+	}
+
+	var expireAt time.Time
+	if deleteAt := obj.GetAnnotations()[r.Annotations.DeleteAt]; r.Annotations.DeleteAt != "" && deleteAt != "" {
+		// An explicit deadline wins over the TTL
+		t, err := time.Parse(time.RFC3339, deleteAt)
+		if err != nil {
+			r.markInvalidDeleteAt(ctx, obj, err)
+			return controller_runtime.Result{}, nil
+		}
+		expireAt = t.UTC()
+	} else {
+		expiry, err := r.leaseExpiry(obj)
+		if err != nil {
+			r.markInvalidTTL(ctx, obj, err)
+			return controller_runtime.Result{}, nil
+		}
+		expireAt = expiry(startAt)
+	}
+
+	// Renewals extend the lease within the renewal limits
+	expireAt, ok := r.applyRenewals(ctx, obj, expireAt, now)
+	if !ok {
 		return controller_runtime.Result{}, nil
 	}
 
-	expireAt := startAt.Add(ttl)
-	if now.After(expireAt) {
+	// Members of a lease group share one lease
+	if group := r.leaseGroup(obj); group != "" {
+		startAt, expireAt = r.groupLease(ctx, obj, group, startAt, expireAt)
+	}
+
+	// A paused lease does not count down
+	expireAt, paused := r.applyPause(ctx, obj, expireAt, now)
+	// Neither pauses nor renewals outlast the max-lifetime
+	deadline, limited := r.lifetimeDeadline(obj)
+	if limited && expireAt.After(deadline) {
+		expireAt, paused = deadline, paused && now.Before(deadline)
+	}
+	if paused {
+		res := r.setPaused(ctx, obj, expireAt, now)
+		if limited {
+			res.RequeueAfter = deadline.Sub(now)
+		}
+		return res, nil
+	}
+
+	// A lease that expires with another object ends once that object is gone
+	expireAt, valid, err := r.dependencyExpiry(ctx, obj, expireAt, now)
+	if err != nil || !valid {
+		return controller_runtime.Result{}, err
+	}
+
+	if r.Breaker != nil {
+		r.Breaker.Observe(req.NamespacedName, expireAt)
+	}
+
+	if !now.Before(expireAt) {
 		return r.handleExpired(ctx, obj, expireAt)
 	}
 
-	return r.setActive(ctx, obj, expireAt, now), nil
+	// Renewed while hibernated, bring the workload back
+	if r.isHibernated(obj) {
+		if err := r.resume(ctx, obj); err != nil {
+			return controller_runtime.Result{}, err
+		}
+	}
+
+	res = r.setActive(ctx, obj, expireAt, now)
+	if mode == util.LeaseModeHeartbeat {
+		res = r.checkHeartbeat(ctx, obj, startAt, expireAt, now, res)
+	}
+	return res, nil
 }
 
 // ---------- helpers ----------
@@ -167,15 +446,78 @@ func (r *LeaseWatcher) getObject(ctx context.Context, key client.ObjectKey) (*un
 	return obj, nil
 }
 
+// selected reports whether obj matches the opt-in selector, if there is one
+func (r *LeaseWatcher) selected(obj *unstructured.Unstructured) bool {
+	return r.Selector == nil || r.Selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// leaseExpiry parses the TTL of obj into the function that computes its
+// expiry from the lease start. The TTL is either a duration or a deadline.
+func (r *LeaseWatcher) leaseExpiry(obj *unstructured.Unstructured) (func(time.Time) time.Time, error) {
+	ttl := obj.GetAnnotations()[r.Annotations.TTL]
+	clock := r.leaseClock(obj)
+	if !util.IsDeadlineExpression(ttl) && clock == util.LeaseClockWall {
+		return util.LeaseExpiry(ttl, r.CalendarDurations)
+	}
+	loc, err := r.leaseLocation(obj)
+	if err != nil {
+		return nil, err
+	}
+	if util.IsDeadlineExpression(ttl) {
+		return util.ParseDeadline(ttl, loc, r.businessClock())
+	}
+	return r.businessExpiry(ttl, loc)
+}
+
+// leaseLocation returns the timezone deadlines of obj are read in, its
+// lease-timezone before the controller default
+func (r *LeaseWatcher) leaseLocation(obj *unstructured.Unstructured) (*time.Location, error) {
+	if name := obj.GetAnnotations()[r.Annotations.Timezone]; r.Annotations.Timezone != "" && name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lease-timezone %q: %w", name, err)
+		}
+		return loc, nil
+	}
+	if r.Location != nil {
+		return r.Location, nil
+	}
+	return time.UTC, nil
+}
+
+// leaseMode returns the lease mode requested by the object, falling back to fixed
+func (r *LeaseWatcher) leaseMode(obj *unstructured.Unstructured) util.LeaseMode {
+	if r.Annotations.LeaseMode == "" {
+		return util.LeaseModeFixed
+	}
+	mode, _ := util.ParseLeaseMode(obj.GetAnnotations()[r.Annotations.LeaseMode])
+	return mode
+}
+
 func (r *LeaseWatcher) noTTL(obj *unstructured.Unstructured) bool {
 	anns := obj.GetAnnotations()
+	if r.Annotations.DeleteAt != "" && anns[r.Annotations.DeleteAt] != "" {
+		return false
+	}
 	return anns[r.Annotations.TTL] == ""
 }
 
+// hasLeaseAnnotation reports whether the annotations carry a TTL or a delete-at deadline
+func (r *LeaseWatcher) hasLeaseAnnotation(anns map[string]string) bool {
+	if _, has := anns[r.Annotations.TTL]; has {
+		return true
+	}
+	if r.Annotations.DeleteAt == "" {
+		return false
+	}
+	_, has := anns[r.Annotations.DeleteAt]
+	return has
+}
+
 func (r *LeaseWatcher) cleanupLeaseAnnotations(ctx context.Context, obj *unstructured.Unstructured) {
 	anns := obj.GetAnnotations()
 	cleaned := false
-	for _, k := range []string{r.Annotations.ExpireAt, r.Annotations.Status, r.Annotations.LeaseStart} {
+	for _, k := range []string{r.Annotations.ExpireAt, r.Annotations.Status, r.Annotations.StatusJSON, r.Annotations.LeaseStart, r.Annotations.OnExpireApplied, r.Annotations.Phase, r.Annotations.WouldExpire, r.Annotations.HeartbeatMissed, r.Annotations.PausedAt, r.Annotations.PausedDuration, r.Annotations.InheritedFrom, r.Annotations.DrainStartedAt, r.Annotations.LeaseExtension} {
 		if _, ok := anns[k]; ok {
 			delete(anns, k)
 			cleaned = true
@@ -190,6 +532,7 @@ func (r *LeaseWatcher) cleanupLeaseAnnotations(ctx context.Context, obj *unstruc
 	if r.Recorder != nil {
 		r.Recorder.Eventf(obj, nil, "Normal", "LeaseAnnotationsCleaned", "LeaseAnnotationsCleaned", "Removed lease annotations because TTL is missing")
 	}
+	r.recordHistory(ctx, obj, "LeaseAnnotationsCleaned", "Removed lease annotations because TTL is missing", "")
 }
 
 func (r *LeaseWatcher) ensureLeaseStart(ctx context.Context, obj *unstructured.Unstructured, now time.Time) time.Time {
@@ -204,14 +547,17 @@ func (r *LeaseWatcher) ensureLeaseStart(ctx context.Context, obj *unstructured.U
 		if r.Recorder != nil {
 			r.Recorder.Eventf(obj, nil, "Warning", "LeaseStartReset", "LeaseStartReset", "Invalid lease-start, reset to now")
 		}
+		r.recordHistory(ctx, obj, "LeaseStartReset", "Invalid lease-start, reset to now", "")
 		return now
 	}
-	// missing, set
+	// missing, set. A new lease does not carry over earlier pauses and renewals.
+	r.removeAnnotations(ctx, obj, r.Annotations.PausedDuration, r.Annotations.LeaseExtension)
 	anns[r.Annotations.LeaseStart] = now.Format(time.RFC3339)
 	r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.LeaseStart: anns[r.Annotations.LeaseStart]})
 	if r.Recorder != nil {
 		r.Recorder.Eventf(obj, nil, "Normal", "LeaseStarted", "LeaseStarted", "Lease started")
 	}
+	r.recordHistory(ctx, obj, "LeaseStarted", "Lease started", util.AnnotationManager(obj, r.Annotations.TTL))
 	if r.Metrics != nil {
 		r.Metrics.LeasesStarted.Inc()
 	}
@@ -219,45 +565,132 @@ func (r *LeaseWatcher) ensureLeaseStart(ctx context.Context, obj *unstructured.U
 }
 
 func (r *LeaseWatcher) markInvalidTTL(ctx context.Context, obj *unstructured.Unstructured, parseErr error) {
-	msg := fmt.Sprintf("Invalid TTL: %v", parseErr)
-	r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.Status: msg})
-	if r.Recorder != nil {
-		r.Recorder.Eventf(obj, nil, "Warning", "InvalidTTL", "InvalidTTL", "%s", msg)
-	}
+	r.markInvalid(ctx, obj, "InvalidTTL", fmt.Sprintf("Invalid TTL: %v", parseErr))
 	if r.Metrics != nil {
 		r.Metrics.InvalidTTL.Inc()
 	}
 }
 
+func (r *LeaseWatcher) markInvalidDeleteAt(ctx context.Context, obj *unstructured.Unstructured, parseErr error) {
+	r.markInvalid(ctx, obj, "InvalidDeleteAt", fmt.Sprintf("Invalid delete-at: %v", parseErr))
+	if r.Metrics != nil {
+		r.Metrics.InvalidDeleteAt.Inc()
+	}
+}
+
+// markInvalid writes a validation error to the status annotation and emits a warning event
+func (r *LeaseWatcher) markInvalid(ctx context.Context, obj *unstructured.Unstructured, reason, msg string) {
+	r.setStatus(ctx, obj, reason, msg, nil)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", reason, reason, "%s", msg)
+	}
+	r.recordHistory(ctx, obj, reason, msg, "")
+}
+
 //nolint:unparam
 func (r *LeaseWatcher) handleExpired(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time) (controller_runtime.Result, error) {
 	log := logger.FromContext(ctx)
-	leaseStatus := "Lease expired. Deleting object."
-	r.updateAnnotations(ctx, obj, map[string]string{
+	anns := obj.GetAnnotations()
+
+	actionName, action, actionArg, err := util.ParseExpiryAction(anns[r.Annotations.OnExpire])
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidOnExpire", fmt.Sprintf("Invalid on-expire: %v", err))
+		return controller_runtime.Result{}, nil
+	}
+	propagation, err := r.propagationPolicy(obj)
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidDeletionPropagation", fmt.Sprintf("Invalid deletion-propagation: %v", err))
+		return controller_runtime.Result{}, nil
+	}
+	// Actions other than delete leave the object in place, only apply them once per expiry
+	if r.Annotations.OnExpireApplied != "" && anns[r.Annotations.OnExpireApplied] == expireAt.Format(time.RFC3339) {
+		r.expiryHandled(obj)
+		return controller_runtime.Result{}, nil
+	}
+
+	if r.DryRun {
+		r.wouldExpire(ctx, obj, expireAt, actionName)
+		return controller_runtime.Result{}, nil
+	}
+	if r.KillSwitch != nil {
+		// Until the kill switch was read it may be engaged, wait for it
+		if !r.KillSwitch.Synced() {
+			log.Info("Kill switch not read yet, holding the expiry")
+			return controller_runtime.Result{RequeueAfter: KillSwitchSyncRequeueInterval}, nil
+		}
+		if frozen, reason := r.KillSwitch.Frozen(); frozen {
+			leaseStatus := "Lease expired. Deletion frozen by kill switch."
+			if reason != "" {
+				leaseStatus = fmt.Sprintf("Lease expired. Deletion frozen by kill switch: %s.", reason)
+			}
+			return r.deferExpiry(ctx, obj, expireAt, "DeletionFrozen", leaseStatus, FrozenRequeueInterval), nil
+		}
+	}
+
+	// Lease group members are handled in lease-group-order
+	if group := r.leaseGroup(obj); group != "" {
+		if res, wait := r.awaitGroupOrder(ctx, obj, group, expireAt); wait {
+			return res, nil
+		}
+	}
+
+	if actionName == util.ExpiryActionHibernate {
+		// Waiting out the grace period does not touch the object
+		if !r.isHibernated(obj) {
+			if res, ok := r.admitExpiry(ctx, obj, expireAt); !ok {
+				return res, nil
+			}
+		}
+		res, done, err := r.hibernate(ctx, obj, expireAt)
+		if done || err != nil {
+			if err == nil {
+				// Scaled down, not due again until the grace period ends
+				r.expiryHandled(obj)
+			}
+			return res, err
+		}
+		// Grace period is over, fall through to deletion
+		actionName, action, actionArg, _ = util.ParseExpiryAction(util.ExpiryActionDelete)
+	}
+	// A drain in progress was admitted when it started
+	if !r.draining(obj) {
+		if res, ok := r.admitExpiry(ctx, obj, expireAt); !ok {
+			return res, nil
+		}
+	}
+	// Namespaces are drained before they are deleted
+	if r.isNamespaceLease() && actionName == util.ExpiryActionDelete {
+		if res, wait := r.drainNamespace(ctx, obj, expireAt); wait {
+			return res, nil
+		}
+	}
+	if actionName == util.ExpiryActionDelete && actionArg == "" {
+		actionArg = string(propagation)
+	}
+
+	leaseStatus, phase := "Lease expired. Deleting object.", PhaseDeleted
+	if actionName != util.ExpiryActionDelete {
+		leaseStatus, phase = fmt.Sprintf("Lease expired. Applying on-expire action %s.", actionName), PhaseExpired
+	}
+	r.setStatus(ctx, obj, "LeaseExpired", leaseStatus, map[string]string{
 		r.Annotations.ExpireAt: expireAt.Format(time.RFC3339),
-		r.Annotations.Status:   leaseStatus,
+		r.Annotations.Phase:    phase,
 	})
 	if r.Recorder != nil {
 		r.Recorder.Eventf(obj, nil, "Normal", "LeaseExpired", "LeaseExpired", "%s", leaseStatus)
 	}
+	r.recordHistory(ctx, obj, "LeaseExpired", leaseStatus, "")
 	if r.Metrics != nil {
 		r.Metrics.LeasesExpired.Inc()
 	}
 
-	// Check for cleanup job configuration
-	anns := obj.GetAnnotations()
-	annotationKeys := map[string]string{
-		"OnDeleteJob":       r.Annotations.OnDeleteJob,
-		"JobServiceAccount": r.Annotations.JobServiceAccount,
-		"JobImage":          r.Annotations.JobImage,
-		"JobWait":           r.Annotations.JobWait,
-		"JobTimeout":        r.Annotations.JobTimeout,
-		"JobTTL":            r.Annotations.JobTTL,
-		"JobBackoffLimit":   r.Annotations.JobBackoffLimit,
-		"JobEnvSecrets":     r.Annotations.JobEnvSecrets,
+	// Check for cleanup job configuration. With the cleanup finalizer the job
+	// runs once the deletion has started instead, and a draining namespace
+	// started it already.
+	config, err := r.cleanupJobConfig(obj)
+	if actionName == util.ExpiryActionDelete && (hasCleanupFinalizer(obj) || r.draining(obj)) {
+		config, err = nil, nil
 	}
-
-	config, err := util.ParseCleanupJobConfig(anns, annotationKeys)
 	if err != nil {
 		// Invalid configuration - log error, emit event, proceed with deletion
 		log.Error(err, "Invalid cleanup job configuration")
@@ -278,37 +711,96 @@ func (r *LeaseWatcher) handleExpired(ctx context.Context, obj *unstructured.Unst
 		// Always proceed with deletion regardless of cleanup job outcome
 	}
 
-	if err := util.DeleteWithUIDPrecondition(ctx, r.Client, obj); client.IgnoreNotFound(err) != nil {
+	// The object and its history are about to go
+	if actionName == util.ExpiryActionDelete {
+		if err := r.archiveHistory(ctx, obj); err != nil {
+			log.Error(err, "Unable to archive the lease history", "configmap", r.HistoryConfigMap)
+		}
+	}
+
+	if err := action.Execute(ctx, r.Client, obj, actionArg); client.IgnoreNotFound(err) != nil {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "ExpiryActionFailed", "ExpiryActionFailed", "On-expire action %s failed: %v", actionName, err)
+		}
+		if r.Metrics != nil {
+			r.Metrics.ExpiryActions.WithLabelValues(actionName, "failed").Inc()
+		}
 		return controller_runtime.Result{}, err
 	}
+	if r.Metrics != nil {
+		r.Metrics.ExpiryActions.WithLabelValues(actionName, "succeeded").Inc()
+	}
+	// The expiry is handled, it counts towards a mass expiry until the
+	// breaker's window has passed
+	r.expiryHandled(obj)
+	if actionName == util.ExpiryActionDelete {
+		if actionArg == string(metav1.DeletePropagationForeground) {
+			// The object stays until its dependents are gone, follow its progress
+			return controller_runtime.Result{RequeueAfter: ForegroundRequeueInterval}, nil
+		}
+		return controller_runtime.Result{}, nil
+	}
+
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "ExpiryActionApplied", "ExpiryActionApplied", "Applied on-expire action %s", actionName)
+	}
+	r.recordHistory(ctx, obj, "ExpiryActionApplied", fmt.Sprintf("Applied on-expire action %s", actionName), "")
+	r.setStatus(ctx, obj, "ExpiryActionApplied", fmt.Sprintf("Lease expired. Applied on-expire action %s.", actionName), map[string]string{
+		r.Annotations.OnExpireApplied: expireAt.Format(time.RFC3339),
+	})
 	return controller_runtime.Result{}, nil
 }
 
+// cleanupJobKeys maps cleanup job settings to their annotation keys
+func (r *LeaseWatcher) cleanupJobKeys() map[string]string {
+	return map[string]string{
+		"OnDeleteJob":       r.Annotations.OnDeleteJob,
+		"JobServiceAccount": r.Annotations.JobServiceAccount,
+		"JobImage":          r.Annotations.JobImage,
+		"JobWait":           r.Annotations.JobWait,
+		"JobTimeout":        r.Annotations.JobTimeout,
+		"JobTTL":            r.Annotations.JobTTL,
+		"JobBackoffLimit":   r.Annotations.JobBackoffLimit,
+		"JobEnvSecrets":     r.Annotations.JobEnvSecrets,
+	}
+}
+
+// cleanupJobConfig parses the cleanup job of obj and decides where it runs
+func (r *LeaseWatcher) cleanupJobConfig(obj *unstructured.Unstructured) (*util.CleanupJobConfig, error) {
+	config, err := util.ParseCleanupJobConfig(obj.GetAnnotations(), r.cleanupJobKeys())
+	if err != nil || config == nil {
+		return config, err
+	}
+	if config.Namespace, err = r.cleanupJobNamespace(obj); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// cleanupJobNamespace returns the namespace the cleanup job of obj runs in.
+// Cluster-scoped objects have no namespace, and a Namespace is about to be
+// deleted, so their jobs run in the ops namespace.
+func (r *LeaseWatcher) cleanupJobNamespace(obj *unstructured.Unstructured) (string, error) {
+	if !r.ClusterScoped && !r.isNamespaceLease() {
+		return obj.GetNamespace(), nil
+	}
+	if r.OpsNamespace == "" {
+		return "", fmt.Errorf("cleanup jobs for cluster-scoped objects need an ops namespace")
+	}
+	if r.isNamespaceLease() && r.OpsNamespace == obj.GetName() {
+		return "", fmt.Errorf("the ops namespace %s cannot run its own cleanup job", r.OpsNamespace)
+	}
+	return r.OpsNamespace, nil
+}
+
 // executeCleanupJob creates and optionally waits for a cleanup job
 func (r *LeaseWatcher) executeCleanupJob(ctx context.Context, obj *unstructured.Unstructured, config *util.CleanupJobConfig, expireAt time.Time) error {
 	log := logger.FromContext(ctx)
 	jobStart := time.Now()
 
-	// Parse lease start time
-	anns := obj.GetAnnotations()
-	leaseStartStr := anns[r.Annotations.LeaseStart]
-	leaseStartedAt, err := time.Parse(time.RFC3339, leaseStartStr)
-	if err != nil {
-		leaseStartedAt = time.Now() // Fallback
-	}
-
-	// Create the cleanup job
-	job, err := util.CreateCleanupJob(ctx, r.Client, obj, r.GVK, config, leaseStartedAt, expireAt)
+	job, err := r.createCleanupJob(ctx, obj, config, expireAt)
 	if err != nil {
-		return fmt.Errorf("failed to create cleanup job: %w", err)
-	}
-
-	log.Info("Cleanup job created", "job", job.Name, "namespace", job.Namespace)
-	if r.Recorder != nil {
-		r.Recorder.Eventf(obj, nil, "Normal", "CleanupJobCreated", "CleanupJobCreated", "Created cleanup job: %s", job.Name)
-	}
-	if r.Metrics != nil {
-		r.Metrics.CleanupJobsCreated.Inc()
+		return err
 	}
 
 	// If wait is enabled, wait for job completion
@@ -339,13 +831,92 @@ func (r *LeaseWatcher) executeCleanupJob(ctx context.Context, obj *unstructured.
 	return nil
 }
 
+// createCleanupJob creates the cleanup job for obj without waiting for it
+func (r *LeaseWatcher) createCleanupJob(ctx context.Context, obj *unstructured.Unstructured, config *util.CleanupJobConfig, expireAt time.Time) (*batchv1.Job, error) {
+	log := logger.FromContext(ctx)
+
+	// Parse lease start time
+	anns := obj.GetAnnotations()
+	leaseStartStr := anns[r.Annotations.LeaseStart]
+	leaseStartedAt, err := time.Parse(time.RFC3339, leaseStartStr)
+	if err != nil {
+		leaseStartedAt = time.Now() // Fallback
+	}
+
+	// Create the cleanup job
+	job, err := util.CreateCleanupJob(ctx, r.Client, obj, r.GVK, config, leaseStartedAt, expireAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cleanup job: %w", err)
+	}
+
+	log.Info("Cleanup job created", "job", job.Name, "namespace", job.Namespace)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "CleanupJobCreated", "CleanupJobCreated", "Created cleanup job: %s", job.Name)
+	}
+	if r.Metrics != nil {
+		r.Metrics.CleanupJobsCreated.Inc()
+	}
+	return job, nil
+}
+
+// deferExpiry leaves an expired object in place and checks it again after
+// requeue. The Warning event is only sent when the status changes, so each
+// object is told once why it is being kept.
+func (r *LeaseWatcher) deferExpiry(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time, reason, leaseStatus string, requeue time.Duration) controller_runtime.Result {
+	if obj.GetAnnotations()[r.Annotations.Status] != leaseStatus && r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", reason, reason, "%s", leaseStatus)
+	}
+	r.setStatus(ctx, obj, reason, leaseStatus, map[string]string{
+		r.Annotations.ExpireAt: expireAt.Format(time.RFC3339),
+	})
+	return controller_runtime.Result{RequeueAfter: requeue}
+}
+
 func (r *LeaseWatcher) setActive(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time, now time.Time) controller_runtime.Result {
-	status := fmt.Sprintf("Lease active. Expires at %s UTC.", expireAt.Format(time.RFC3339))
-	r.updateAnnotations(ctx, obj, map[string]string{
+	reason, status := "LeaseActive", fmt.Sprintf("Lease active. Expires at %s UTC.", expireAt.Format(time.RFC3339))
+
+	// Requeue at each warning threshold so owners get advance notice
+	remaining, toWall := r.remainingOnClock(obj, expireAt, now)
+	crossed, warn, requeue := crossedWarning(r.warningThresholds(obj), remaining)
+	requeue = toWall(requeue)
+	if warn {
+		left := r.formatDuration(obj, crossed)
+		reason, status = "LeaseExpiringSoon", fmt.Sprintf("Lease expiring soon. Expires at %s UTC, less than %s remaining.", expireAt.Format(time.RFC3339), left)
+		// The status annotation records which warning was already sent
+		if obj.GetAnnotations()[r.Annotations.Status] != status {
+			if r.Recorder != nil {
+				r.Recorder.Eventf(obj, nil, "Warning", "LeaseExpiringSoon", "LeaseExpiringSoon", "Lease expires at %s, less than %s remaining", expireAt.Format(time.RFC3339), left)
+			}
+			if r.Metrics != nil {
+				r.Metrics.ExpiryWarnings.Inc()
+			}
+		}
+	}
+
+	r.clearWouldExpire(ctx, obj)
+	r.setStatus(ctx, obj, reason, status, map[string]string{
 		r.Annotations.ExpireAt: expireAt.Format(time.RFC3339),
-		r.Annotations.Status:   status,
+		r.Annotations.Phase:    PhaseActive,
 	})
-	return controller_runtime.Result{RequeueAfter: expireAt.Sub(now)}
+	return controller_runtime.Result{RequeueAfter: requeue}
+}
+
+// removeAnnotations deletes the given keys, skipping unconfigured (empty) and absent ones
+func (r *LeaseWatcher) removeAnnotations(ctx context.Context, obj *unstructured.Unstructured, keys ...string) {
+	anns := obj.GetAnnotations()
+	base := obj.DeepCopy()
+	removed := false
+	for _, k := range keys {
+		if _, ok := anns[k]; k != "" && ok {
+			delete(anns, k)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	obj.SetAnnotations(anns)
+	_ = r.Patch(ctx, obj, client.MergeFrom(base))
 }
 
 func (r *LeaseWatcher) updateAnnotations(ctx context.Context, obj *unstructured.Unstructured, newAnns map[string]string) {
@@ -355,6 +926,10 @@ func (r *LeaseWatcher) updateAnnotations(ctx context.Context, obj *unstructured.
 		anns = map[string]string{}
 	}
 	for k, v := range newAnns {
+		// Optional annotations that are not configured have an empty key
+		if k == "" {
+			continue
+		}
 		anns[k] = v
 	}
 	obj.SetAnnotations(anns)
@@ -380,9 +955,52 @@ func (r *LeaseWatcher) SetupWithManager(mgr manager.Manager) error {
 	obj := &unstructured.Unstructured{}
 	obj.SetGroupVersionKind(r.GVK)
 
-	return controller_runtime.NewControllerManagedBy(mgr).
-		For(obj, builder.WithPredicates(r.onlyWithTTLAnnotation())).
-		Complete(r)
+	if r.Annotations.ExpireWith != "" {
+		// Maps deletions of referenced objects back to the objects that expire with them
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), obj, ExpireWithIndex, r.expireWithIndexValue); err != nil {
+			return err
+		}
+	}
+
+	if r.InheritLease && r.Annotations.InheritedFrom != "" {
+		// Maps lease changes of owners back to the objects that inherit from them
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), obj, OwnerIndex, ownerIndexValue); err != nil {
+			return err
+		}
+	}
+
+	b := controller_runtime.NewControllerManagedBy(mgr).
+		For(obj, builder.WithPredicates(r.onlyWithTTLAnnotation()))
+	if r.Annotations.LeasePaused != "" && !r.ClusterScoped {
+		// Pausing or unpausing a Namespace affects every lease in it
+		b = b.Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.leasesInNamespace), builder.WithPredicates(r.namespacePauseChanged()))
+	}
+	// Kinds referenced by expire-with and owner kinds are watched once they
+	// are first seen
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+	r.dependencies.controller, r.dependencies.cache = c, mgr.GetCache()
+	r.reader, r.apiReader = mgr.GetCache(), mgr.GetAPIReader()
+	return nil
+}
+
+// cached returns the reader for leased objects, the informer cache once the
+// watcher is set up
+func (r *LeaseWatcher) cached() client.Reader {
+	if r.reader != nil {
+		return r.reader
+	}
+	return r.Client
+}
+
+// live returns the reader that bypasses the cache
+func (r *LeaseWatcher) live() client.Reader {
+	if r.apiReader != nil {
+		return r.apiReader
+	}
+	return r.Client
 }
 
 // handleNamespaceEvents listens for tracker events and triggers reconciliation for new namespaces
@@ -403,7 +1021,7 @@ func (r *LeaseWatcher) handleNamespaceEvents(mgr clientProvider) {
 				for _, obj := range list.Items {
 					anns := obj.GetAnnotations()
 					if anns != nil {
-						if _, has := anns[r.Annotations.TTL]; has {
+						if r.hasLeaseAnnotation(anns) {
 							req := controller_runtime.Request{
 								NamespacedName: client.ObjectKeyFromObject(&obj),
 							}
@@ -18,7 +18,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -45,6 +47,10 @@ func defaultAnn() Annotations {
 		LeaseStart: "object-lease-controller.ullberg.io/lease-start",
 		ExpireAt:   "object-lease-controller.ullberg.io/expire-at",
 		Status:     "object-lease-controller.ullberg.io/lease-status",
+		DeleteAt:   "object-lease-controller.ullberg.io/delete-at",
+
+		OnExpire:        "object-lease-controller.ullberg.io/on-expire",
+		OnExpireApplied: "object-lease-controller.ullberg.io/on-expire-applied",
 	}
 }
 
@@ -215,6 +221,14 @@ func TestOnlyWithTTLAnnotation_Update(t *testing.T) {
 	if !pred.UpdateFunc(evAdd) {
 		t.Errorf("UpdateFunc(TTL added) = false, want true")
 	}
+
+	// A deletion that starts should trigger so the cleanup finalizer can run
+	deleting := baseOld.DeepCopy()
+	now := metav1.Now()
+	deleting.SetDeletionTimestamp(&now)
+	if !pred.UpdateFunc(event.UpdateEvent{ObjectOld: baseOld, ObjectNew: deleting}) {
+		t.Errorf("UpdateFunc(deletion started) = false, want true")
+	}
 }
 
 func TestOnlyWithTTLAnnotation_Delete_Generic(t *testing.T) {
@@ -1602,3 +1616,392 @@ func (f *fakeManager) GetLogger() logr.Logger
 func (f *fakeManager) GetControllerOptions() config.Controller                  { return config.Controller{} }
 func (f *fakeManager) GetConverterRegistry() conversion.Registry                { return nil }
 func (f *fakeManager) GetEventRecorder(name string) events.EventRecorder        { return nil }
+
+// delete-at sets expire-at to the absolute deadline
+func TestReconcile_DeleteAtSetsExpireAt(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	deadline := time.Now().UTC().Add(3 * time.Hour).Truncate(time.Second)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "da1")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().DeleteAt: deadline.Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+
+	res, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "da1"}})
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "da1").GetAnnotations()
+	if got[defaultAnn().ExpireAt] != deadline.Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q, want %q", got[defaultAnn().ExpireAt], deadline.Format(time.RFC3339))
+	}
+	if got[defaultAnn().LeaseStart] == "" {
+		t.Fatalf("expected lease-start to be set")
+	}
+	if res.RequeueAfter <= 0 {
+		t.Fatalf("expected positive RequeueAfter, got %v", res.RequeueAfter)
+	}
+}
+
+// delete-at takes precedence over ttl
+func TestReconcile_DeleteAtOverridesTTL(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	deadline := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Second)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "da2")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:      "1h",
+		defaultAnn().DeleteAt: deadline.Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "da2"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "da2").GetAnnotations()
+	if got[defaultAnn().ExpireAt] != deadline.Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q, want delete-at %q", got[defaultAnn().ExpireAt], deadline.Format(time.RFC3339))
+	}
+}
+
+// delete-at in the past deletes the object
+func TestReconcile_DeleteAtInPastDeletesObject(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "da3")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().DeleteAt: time.Now().UTC().Add(-time.Minute).Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "da3"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	err := cl.Get(ctx, types.NamespacedName{Namespace: "default", Name: "da3"}, out)
+	if err == nil || !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound after delete, got %v", err)
+	}
+}
+
+// Invalid delete-at writes status and emits an event without deleting
+func TestReconcile_InvalidDeleteAt_StatusOnly(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	reg := withIsolatedRegistry(t)
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "da4")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:      "1s",
+		defaultAnn().DeleteAt: "next tuesday",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "da4"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "da4").GetAnnotations()
+	if !strings.Contains(got[defaultAnn().Status], "Invalid delete-at") {
+		t.Fatalf("lease-status should mention Invalid delete-at, got %q", got[defaultAnn().Status])
+	}
+	if got[defaultAnn().ExpireAt] != "" {
+		t.Fatalf("expire-at should be empty, got %q", got[defaultAnn().ExpireAt])
+	}
+
+	found := false
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, "InvalidDeleteAt") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected InvalidDeleteAt event")
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "object_lease_controller_invalid_delete_at_total" {
+			if mf.Metric[0].GetCounter().GetValue() != 1 {
+				t.Fatalf("invalid_delete_at_total = %v, want 1", mf.Metric[0].GetCounter().GetValue())
+			}
+			return
+		}
+	}
+	t.Fatalf("invalid_delete_at_total not found in metrics")
+}
+
+func TestOnlyWithTTLAnnotation_CreateWithDeleteAt(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, _ := newWatcher(t, gvk)
+	pred := r.onlyWithTTLAnnotation()
+
+	u := makeObj(map[string]string{r.Annotations.DeleteAt: "2030-01-01T00:00:00Z"})
+	if !pred.CreateFunc(event.CreateEvent{Object: u}) {
+		t.Fatalf("expected create with delete-at to trigger reconcile")
+	}
+
+	oldObj := makeObj(map[string]string{r.Annotations.DeleteAt: "2030-01-01T00:00:00Z"})
+	newObj := makeObj(map[string]string{r.Annotations.DeleteAt: "2031-01-01T00:00:00Z"})
+	if !pred.UpdateFunc(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}) {
+		t.Fatalf("expected delete-at change to trigger reconcile")
+	}
+}
+
+// A non-delete on-expire action keeps the object and is applied only once per expiry
+func TestHandleExpired_LabelActionAppliedOnce(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	reg := withIsolatedRegistry(t)
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "label-me")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:      "1s",
+		defaultAnn().OnExpire: "label:lease=expired",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+
+	expireAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	for i := 0; i < 2; i++ {
+		cur := get(t, cl, gvk, "default", "label-me")
+		if _, err := r.handleExpired(ctx, cur, expireAt); err != nil {
+			t.Fatalf("handleExpired error: %v", err)
+		}
+	}
+
+	got := get(t, cl, gvk, "default", "label-me")
+	if got.GetLabels()["lease"] != "expired" {
+		t.Fatalf("expected label lease=expired, got %v", got.GetLabels())
+	}
+	if got.GetAnnotations()[defaultAnn().OnExpireApplied] != expireAt.Format(time.RFC3339) {
+		t.Fatalf("expected on-expire-applied to be %s, got %q", expireAt.Format(time.RFC3339), got.GetAnnotations()[defaultAnn().OnExpireApplied])
+	}
+
+	applied := 0
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, "ExpiryActionApplied") {
+			applied++
+		}
+	}
+	if applied != 1 {
+		t.Fatalf("expected exactly one ExpiryActionApplied event, got %d", applied)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != "object_lease_controller_expiry_actions_total" {
+			continue
+		}
+		for _, m := range mf.Metric {
+			lbls := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				lbls[lp.GetName()] = lp.GetValue()
+			}
+			if lbls["action"] == "label" && lbls["result"] == "succeeded" && m.GetCounter().GetValue() == 1 {
+				return
+			}
+		}
+	}
+	t.Fatalf("expected expiry_actions_total{action=label,result=succeeded} = 1")
+}
+
+// An unknown on-expire action is reported and the object is kept
+func TestHandleExpired_InvalidOnExpireKeepsObject(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "bad-action")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:      "1s",
+		defaultAnn().OnExpire: "shred",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+
+	if _, err := r.handleExpired(ctx, obj, time.Now().UTC()); err != nil {
+		t.Fatalf("handleExpired error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "bad-action")
+	if !strings.Contains(got.GetAnnotations()[defaultAnn().Status], "Invalid on-expire") {
+		t.Fatalf("lease-status should mention Invalid on-expire, got %q", got.GetAnnotations()[defaultAnn().Status])
+	}
+}
+
+func TestOnlyWithTTLAnnotation_Selector(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, _ := newWatcher(t, gvk)
+	r.Selector = labels.SelectorFromSet(labels.Set{"lease": "on"})
+	p := r.onlyWithTTLAnnotation()
+
+	in := &unstructured.Unstructured{}
+	setMeta(in, gvk, "default", "in")
+	in.SetAnnotations(map[string]string{defaultAnn().TTL: "1h"})
+	in.SetLabels(map[string]string{"lease": "on"})
+	out := in.DeepCopy()
+	out.SetLabels(nil)
+
+	if !p.CreateFunc(event.CreateEvent{Object: in}) {
+		t.Fatalf("expected a selected object to be reconciled")
+	}
+	if p.CreateFunc(event.CreateEvent{Object: out}) {
+		t.Fatalf("expected an object outside the selector to be skipped")
+	}
+	if !p.UpdateFunc(event.UpdateEvent{ObjectOld: out, ObjectNew: in}) {
+		t.Fatalf("expected an object joining the selector to be reconciled")
+	}
+	if p.UpdateFunc(event.UpdateEvent{ObjectOld: in, ObjectNew: out}) {
+		t.Fatalf("expected an object leaving the selector to be skipped")
+	}
+}
+
+func TestReconcile_SkipsObjectsOutsideSelector(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl, _ := newWatcher(t, gvk, newExpiredObj(gvk, "unselected"))
+	r.Selector = labels.SelectorFromSet(labels.Set{"lease": "on"})
+
+	reconcileName(t, r, "unselected")
+	if got := get(t, cl, gvk, "default", "unselected"); got.GetDeletionTimestamp() != nil {
+		t.Fatalf("expected the object outside the selector to be kept")
+	}
+}
+
+func TestReconcile_ClusterScopedCleanupJobRunsInOpsNamespace(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	a := finalizerAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "", "temp-role")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:         "1h",
+		a.LeaseStart:  time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+		a.OnDeleteJob: "scripts/revoke.sh",
+	})
+	r, _, scheme := newWatcher(t, gvk)
+	_ = batchv1.AddToScheme(scheme)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(obj).Build()
+	r.Client = cl
+	r.Annotations = a
+	r.ClusterScoped = true
+	r.OpsNamespace = "lease-ops"
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Name: "temp-role"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := cl.List(ctx, jobs, client.InNamespace("lease-ops")); err != nil || len(jobs.Items) != 1 {
+		t.Fatalf("expected one cleanup job in the ops namespace, got %d (%v)", len(jobs.Items), err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, types.NamespacedName{Name: "temp-role"}, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the expired ClusterRole to be deleted, got %v", err)
+	}
+}
+
+func TestCleanupJobNamespace_ClusterScopedNeedsOpsNamespace(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	r, _, _ := newWatcher(t, gvk)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "", "temp-role")
+
+	if _, err := r.cleanupJobNamespace(obj); err != nil {
+		t.Fatalf("namespaced kinds need no ops namespace, got %v", err)
+	}
+	r.ClusterScoped = true
+	if _, err := r.cleanupJobNamespace(obj); err == nil {
+		t.Fatalf("expected an error without an ops namespace")
+	}
+}
+
+func TestReconcile_CalendarMonthEndsAtMonthEnd(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "calendar")
+	obj.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "1mo",
+		defaultAnn().LeaseStart: "2099-01-31T10:00:00Z",
+	})
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.CalendarDurations = true
+
+	reconcileName(t, r, "calendar")
+	if got := get(t, cl, gvk, "default", "calendar").GetAnnotations()[defaultAnn().ExpireAt]; got != "2099-02-28T10:00:00Z" {
+		t.Fatalf("expire-at = %q, want the end of February", got)
+	}
+}
+
+func TestReconcile_DeadlineTTLResolvesInTimezone(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	const tzAnn = "object-lease-controller.ullberg.io/lease-timezone"
+	controllerTZ := &unstructured.Unstructured{}
+	setMeta(controllerTZ, gvk, "default", "controller-tz")
+	controllerTZ.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "until friday 18:00",
+		defaultAnn().LeaseStart: "2099-01-28T10:00:00Z",
+	})
+	objectTZ := &unstructured.Unstructured{}
+	setMeta(objectTZ, gvk, "default", "object-tz")
+	objectTZ.SetAnnotations(map[string]string{
+		defaultAnn().TTL:        "until friday 18:00",
+		defaultAnn().LeaseStart: "2099-01-28T10:00:00Z",
+		tzAnn:                   "America/New_York",
+	})
+	invalidTZ := &unstructured.Unstructured{}
+	setMeta(invalidTZ, gvk, "default", "invalid-tz")
+	invalidTZ.SetAnnotations(map[string]string{
+		defaultAnn().TTL: "until friday 18:00",
+		tzAnn:            "Mars/Olympus",
+	})
+	r, cl, _ := newWatcher(t, gvk, controllerTZ, objectTZ, invalidTZ)
+	r.Annotations.Timezone = tzAnn
+	r.Location = stockholm
+
+	for name, want := range map[string]string{
+		"controller-tz": "2099-01-30T17:00:00Z",
+		"object-tz":     "2099-01-30T23:00:00Z",
+	} {
+		reconcileName(t, r, name)
+		if got := get(t, cl, gvk, "default", name).GetAnnotations()[defaultAnn().ExpireAt]; got != want {
+			t.Errorf("%s: expire-at = %q, want %q", name, got, want)
+		}
+	}
+
+	reconcileName(t, r, "invalid-tz")
+	if status := get(t, cl, gvk, "default", "invalid-tz").GetAnnotations()[defaultAnn().Status]; !strings.Contains(status, "lease-timezone") {
+		t.Fatalf("expected an invalid lease-timezone status, got %q", status)
+	}
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"object-lease-controller/pkg/util"
+)
+
+const (
+	// DrainPollInterval is how often a draining namespace is checked
+	DrainPollInterval = 10 * time.Second
+	// NamespaceStuckAfter is how long a namespace may be terminating before
+	// it is reported as stuck
+	NamespaceStuckAfter = 5 * time.Minute
+)
+
+// namespaceBlockingConditions are the Namespace conditions that explain why
+// a deletion does not finish
+var namespaceBlockingConditions = map[string]bool{
+	"NamespaceDeletionDiscoveryFailure":           true,
+	"NamespaceDeletionGroupVersionParsingFailure": true,
+	"NamespaceDeletionContentFailure":             true,
+	"NamespaceContentRemaining":                   true,
+	"NamespaceFinalizersRemaining":                true,
+}
+
+// isNamespaceLease reports whether the watched kind is Namespace
+func (r *LeaseWatcher) isNamespaceLease() bool {
+	return r.GVK.Group == "" && r.GVK.Kind == "Namespace"
+}
+
+// draining reports whether obj is a namespace whose drain has started
+func (r *LeaseWatcher) draining(obj *unstructured.Unstructured) bool {
+	return r.Annotations.DrainStartedAt != "" && obj.GetAnnotations()[r.Annotations.DrainStartedAt] != ""
+}
+
+// drainNamespace holds an expired namespace until its pods are gone, and its
+// cleanup job has finished when job-wait is set, or drain-timeout has passed.
+// The cleanup job is started once, when the drain starts, so it can stop the
+// workloads. Returns false when the namespace can be deleted.
+func (r *LeaseWatcher) drainNamespace(ctx context.Context, obj *unstructured.Unstructured, expireAt time.Time) (controller_runtime.Result, bool) {
+	v := obj.GetAnnotations()[r.Annotations.DrainTimeout]
+	if r.Annotations.DrainTimeout == "" || r.Annotations.DrainStartedAt == "" || v == "" {
+		return controller_runtime.Result{}, false
+	}
+	timeout, err := util.ParseFlexibleDuration(v)
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidDrainTimeout", fmt.Sprintf("Invalid drain-timeout: %v", err))
+		return controller_runtime.Result{}, true
+	}
+
+	now := time.Now().UTC()
+	config, configErr := r.cleanupJobConfig(obj)
+	startedAt, err := time.Parse(time.RFC3339, obj.GetAnnotations()[r.Annotations.DrainStartedAt])
+	if err != nil {
+		startedAt = now.Truncate(time.Second)
+		updates := map[string]string{
+			r.Annotations.DrainStartedAt: startedAt.Format(time.RFC3339),
+			r.Annotations.ExpireAt:       expireAt.Format(time.RFC3339),
+		}
+		if configErr != nil {
+			logger.FromContext(ctx).Error(configErr, "Invalid cleanup job configuration")
+			if r.Recorder != nil {
+				r.Recorder.Eventf(obj, nil, "Warning", "CleanupJobConfigInvalid", "CleanupJobConfigInvalid", "Invalid cleanup job config: %v", configErr)
+			}
+		} else if config != nil && r.Annotations.CleanupJobName != "" {
+			if job, err := r.createCleanupJob(ctx, obj, config, expireAt); err != nil {
+				logger.FromContext(ctx).Error(err, "Cleanup job execution failed")
+				r.cleanupJobFailed(obj, err)
+			} else {
+				updates[r.Annotations.CleanupJobName] = job.Name
+			}
+		}
+		r.updateAnnotations(ctx, obj, updates)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Normal", "NamespaceDraining", "NamespaceDraining", "Lease expired, draining namespace for up to %s before deleting it", util.FormatFlexibleDuration(timeout))
+		}
+	}
+
+	pending := r.drainPending(ctx, obj, config)
+	if len(pending) == 0 {
+		return controller_runtime.Result{}, false
+	}
+	if now.Sub(startedAt) >= timeout {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "DrainTimeout", "DrainTimeout", "Namespace did not drain within %s, still waiting for %s. Deleting it anyway.", util.FormatFlexibleDuration(timeout), strings.Join(pending, " and "))
+		}
+		return controller_runtime.Result{}, false
+	}
+	r.setStatus(ctx, obj, "NamespaceDraining", fmt.Sprintf("Lease expired. Draining namespace, waiting for %s.", strings.Join(pending, " and ")), nil)
+	return controller_runtime.Result{RequeueAfter: DrainPollInterval}, true
+}
+
+// drainPending lists what a draining namespace is still waiting for
+func (r *LeaseWatcher) drainPending(ctx context.Context, obj *unstructured.Unstructured, config *util.CleanupJobConfig) []string {
+	var pending []string
+
+	// A job that is not found yet may still be on its way into the cache, it
+	// is treated as running until the drain times out
+	if jobName := obj.GetAnnotations()[r.Annotations.CleanupJobName]; config != nil && config.Wait && jobName != "" {
+		job := &batchv1.Job{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: config.Namespace, Name: jobName}, job)
+		if client.IgnoreNotFound(err) != nil {
+			logger.FromContext(ctx).Error(err, "Unable to read cleanup job", "job", jobName)
+		}
+		if done, _ := util.JobFinished(job); err != nil || !done {
+			pending = append(pending, "cleanup job "+jobName)
+		}
+	}
+
+	// Pods are listed as unstructured, so they are read from the API server
+	// instead of being cached for the whole cluster
+	pods := &unstructured.UnstructuredList{}
+	pods.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+	if err := r.List(ctx, pods, client.InNamespace(obj.GetName())); err != nil {
+		logger.FromContext(ctx).Error(err, "Unable to list pods", "namespace", obj.GetName())
+		return append(pending, "pods")
+	}
+	running := 0
+	for i := range pods.Items {
+		phase, _, _ := unstructured.NestedString(pods.Items[i].Object, "status", "phase")
+		if phase != string(corev1.PodSucceeded) && phase != string(corev1.PodFailed) {
+			running++
+		}
+	}
+	switch {
+	case running == 1:
+		pending = append(pending, "1 pod")
+	case running > 1:
+		pending = append(pending, fmt.Sprintf("%d pods", running))
+	}
+	return pending
+}
+
+// awaitNamespaceDeletion follows a namespace the controller deleted until it
+// is gone. Namespaces that stay in Terminating are reported with what blocks
+// them.
+func (r *LeaseWatcher) awaitNamespaceDeletion(ctx context.Context, obj *unstructured.Unstructured) controller_runtime.Result {
+	waiting := time.Since(obj.GetDeletionTimestamp().Time)
+	blockers := namespaceBlockers(obj)
+
+	blockedBy := "nothing reported"
+	if len(blockers) > 0 {
+		blockedBy = strings.Join(blockers, "; ")
+	}
+	reason, leaseStatus := "NamespaceTerminating", "Lease expired. Waiting for the namespace to terminate."
+	if waiting >= NamespaceStuckAfter {
+		reason, leaseStatus = "NamespaceStuckTerminating", fmt.Sprintf("Lease expired. Namespace stuck in Terminating, blocked by: %s.", blockedBy)
+	}
+	// An event each time the blockers change
+	if obj.GetAnnotations()[r.Annotations.Status] != leaseStatus {
+		if waiting >= NamespaceStuckAfter && r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "NamespaceStuckTerminating", "NamespaceStuckTerminating", "Namespace terminating for %s, blocked by: %s", util.FormatFlexibleDuration(waiting.Truncate(time.Second)), blockedBy)
+		}
+		r.setStatus(ctx, obj, reason, leaseStatus, nil)
+	}
+	return controller_runtime.Result{RequeueAfter: ForegroundRequeueInterval}
+}
+
+// namespaceBlockers lists the finalizers and conditions that keep a
+// namespace in Terminating
+func namespaceBlockers(obj *unstructured.Unstructured) []string {
+	var blockers []string
+	if finalizers := obj.GetFinalizers(); len(finalizers) > 0 {
+		blockers = append(blockers, "finalizers "+strings.Join(finalizers, ", "))
+	}
+	if finalizers, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "finalizers"); len(finalizers) > 0 {
+		blockers = append(blockers, "namespace finalizers "+strings.Join(finalizers, ", "))
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _, _ := unstructured.NestedString(cond, "type")
+		status, _, _ := unstructured.NestedString(cond, "status")
+		message, _, _ := unstructured.NestedString(cond, "message")
+		if namespaceBlockingConditions[condType] && status == string(corev1.ConditionTrue) && message != "" {
+			blockers = append(blockers, message)
+		}
+	}
+	return blockers
+}
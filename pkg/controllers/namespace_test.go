@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"object-lease-controller/pkg/util"
+)
+
+var namespaceGVK = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"}
+
+func namespaceAnn() Annotations {
+	a := finalizerAnn()
+	a.DrainTimeout = "object-lease-controller.ullberg.io/drain-timeout"
+	a.DrainStartedAt = "object-lease-controller.ullberg.io/drain-started-at"
+	return a
+}
+
+func newNamespaceWatcher(t *testing.T, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	r, _, scheme := newWatcher(t, namespaceGVK)
+	_ = batchv1.AddToScheme(scheme)
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(schema.GroupVersionKind{Version: "v1", Kind: "PodList"}, &unstructured.UnstructuredList{})
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r.Client = cl
+	r.Annotations = namespaceAnn()
+	r.OpsNamespace = "lease-ops"
+	return r, cl
+}
+
+func expiredNamespace(name string, extra map[string]string) *unstructured.Unstructured {
+	a := namespaceAnn()
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, namespaceGVK, "", name)
+	anns := map[string]string{
+		a.TTL:        "1h",
+		a.LeaseStart: time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+	}
+	for k, v := range extra {
+		anns[k] = v
+	}
+	obj.SetAnnotations(anns)
+	return obj
+}
+
+func runningPod(ns, name string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{}
+	setMeta(pod, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, ns, name)
+	_ = unstructured.SetNestedField(pod.Object, "Running", "status", "phase")
+	return pod
+}
+
+func reconcileNamespace(t *testing.T, r *LeaseWatcher, name string) controller_runtime.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), controller_runtime.Request{NamespacedName: types.NamespacedName{Name: name}})
+	if err != nil {
+		t.Fatalf("reconcile %s error: %v", name, err)
+	}
+	return res
+}
+
+func namespaceGone(t *testing.T, cl client.Client, name string) bool {
+	t.Helper()
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(namespaceGVK)
+	return apierrors.IsNotFound(cl.Get(context.Background(), types.NamespacedName{Name: name}, out))
+}
+
+func TestReconcile_NamespaceDrainsBeforeDelete(t *testing.T) {
+	a := namespaceAnn()
+	ns := expiredNamespace("team-a", map[string]string{
+		a.DrainTimeout: "10m",
+		a.OnDeleteJob:  "scripts/drain.sh",
+	})
+	pod := runningPod("team-a", "web")
+	r, cl := newNamespaceWatcher(t, ns, pod)
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+
+	res := reconcileNamespace(t, r, "team-a")
+	if res.RequeueAfter != DrainPollInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, DrainPollInterval)
+	}
+	status := get(t, cl, namespaceGVK, "", "team-a").GetAnnotations()[a.Status]
+	if !strings.Contains(status, "waiting for 1 pod") {
+		t.Fatalf("unexpected status %q", status)
+	}
+	jobs := &batchv1.JobList{}
+	if err := cl.List(context.Background(), jobs, client.InNamespace("lease-ops")); err != nil || len(jobs.Items) != 1 {
+		t.Fatalf("expected one cleanup job in the ops namespace, got %d (%v)", len(jobs.Items), err)
+	}
+	if n := countEvents(rec, "NamespaceDraining"); n != 1 {
+		t.Fatalf("expected one NamespaceDraining event, got %d", n)
+	}
+
+	if err := cl.Delete(context.Background(), pod); err != nil {
+		t.Fatalf("delete pod error: %v", err)
+	}
+	reconcileNamespace(t, r, "team-a")
+	if !namespaceGone(t, cl, "team-a") {
+		t.Fatalf("expected the drained namespace to be deleted")
+	}
+	if err := cl.List(context.Background(), jobs, client.InNamespace("lease-ops")); err != nil || len(jobs.Items) != 1 {
+		t.Fatalf("expected the cleanup job to run once, got %d (%v)", len(jobs.Items), err)
+	}
+}
+
+func TestReconcile_NamespaceDrainTimeoutDeletesAnyway(t *testing.T) {
+	a := namespaceAnn()
+	r, cl := newNamespaceWatcher(t, expiredNamespace("team-b", map[string]string{a.DrainTimeout: "0s"}), runningPod("team-b", "web"))
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+
+	reconcileNamespace(t, r, "team-b")
+	if !namespaceGone(t, cl, "team-b") {
+		t.Fatalf("expected the namespace to be deleted after the drain timeout")
+	}
+	if n := countEvents(rec, "DrainTimeout"); n != 1 {
+		t.Fatalf("expected one DrainTimeout event, got %d", n)
+	}
+}
+
+func TestReconcile_NamespaceCleanupJobNeedsOpsNamespace(t *testing.T) {
+	a := namespaceAnn()
+	r, cl := newNamespaceWatcher(t, expiredNamespace("team-c", map[string]string{a.OnDeleteJob: "scripts/cleanup.sh"}))
+	r.OpsNamespace = ""
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+
+	reconcileNamespace(t, r, "team-c")
+	if n := countEvents(rec, "CleanupJobConfigInvalid"); n != 1 {
+		t.Fatalf("expected one CleanupJobConfigInvalid event, got %d", n)
+	}
+	if n := len(listJobs(t, cl)); n != 0 {
+		t.Fatalf("expected no job inside the namespace being deleted, got %d", n)
+	}
+	if !namespaceGone(t, cl, "team-c") {
+		t.Fatalf("expected the namespace to be deleted")
+	}
+}
+
+func TestReconcile_NamespaceStuckTerminatingIsReported(t *testing.T) {
+	a := namespaceAnn()
+	ns := &unstructured.Unstructured{}
+	setMeta(ns, namespaceGVK, "", "stuck")
+	ns.SetAnnotations(map[string]string{a.TTL: "1h", a.Phase: PhaseDeleted})
+	ns.SetFinalizers([]string{"example.com/hold"})
+	ts := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	ns.SetDeletionTimestamp(&ts)
+	_ = unstructured.SetNestedSlice(ns.Object, []interface{}{map[string]interface{}{
+		"type":    "NamespaceContentRemaining",
+		"status":  "True",
+		"message": "Some resources are remaining: widgets.example.com has 1 resource instances",
+	}}, "status", "conditions")
+	r, cl := newNamespaceWatcher(t, ns)
+	rec := newFakeEventsRecorder(20)
+	r.Recorder = rec
+
+	for i := 0; i < 2; i++ {
+		if res := reconcileNamespace(t, r, "stuck"); res.RequeueAfter != ForegroundRequeueInterval {
+			t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, ForegroundRequeueInterval)
+		}
+	}
+	status := get(t, cl, namespaceGVK, "", "stuck").GetAnnotations()[a.Status]
+	if !strings.Contains(status, "example.com/hold") || !strings.Contains(status, "widgets.example.com") {
+		t.Fatalf("expected the blockers in the status, got %q", status)
+	}
+	if n := countEvents(rec, "NamespaceStuckTerminating"); n != 1 {
+		t.Fatalf("expected one NamespaceStuckTerminating event, got %d", n)
+	}
+}
+
+func TestReconcile_NamespaceLeaseUsesItsOwnNameForTracking(t *testing.T) {
+	r, cl := newNamespaceWatcher(t, expiredNamespace("team-d", nil))
+	r.Tracker = util.NewNamespaceTracker()
+	r.Tracker.AddNamespace("team-d")
+
+	reconcileNamespace(t, r, "team-d")
+	if !namespaceGone(t, cl, "team-d") {
+		t.Fatalf("expected the tracked namespace to be handled")
+	}
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"object-lease-controller/pkg/util"
+)
+
+// pausedValue reports whether a lease-paused annotation value turns the pause on
+func pausedValue(v string) bool {
+	b, err := strconv.ParseBool(v)
+	return err == nil && b
+}
+
+// isPaused checks the lease-paused annotation on the object and its Namespace
+func (r *LeaseWatcher) isPaused(ctx context.Context, obj *unstructured.Unstructured) bool {
+	return r.objectPaused(obj) || r.namespacePaused(ctx, obj.GetNamespace())
+}
+
+// objectPaused checks the lease-paused annotation on the object only
+func (r *LeaseWatcher) objectPaused(obj *unstructured.Unstructured) bool {
+	return r.Annotations.LeasePaused != "" && pausedValue(obj.GetAnnotations()[r.Annotations.LeasePaused])
+}
+
+// namespacePaused checks the lease-paused annotation on the Namespace
+func (r *LeaseWatcher) namespacePaused(ctx context.Context, namespace string) bool {
+	if r.Annotations.LeasePaused == "" || namespace == "" {
+		return false
+	}
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		logger.FromContext(ctx).V(1).Info("unable to read namespace for lease pause", "namespace", namespace, "error", err.Error())
+		return false
+	}
+	return pausedValue(ns.GetAnnotations()[r.Annotations.LeasePaused])
+}
+
+// applyPause extends expireAt by the time the lease has spent paused. While a
+// pause is in effect it starts or continues the pause and returns paused=true.
+// When the pause is lifted the elapsed time is added to paused-duration.
+func (r *LeaseWatcher) applyPause(ctx context.Context, obj *unstructured.Unstructured, expireAt, now time.Time) (time.Time, bool) {
+	if r.Annotations.LeasePaused == "" {
+		return expireAt, false
+	}
+	anns := obj.GetAnnotations()
+	var total time.Duration
+	if v := anns[r.Annotations.PausedDuration]; v != "" {
+		if d, err := util.ParseFlexibleDuration(v); err == nil {
+			total = d
+		}
+	}
+	pausedAt, err := time.Parse(time.RFC3339, anns[r.Annotations.PausedAt])
+	wasPaused := err == nil
+
+	if r.isPaused(ctx, obj) {
+		if !wasPaused {
+			pausedAt = now
+			r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.PausedAt: now.Format(time.RFC3339)})
+			if r.Recorder != nil {
+				r.Recorder.Eventf(obj, nil, "Normal", "LeasePaused", "LeasePaused", "Lease paused")
+			}
+			r.recordHistory(ctx, obj, "LeasePaused", "Lease paused", util.AnnotationManager(obj, r.Annotations.LeasePaused))
+			if r.Metrics != nil {
+				r.Metrics.LeasesPaused.Inc()
+			}
+		}
+		return expireAt.Add(total).Add(now.Sub(pausedAt)), true
+	}
+
+	if wasPaused {
+		total += now.Sub(pausedAt).Truncate(time.Second)
+		r.removeAnnotations(ctx, obj, r.Annotations.PausedAt)
+		r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.PausedDuration: util.FormatFlexibleDuration(total)})
+		message := fmt.Sprintf("Lease unpaused after %s, paused for %s in total", util.FormatFlexibleDuration(now.Sub(pausedAt).Truncate(time.Second)), util.FormatFlexibleDuration(total))
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Normal", "LeaseUnpaused", "LeaseUnpaused", "%s", message)
+		}
+		r.recordHistory(ctx, obj, "LeaseUnpaused", message, "")
+	}
+	return expireAt.Add(total), false
+}
+
+// setPaused records the frozen lease. No requeue is needed, lifting the
+// pause on the object or its Namespace triggers a reconcile.
+func (r *LeaseWatcher) setPaused(ctx context.Context, obj *unstructured.Unstructured, expireAt, now time.Time) controller_runtime.Result {
+	pausedAt, err := time.Parse(time.RFC3339, obj.GetAnnotations()[r.Annotations.PausedAt])
+	if err != nil {
+		pausedAt = now
+	}
+	remaining := expireAt.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	newAnns := map[string]string{}
+	// A hibernated workload keeps its phase so it can still be resumed
+	if !r.isHibernated(obj) {
+		newAnns[r.Annotations.Phase] = PhasePaused
+	}
+	r.setStatus(ctx, obj, "LeasePaused", fmt.Sprintf("Lease paused since %s UTC with %s remaining.", pausedAt.Format(time.RFC3339), r.formatDuration(obj, remaining.Truncate(time.Second))), newAnns)
+	return controller_runtime.Result{}
+}
+
+// namespacePauseChanged only lets Namespace updates through when lease-paused changed
+func (r *LeaseWatcher) namespacePauseChanged() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if e.ObjectOld == nil || e.ObjectNew == nil {
+				return false
+			}
+			return e.ObjectOld.GetAnnotations()[r.Annotations.LeasePaused] != e.ObjectNew.GetAnnotations()[r.Annotations.LeasePaused]
+		},
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	}
+}
+
+// leasesInNamespace maps a Namespace to reconcile requests for its leased objects
+func (r *LeaseWatcher) leasesInNamespace(ctx context.Context, ns client.Object) []controller_runtime.Request {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   r.GVK.Group,
+		Version: r.GVK.Version,
+		Kind:    r.GVK.Kind + "List",
+	})
+	if err := r.List(ctx, list, client.InNamespace(ns.GetName())); err != nil {
+		logger.FromContext(ctx).Error(err, "unable to list objects for paused namespace", "namespace", ns.GetName())
+		return nil
+	}
+	var reqs []controller_runtime.Request
+	for i := range list.Items {
+		if r.hasLeaseAnnotation(list.Items[i].GetAnnotations()) {
+			reqs = append(reqs, controller_runtime.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+	return reqs
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func pauseAnn() Annotations {
+	a := defaultAnn()
+	a.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	a.LeasePaused = "object-lease-controller.ullberg.io/lease-paused"
+	a.PausedAt = "object-lease-controller.ullberg.io/paused-at"
+	a.PausedDuration = "object-lease-controller.ullberg.io/paused-duration"
+	return a
+}
+
+// newPauseWatcher builds a watcher whose client also knows about Namespaces
+func newPauseWatcher(t *testing.T, gvk schema.GroupVersionKind, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	r, _, scheme := newWatcher(t, gvk)
+	// Only the Namespace types, the leased kind is registered as unstructured
+	scheme.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Namespace{}, &corev1.NamespaceList{})
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r.Client = cl
+	r.Annotations = pauseAnn()
+	return r, cl
+}
+
+func TestReconcile_PausedLeaseIsNotDeleted(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := pauseAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "paused")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:         "1h",
+		a.LeaseStart:  time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+		a.LeasePaused: "true",
+	})
+
+	r, cl := newPauseWatcher(t, gvk, obj)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	res, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "paused"}})
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if res.RequeueAfter != 0 {
+		t.Fatalf("paused lease should not requeue, got %v", res.RequeueAfter)
+	}
+	got := get(t, cl, gvk, "default", "paused").GetAnnotations()
+	if got[a.PausedAt] == "" {
+		t.Fatalf("expected paused-at to be set")
+	}
+	if got[a.Phase] != PhasePaused {
+		t.Fatalf("phase = %q, want %q", got[a.Phase], PhasePaused)
+	}
+	if !strings.HasPrefix(got[a.Status], "Lease paused") {
+		t.Fatalf("unexpected status %q", got[a.Status])
+	}
+	if n := countEvents(rec, "LeasePaused"); n != 1 {
+		t.Fatalf("expected one LeasePaused event, got %d", n)
+	}
+}
+
+func TestReconcile_UnpauseExtendsExpiry(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := pauseAnn()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	start := now.Add(-50 * time.Minute)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "unpaused")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:            "1h",
+		a.LeaseStart:     start.Format(time.RFC3339),
+		a.PausedAt:       now.Add(-30 * time.Minute).Format(time.RFC3339),
+		a.PausedDuration: "10m",
+	})
+
+	r, cl := newPauseWatcher(t, gvk, obj)
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "unpaused"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	got := get(t, cl, gvk, "default", "unpaused").GetAnnotations()
+	if _, ok := got[a.PausedAt]; ok {
+		t.Fatalf("expected paused-at to be removed")
+	}
+	if got[a.PausedDuration] != "40m" {
+		t.Fatalf("paused-duration = %q, want 40m", got[a.PausedDuration])
+	}
+	expireAt, err := time.Parse(time.RFC3339, got[a.ExpireAt])
+	if err != nil {
+		t.Fatalf("invalid expire-at %q: %v", got[a.ExpireAt], err)
+	}
+	// start + ttl + 40m paused, allow a second of drift
+	if want := start.Add(100 * time.Minute); expireAt.Sub(want) > time.Second || want.Sub(expireAt) > time.Second {
+		t.Fatalf("expire-at = %v, want about %v", expireAt, want)
+	}
+}
+
+func TestReconcile_NamespacePauseFreezesLease(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := pauseAnn()
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "frozen", Annotations: map[string]string{a.LeasePaused: "true"}}}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "frozen", "cm")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:        "1h",
+		a.LeaseStart: time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339),
+	})
+
+	r, cl := newPauseWatcher(t, gvk, ns, obj)
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "frozen", Name: "cm"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if got := get(t, cl, gvk, "frozen", "cm").GetAnnotations(); got[a.PausedAt] == "" {
+		t.Fatalf("expected namespace pause to freeze the lease")
+	}
+
+	// Lifting the namespace pause lets the overdue lease expire
+	ns.Annotations = nil
+	if err := cl.Update(ctx, ns); err != nil {
+		t.Fatalf("update namespace: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound after unpause, got %v", err)
+	}
+}
+
+func TestLeasesInNamespace(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := pauseAnn()
+
+	leased := &unstructured.Unstructured{}
+	setMeta(leased, gvk, "team", "leased")
+	leased.SetAnnotations(map[string]string{a.TTL: "1h"})
+	plain := &unstructured.Unstructured{}
+	setMeta(plain, gvk, "team", "plain")
+	other := &unstructured.Unstructured{}
+	setMeta(other, gvk, "other", "leased")
+	other.SetAnnotations(map[string]string{a.TTL: "1h"})
+
+	r, _ := newPauseWatcher(t, gvk, leased, plain, other)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}
+
+	reqs := r.leasesInNamespace(context.Background(), ns)
+	if len(reqs) != 1 || reqs[0].Name != "leased" || reqs[0].Namespace != "team" {
+		t.Fatalf("unexpected requests %v", reqs)
+	}
+}
+
+func TestNamespacePauseChangedPredicate(t *testing.T) {
+	r := &LeaseWatcher{Annotations: pauseAnn()}
+	p := r.namespacePauseChanged()
+
+	old := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}
+	paused := old.DeepCopy()
+	paused.Annotations = map[string]string{pauseAnn().LeasePaused: "true"}
+	relabeled := old.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "a"}
+
+	if !p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: paused}) {
+		t.Fatalf("expected pause change to pass")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: relabeled}) {
+		t.Fatalf("expected unrelated change to be filtered")
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+
+	"object-lease-controller/pkg/util"
+)
+
+// ForegroundRequeueInterval is how often a foreground deletion is checked for progress
+const ForegroundRequeueInterval = 15 * time.Second
+
+// propagationPolicy returns the deletion propagation for obj: the
+// deletion-propagation annotation, or the controller default.
+func (r *LeaseWatcher) propagationPolicy(obj *unstructured.Unstructured) (metav1.DeletionPropagation, error) {
+	if v := obj.GetAnnotations()[r.Annotations.DeletionPropagation]; r.Annotations.DeletionPropagation != "" && v != "" {
+		return util.ParsePropagationPolicy(v)
+	}
+	return r.DefaultPropagation, nil
+}
+
+// awaitDeletion reports progress on an object the controller deleted that is
+// still terminating, e.g. while a foreground deletion removes its dependents.
+func (r *LeaseWatcher) awaitDeletion(ctx context.Context, obj *unstructured.Unstructured) controller_runtime.Result {
+	// Only follow deletions started by the controller
+	if r.Annotations.Phase == "" || obj.GetAnnotations()[r.Annotations.Phase] != PhaseDeleted {
+		return controller_runtime.Result{}
+	}
+	if r.isNamespaceLease() {
+		return r.awaitNamespaceDeletion(ctx, obj)
+	}
+
+	waiting := time.Since(obj.GetDeletionTimestamp().Time).Truncate(time.Second)
+	leaseStatus := "Lease expired. Waiting for deletion to finish."
+	if finalizers := obj.GetFinalizers(); len(finalizers) > 0 {
+		leaseStatus = fmt.Sprintf("Lease expired. Waiting for deletion to finish, pending finalizers: %s.", strings.Join(finalizers, ", "))
+	}
+	// An event each time the pending finalizers change shows progress
+	if obj.GetAnnotations()[r.Annotations.Status] != leaseStatus {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Normal", "DeletionInProgress", "DeletionInProgress", "Deletion in progress for %s, pending finalizers: %v", util.FormatFlexibleDuration(waiting), obj.GetFinalizers())
+		}
+		r.setStatus(ctx, obj, "DeletionInProgress", leaseStatus, nil)
+	}
+	return controller_runtime.Result{RequeueAfter: ForegroundRequeueInterval}
+}
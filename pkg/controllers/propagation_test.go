@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func propagationAnn() Annotations {
+	a := defaultAnn()
+	a.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	a.DeletionPropagation = "object-lease-controller.ullberg.io/deletion-propagation"
+	return a
+}
+
+// newPropagationWatcher records the propagation policy of every Delete call
+func newPropagationWatcher(t *testing.T, obj *unstructured.Unstructured, policies *[]metav1.DeletionPropagation) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	r, cl, _ := newWatcher(t, obj.GroupVersionKind(), obj)
+	cl = interceptor.NewClient(cl.(client.WithWatch), interceptor.Funcs{
+		Delete: func(ctx context.Context, c client.WithWatch, o client.Object, opts ...client.DeleteOption) error {
+			do := &client.DeleteOptions{}
+			do.ApplyOptions(opts)
+			var p metav1.DeletionPropagation
+			if do.PropagationPolicy != nil {
+				p = *do.PropagationPolicy
+			}
+			*policies = append(*policies, p)
+			return c.Delete(ctx, o, opts...)
+		},
+	})
+	r.Client = cl
+	r.Annotations = propagationAnn()
+	return r, cl
+}
+
+func TestReconcile_ForegroundDeletionIsFollowed(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := propagationAnn()
+
+	obj := newExpiredObj(gvk, "fg")
+	anns := obj.GetAnnotations()
+	anns[a.DeletionPropagation] = "foreground"
+	obj.SetAnnotations(anns)
+	// Stands in for dependents that are still being removed
+	obj.SetFinalizers([]string{"example.com/dependents"})
+
+	var policies []metav1.DeletionPropagation
+	r, cl := newPropagationWatcher(t, obj, &policies)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "fg"}}
+
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if len(policies) != 1 || policies[0] != metav1.DeletePropagationForeground {
+		t.Fatalf("expected a single Foreground delete, got %v", policies)
+	}
+	if res.RequeueAfter != ForegroundRequeueInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, ForegroundRequeueInterval)
+	}
+
+	// Still terminating, progress is reported and no second delete is sent
+	res, err = r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if res.RequeueAfter != ForegroundRequeueInterval {
+		t.Fatalf("RequeueAfter = %v, want %v", res.RequeueAfter, ForegroundRequeueInterval)
+	}
+	if len(policies) != 1 {
+		t.Fatalf("expected no further deletes while waiting, got %v", policies)
+	}
+	cur := get(t, cl, gvk, "default", "fg")
+	if status := cur.GetAnnotations()[a.Status]; !strings.Contains(status, "example.com/dependents") {
+		t.Fatalf("status should list pending finalizers, got %q", status)
+	}
+	if n := countEvents(rec, "DeletionInProgress"); n != 1 {
+		t.Fatalf("expected one DeletionInProgress event, got %d", n)
+	}
+
+	// Dependents gone, the object disappears
+	cur.SetFinalizers(nil)
+	if err := cl.Update(ctx, cur); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+	if res, err := r.Reconcile(ctx, req); err != nil || res.RequeueAfter != 0 {
+		t.Fatalf("Reconcile after deletion = (%v, %v), want no requeue", res, err)
+	}
+	out := &unstructured.Unstructured{}
+	out.SetGroupVersionKind(gvk)
+	if err := cl.Get(ctx, req.NamespacedName, out); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestReconcile_DefaultPropagationApplies(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	var policies []metav1.DeletionPropagation
+	r, _ := newPropagationWatcher(t, newExpiredObj(gvk, "orphan"), &policies)
+	r.DefaultPropagation = metav1.DeletePropagationOrphan
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "orphan"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if len(policies) != 1 || policies[0] != metav1.DeletePropagationOrphan {
+		t.Fatalf("expected an Orphan delete, got %v", policies)
+	}
+}
+
+func TestReconcile_InvalidDeletionPropagationDoesNotDelete(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := propagationAnn()
+
+	obj := newExpiredObj(gvk, "bad")
+	anns := obj.GetAnnotations()
+	anns[a.DeletionPropagation] = "cascade"
+	obj.SetAnnotations(anns)
+
+	var policies []metav1.DeletionPropagation
+	r, cl := newPropagationWatcher(t, obj, &policies)
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "bad"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if len(policies) != 0 {
+		t.Fatalf("expected no delete, got %v", policies)
+	}
+	if status := get(t, cl, gvk, "default", "bad").GetAnnotations()[a.Status]; !strings.Contains(status, "Invalid deletion-propagation") {
+		t.Fatalf("unexpected status %q", status)
+	}
+}
+
+func TestAwaitDeletion_IgnoresForeignDeletes(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "foreign")
+	now := metav1.NewTime(time.Now())
+	obj.SetDeletionTimestamp(&now)
+
+	r := &LeaseWatcher{Annotations: propagationAnn()}
+	if res := r.awaitDeletion(context.Background(), obj); res.RequeueAfter != 0 {
+		t.Fatalf("expected deletions started elsewhere to be ignored, got %v", res)
+	}
+}
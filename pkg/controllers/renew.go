@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"object-lease-controller/pkg/util"
+)
+
+// renewalLimits returns the max-renewals and max-lifetime of obj, the lower
+// of its own and the controller's. A negative count or a zero lifetime is
+// no limit.
+func (r *LeaseWatcher) renewalLimits(obj *unstructured.Unstructured) (int, time.Duration, error) {
+	maxRenewals, maxLifetime := -1, r.MaxLifetime
+	if r.MaxRenewals > 0 {
+		maxRenewals = r.MaxRenewals
+	}
+	anns := obj.GetAnnotations()
+	if v := anns[r.Annotations.MaxRenewals]; r.Annotations.MaxRenewals != "" && v != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid max-renewals %q: expected a count of zero or more", v)
+		}
+		if maxRenewals < 0 || n < maxRenewals {
+			maxRenewals = n
+		}
+	}
+	if v := anns[r.Annotations.MaxLifetime]; r.Annotations.MaxLifetime != "" && v != "" {
+		d, err := util.ParseFlexibleDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("invalid max-lifetime %q: expected a positive duration", v)
+		}
+		if maxLifetime <= 0 || d < maxLifetime {
+			maxLifetime = d
+		}
+	}
+	return maxRenewals, maxLifetime, nil
+}
+
+// lifetimeDeadline returns when the max-lifetime of obj ends every lease
+func (r *LeaseWatcher) lifetimeDeadline(obj *unstructured.Unstructured) (time.Time, bool) {
+	created := obj.GetCreationTimestamp()
+	_, maxLifetime, err := r.renewalLimits(obj)
+	if err != nil || maxLifetime <= 0 || created.IsZero() {
+		return time.Time{}, false
+	}
+	return created.Add(maxLifetime).UTC(), true
+}
+
+// renewCount returns how often the lease of obj was renewed
+func (r *LeaseWatcher) renewCount(obj *unstructured.Unstructured) int {
+	n, err := strconv.Atoi(obj.GetAnnotations()[r.Annotations.RenewCount])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// leaseExtension returns how far renewals extended the lease of obj
+func (r *LeaseWatcher) leaseExtension(obj *unstructured.Unstructured) time.Duration {
+	v := obj.GetAnnotations()[r.Annotations.LeaseExtension]
+	if r.Annotations.LeaseExtension == "" || v == "" {
+		return 0
+	}
+	d, err := util.ParseFlexibleDuration(v)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// applyRenewals adds the lease-extension of earlier renewals to expireAt and
+// consumes the renew annotation. A duration extends the lease by that much
+// from its expiry, or from now once it expired, any other value, such as a
+// timestamp, starts it over. Returns false when the renewal limits are
+// invalid and the lease is marked as such.
+func (r *LeaseWatcher) applyRenewals(ctx context.Context, obj *unstructured.Unstructured, expireAt, now time.Time) (time.Time, bool) {
+	anns := obj.GetAnnotations()
+	maxRenewals, _, err := r.renewalLimits(obj)
+	if err != nil {
+		r.markInvalid(ctx, obj, "InvalidRenewalLimit", fmt.Sprintf("Invalid renewal limit: %v", err))
+		return expireAt, false
+	}
+	extension := r.leaseExtension(obj)
+	expireAt = expireAt.Add(extension)
+
+	renew, requested := anns[r.Annotations.Renew]
+	if r.Annotations.Renew == "" || !requested {
+		return expireAt, true
+	}
+	renew = strings.TrimSpace(renew)
+	defer r.removeAnnotations(ctx, obj, r.Annotations.Renew)
+	if renew == "" {
+		return expireAt, true
+	}
+
+	renewedBy := util.AnnotationManager(obj, r.Annotations.Renew)
+	if renewedBy == "" {
+		renewedBy = "unknown"
+	}
+	count := r.renewCount(obj)
+	deadline, limited := r.lifetimeDeadline(obj)
+	switch {
+	case maxRenewals >= 0 && count >= maxRenewals:
+		r.denyRenewal(ctx, obj, renewedBy, fmt.Sprintf("max-renewals of %d reached", maxRenewals))
+		return expireAt, true
+	case limited && !expireAt.Before(deadline):
+		r.denyRenewal(ctx, obj, renewedBy, fmt.Sprintf("max-lifetime reached, the lease ends at %s UTC", deadline.Format(time.RFC3339)))
+		return expireAt, true
+	}
+
+	updates := map[string]string{
+		r.Annotations.RenewCount:    strconv.Itoa(count + 1),
+		r.Annotations.LastRenewedBy: renewedBy,
+	}
+	if d, err := util.ParseFlexibleDuration(renew); err == nil {
+		if d <= 0 {
+			r.denyRenewal(ctx, obj, renewedBy, fmt.Sprintf("renew duration %q is not positive", renew))
+			return expireAt, true
+		}
+		from := expireAt
+		if now.After(from) {
+			from = now
+		}
+		extension += from.Add(d).Sub(expireAt)
+		expireAt = from.Add(d)
+		updates[r.Annotations.LeaseExtension] = util.FormatFlexibleDuration(extension)
+	} else {
+		// Anything else starts the lease over, which only a TTL can
+		if r.Annotations.DeleteAt != "" && anns[r.Annotations.DeleteAt] != "" {
+			r.denyRenewal(ctx, obj, renewedBy, "leases with a delete-at deadline are renewed by a duration")
+			return expireAt, true
+		}
+		expiry, err := r.leaseExpiry(obj)
+		if err != nil {
+			// Left to the invalid TTL handling
+			return expireAt, true
+		}
+		expireAt = expiry(now)
+		updates[r.Annotations.LeaseStart] = now.Format(time.RFC3339)
+		r.removeAnnotations(ctx, obj, r.Annotations.LeaseExtension, r.Annotations.PausedDuration)
+	}
+	r.updateAnnotations(ctx, obj, updates)
+
+	until := expireAt
+	if limited && until.After(deadline) {
+		until = deadline
+	}
+	message := fmt.Sprintf("Lease renewed by %s until %s UTC, renewal %d", renewedBy, until.Format(time.RFC3339), count+1)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseRenewed", "LeaseRenewed", "%s", message)
+	}
+	r.recordHistory(ctx, obj, "LeaseRenewed", message, renewedBy)
+	if r.Metrics != nil {
+		r.Metrics.LeasesRenewed.Inc()
+	}
+	return expireAt, true
+}
+
+// denyRenewal reports a renewal that is refused
+func (r *LeaseWatcher) denyRenewal(ctx context.Context, obj *unstructured.Unstructured, renewedBy, why string) {
+	message := fmt.Sprintf("Renewal by %s denied: %s", renewedBy, why)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Warning", "LeaseRenewalDenied", "LeaseRenewalDenied", "%s", message)
+	}
+	r.recordHistory(ctx, obj, "LeaseRenewalDenied", message, renewedBy)
+	if r.Metrics != nil {
+		r.Metrics.RenewalsDenied.Inc()
+	}
+}
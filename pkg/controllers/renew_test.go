@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func renewAnn() Annotations {
+	a := defaultAnn()
+	a.Renew = "object-lease-controller.ullberg.io/renew"
+	a.RenewCount = "object-lease-controller.ullberg.io/renew-count"
+	a.LastRenewedBy = "object-lease-controller.ullberg.io/last-renewed-by"
+	a.LeaseExtension = "object-lease-controller.ullberg.io/lease-extension"
+	a.MaxRenewals = "object-lease-controller.ullberg.io/max-renewals"
+	a.MaxLifetime = "object-lease-controller.ullberg.io/max-lifetime"
+	return a
+}
+
+// newRenewWatcher builds a watcher whose client returns managedFields, which
+// tell who renewed a lease
+func newRenewWatcher(t *testing.T, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, _, scheme := newWatcher(t, gvk)
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithReturnManagedFields().Build()
+	r.Client = cl
+	r.Annotations = renewAnn()
+	return r, cl
+}
+
+// newRenewObj returns an object created at created whose renew annotation
+// was set by manager
+func newRenewObj(name string, created time.Time, manager string, anns map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}, "default", name)
+	obj.SetCreationTimestamp(metav1.NewTime(created))
+	obj.SetAnnotations(anns)
+	at := metav1.NewTime(time.Now())
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{
+		Manager:    manager,
+		APIVersion: "v1",
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		Time:       &at,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:` + renewAnn().Renew + `":{}}}}`)},
+	}})
+	return obj
+}
+
+func TestReconcile_RenewExtendsLease(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := renewAnn()
+	start := time.Now().UTC().Add(-30 * time.Minute).Truncate(time.Second)
+	obj := newRenewObj("renew", start, "kubectl-annotate", map[string]string{
+		a.TTL:        "1h",
+		a.LeaseStart: start.Format(time.RFC3339),
+		a.Renew:      "2h",
+	})
+	r, cl := newRenewWatcher(t, obj)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	reconcileName(t, r, "renew")
+	got := get(t, cl, gvk, "default", "renew").GetAnnotations()
+	if got[a.ExpireAt] != start.Add(3*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q, want the TTL plus 2h", got[a.ExpireAt])
+	}
+	if _, ok := got[a.Renew]; ok {
+		t.Fatalf("expected the renew annotation to be consumed")
+	}
+	if got[a.RenewCount] != "1" || got[a.LastRenewedBy] != "kubectl-annotate" || got[a.LeaseExtension] != "2h" {
+		t.Fatalf("unexpected renewal record: count %q, by %q, extension %q", got[a.RenewCount], got[a.LastRenewedBy], got[a.LeaseExtension])
+	}
+	if got[a.LeaseStart] != start.Format(time.RFC3339) {
+		t.Fatalf("lease-start changed to %q", got[a.LeaseStart])
+	}
+	if n := countEvents(rec, "LeaseRenewed"); n != 1 {
+		t.Fatalf("expected one LeaseRenewed event, got %d", n)
+	}
+
+	// The extension stays with the lease
+	reconcileName(t, r, "renew")
+	if got := get(t, cl, gvk, "default", "renew").GetAnnotations(); got[a.ExpireAt] != start.Add(3*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q after another reconcile", got[a.ExpireAt])
+	}
+}
+
+func TestReconcile_RenewExpiredLeaseRunsFromNow(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := renewAnn()
+	start := time.Now().UTC().Add(-3 * time.Hour)
+	obj := newRenewObj("late", start, "ci", map[string]string{
+		a.TTL:        "1h",
+		a.LeaseStart: start.Format(time.RFC3339),
+		a.Renew:      "1h",
+	})
+	r, cl := newRenewWatcher(t, obj)
+
+	reconcileName(t, r, "late")
+	got := get(t, cl, gvk, "default", "late").GetAnnotations()
+	expireAt, err := time.Parse(time.RFC3339, got[a.ExpireAt])
+	if err != nil {
+		t.Fatalf("expected the renewed lease to be active, got expire-at %q", got[a.ExpireAt])
+	}
+	if d := time.Until(expireAt); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("expected the lease to run an hour from now, %v left", d)
+	}
+}
+
+func TestReconcile_RenewTimestampRestartsLease(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := renewAnn()
+	start := time.Now().UTC().Add(-50 * time.Minute)
+	obj := newRenewObj("bump", start, "ci", map[string]string{
+		a.TTL:            "1h",
+		a.LeaseStart:     start.Format(time.RFC3339),
+		a.LeaseExtension: "10m",
+		a.RenewCount:     "2",
+		a.Renew:          time.Now().UTC().Format(time.RFC3339),
+	})
+	r, cl := newRenewWatcher(t, obj)
+
+	reconcileName(t, r, "bump")
+	got := get(t, cl, gvk, "default", "bump").GetAnnotations()
+	leaseStart, err := time.Parse(time.RFC3339, got[a.LeaseStart])
+	if err != nil || time.Since(leaseStart) > time.Minute {
+		t.Fatalf("expected the lease to start over, lease-start %q", got[a.LeaseStart])
+	}
+	if _, ok := got[a.LeaseExtension]; ok {
+		t.Fatalf("expected the extension of the old lease to be dropped")
+	}
+	if got[a.RenewCount] != "3" {
+		t.Fatalf("renew-count = %q, want 3", got[a.RenewCount])
+	}
+}
+
+func TestReconcile_RenewDeniedAtMaxRenewals(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := renewAnn()
+	start := time.Now().UTC().Add(-30 * time.Minute).Truncate(time.Second)
+	obj := newRenewObj("limited", start, "kubectl-annotate", map[string]string{
+		a.TTL:         "1h",
+		a.LeaseStart:  start.Format(time.RFC3339),
+		a.RenewCount:  "2",
+		a.MaxRenewals: "5",
+		a.Renew:       "1h",
+	})
+	r, cl := newRenewWatcher(t, obj)
+	// The controller limit is lower than the object's
+	r.MaxRenewals = 2
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	reconcileName(t, r, "limited")
+	got := get(t, cl, gvk, "default", "limited").GetAnnotations()
+	if got[a.ExpireAt] != start.Add(time.Hour).Format(time.RFC3339) || got[a.RenewCount] != "2" {
+		t.Fatalf("expected the lease to stay as it was, expire-at %q and renew-count %q", got[a.ExpireAt], got[a.RenewCount])
+	}
+	if _, ok := got[a.Renew]; ok {
+		t.Fatalf("expected the denied renew annotation to be consumed")
+	}
+	var denied string
+	for len(rec.Events) > 0 {
+		if e := <-rec.Events; strings.Contains(e, "LeaseRenewalDenied") {
+			denied = e
+		}
+	}
+	if !strings.Contains(denied, "kubectl-annotate") || !strings.Contains(denied, "max-renewals of 2") {
+		t.Fatalf("unexpected denial event %q", denied)
+	}
+}
+
+func TestReconcile_MaxLifetimeCapsRenewals(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := renewAnn()
+	created := time.Now().UTC().Add(-50 * time.Minute).Truncate(time.Second)
+	start := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Second)
+	obj := newRenewObj("capped", created, "ci", map[string]string{
+		a.TTL:         "15m",
+		a.LeaseStart:  start.Format(time.RFC3339),
+		a.MaxLifetime: "1h",
+		a.Renew:       "2h",
+	})
+	r, cl := newRenewWatcher(t, obj)
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	res := reconcileName(t, r, "capped")
+	deadline := created.Add(time.Hour)
+	got := get(t, cl, gvk, "default", "capped").GetAnnotations()
+	if got[a.ExpireAt] != deadline.Format(time.RFC3339) {
+		t.Fatalf("expire-at = %q, want the max-lifetime deadline %s", got[a.ExpireAt], deadline.Format(time.RFC3339))
+	}
+	if res.RequeueAfter > 10*time.Minute {
+		t.Fatalf("requeue after %v is past the deadline", res.RequeueAfter)
+	}
+	if n := countEvents(rec, "LeaseRenewed"); n != 1 {
+		t.Fatalf("expected one LeaseRenewed event, got %d", n)
+	}
+
+	// The lease already runs until the deadline
+	u := get(t, cl, gvk, "default", "capped")
+	anns := u.GetAnnotations()
+	anns[a.Renew] = "1h"
+	u.SetAnnotations(anns)
+	if err := cl.Update(t.Context(), u); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	reconcileName(t, r, "capped")
+	if n := countEvents(rec, "LeaseRenewalDenied"); n != 1 {
+		t.Fatalf("expected one LeaseRenewalDenied event, got %d", n)
+	}
+	if got := get(t, cl, gvk, "default", "capped").GetAnnotations(); got[a.RenewCount] != "1" {
+		t.Fatalf("renew-count = %q, want 1", got[a.RenewCount])
+	}
+}
+
+func TestReconcile_InvalidMaxRenewalsStallsLease(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := renewAnn()
+	obj := newExpiredObj(gvk, "invalid-limit")
+	anns := obj.GetAnnotations()
+	anns[a.MaxRenewals] = "many"
+	obj.SetAnnotations(anns)
+	r, cl := newRenewWatcher(t, obj)
+
+	reconcileName(t, r, "invalid-limit")
+	got := get(t, cl, gvk, "default", "invalid-limit")
+	if !strings.HasPrefix(got.GetAnnotations()[a.Status], "Invalid renewal limit") {
+		t.Fatalf("unexpected status %q", got.GetAnnotations()[a.Status])
+	}
+}
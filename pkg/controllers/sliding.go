@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"object-lease-controller/pkg/util"
+)
+
+// activitySnapshot is the last state of an object observed by the controller,
+// taken after the controller's own annotation patches were applied.
+type activitySnapshot struct {
+	uid             types.UID
+	generation      int64
+	resourceVersion string
+	labels          map[string]string
+}
+
+// activityTracker remembers snapshots of sliding-lease objects so user edits
+// can be told apart from the controller's own patches.
+type activityTracker struct {
+	mu        sync.Mutex
+	snapshots map[types.NamespacedName]activitySnapshot
+}
+
+func (t *activityTracker) get(key types.NamespacedName) (activitySnapshot, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.snapshots[key]
+	return s, ok
+}
+
+func (t *activityTracker) record(obj *unstructured.Unstructured) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.snapshots == nil {
+		t.snapshots = map[types.NamespacedName]activitySnapshot{}
+	}
+	t.snapshots[types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}] = snapshotOf(obj)
+}
+
+func (t *activityTracker) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.snapshots, key)
+}
+
+func snapshotOf(obj *unstructured.Unstructured) activitySnapshot {
+	return activitySnapshot{
+		uid:             obj.GetUID(),
+		generation:      obj.GetGeneration(),
+		resourceVersion: obj.GetResourceVersion(),
+		labels:          maps.Clone(obj.GetLabels()),
+	}
+}
+
+// userActivity reports whether cur differs from prev in a way the controller
+// did not cause. Objects with a generation only count spec (generation) and
+// label changes so status updates from other controllers are ignored; objects
+// without a generation (e.g. ConfigMaps) fall back to resourceVersion.
+func userActivity(prev, cur activitySnapshot) bool {
+	if prev.uid != cur.uid {
+		return false
+	}
+	if !maps.Equal(prev.labels, cur.labels) {
+		return true
+	}
+	if cur.generation != 0 {
+		return prev.generation != cur.generation
+	}
+	return prev.resourceVersion != cur.resourceVersion
+}
+
+// slidingUpdate is used by the update predicate to let user edits of
+// sliding-lease objects through
+func (r *LeaseWatcher) slidingUpdate(oldObj, newObj *unstructured.Unstructured) bool {
+	if r.leaseMode(newObj) != util.LeaseModeSliding {
+		return false
+	}
+	return userActivity(snapshotOf(oldObj), snapshotOf(newObj))
+}
+
+// slideLeaseStart restarts the lease at now if the object was edited since the
+// controller last looked at it. Returns the effective lease start.
+func (r *LeaseWatcher) slideLeaseStart(ctx context.Context, obj *unstructured.Unstructured, startAt, now time.Time) time.Time {
+	prev, ok := r.activity.get(types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()})
+	if !ok || !userActivity(prev, snapshotOf(obj)) {
+		return startAt
+	}
+	r.updateAnnotations(ctx, obj, map[string]string{r.Annotations.LeaseStart: now.Format(time.RFC3339)})
+	if r.Recorder != nil {
+		r.Recorder.Eventf(obj, nil, "Normal", "LeaseSlid", "LeaseSlid", "Object changed, sliding lease restarted")
+	}
+	r.recordHistory(ctx, obj, "LeaseSlid", "Object changed, sliding lease restarted", "")
+	if r.Metrics != nil {
+		r.Metrics.LeasesSlid.Inc()
+	}
+	return now
+}
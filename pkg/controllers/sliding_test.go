@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func slidingAnn() Annotations {
+	a := defaultAnn()
+	a.LeaseMode = "object-lease-controller.ullberg.io/lease-mode"
+	return a
+}
+
+func TestUserActivity(t *testing.T) {
+	base := activitySnapshot{uid: "u1", generation: 2, resourceVersion: "10", labels: map[string]string{"a": "b"}}
+	tests := []struct {
+		name string
+		cur  activitySnapshot
+		want bool
+	}{
+		{"unchanged", activitySnapshot{uid: "u1", generation: 2, resourceVersion: "10", labels: map[string]string{"a": "b"}}, false},
+		{"only resourceVersion changed with generation", activitySnapshot{uid: "u1", generation: 2, resourceVersion: "11", labels: map[string]string{"a": "b"}}, false},
+		{"generation changed", activitySnapshot{uid: "u1", generation: 3, resourceVersion: "11", labels: map[string]string{"a": "b"}}, true},
+		{"labels changed", activitySnapshot{uid: "u1", generation: 2, resourceVersion: "11", labels: map[string]string{"a": "c"}}, true},
+		{"different object", activitySnapshot{uid: "u2", generation: 5, resourceVersion: "1"}, false},
+	}
+	for _, tt := range tests {
+		if got := userActivity(base, tt.cur); got != tt.want {
+			t.Errorf("userActivity(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Without a generation, resourceVersion changes count
+	noGen := activitySnapshot{uid: "u1", resourceVersion: "10"}
+	if !userActivity(noGen, activitySnapshot{uid: "u1", resourceVersion: "12"}) {
+		t.Errorf("expected resourceVersion change to count when generation is unset")
+	}
+}
+
+func TestSlidingUpdatePredicate(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	r, _, _ := newWatcher(t, gvk)
+	r.Annotations = slidingAnn()
+	pred := r.onlyWithTTLAnnotation()
+
+	mk := func(gen int64, mode string) *unstructured.Unstructured {
+		u := makeObj(map[string]string{r.Annotations.TTL: "1h", r.Annotations.LeaseMode: mode})
+		u.SetGeneration(gen)
+		return u
+	}
+
+	if !pred.UpdateFunc(event.UpdateEvent{ObjectOld: mk(1, "sliding"), ObjectNew: mk(2, "sliding")}) {
+		t.Fatalf("expected generation change on sliding lease to trigger reconcile")
+	}
+	if pred.UpdateFunc(event.UpdateEvent{ObjectOld: mk(1, "fixed"), ObjectNew: mk(2, "fixed")}) {
+		t.Fatalf("expected generation change on fixed lease to be ignored")
+	}
+}
+
+func TestReconcile_SlidingLeaseRestartsOnUserEdit(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	start := time.Now().UTC().Add(-30 * time.Minute).Truncate(time.Second)
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "slide1")
+	obj.SetAnnotations(map[string]string{
+		slidingAnn().TTL:        "1h",
+		slidingAnn().LeaseMode:  "sliding",
+		slidingAnn().LeaseStart: start.Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = slidingAnn()
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "slide1"}}
+
+	// First reconcile records the baseline, a second one must not slide on our own patches
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile error: %v", err)
+		}
+	}
+	if got := get(t, cl, gvk, "default", "slide1").GetAnnotations()[r.Annotations.LeaseStart]; got != start.Format(time.RFC3339) {
+		t.Fatalf("lease-start moved without user edit: %q", got)
+	}
+
+	// A user edit restarts the lease
+	cur := get(t, cl, gvk, "default", "slide1")
+	cur.SetLabels(map[string]string{"touched": "yes"})
+	if err := cl.Update(ctx, cur); err != nil {
+		t.Fatalf("update error: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	ls, err := time.Parse(time.RFC3339, get(t, cl, gvk, "default", "slide1").GetAnnotations()[r.Annotations.LeaseStart])
+	if err != nil {
+		t.Fatalf("lease-start parse error: %v", err)
+	}
+	if !ls.After(start) {
+		t.Fatalf("expected lease-start to slide forward from %v, got %v", start, ls)
+	}
+
+	found := false
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, "LeaseSlid") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected LeaseSlid event")
+	}
+}
+
+func TestReconcile_InvalidLeaseModeFallsBackToFixed(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "badmode")
+	obj.SetAnnotations(map[string]string{
+		slidingAnn().TTL:       "1h",
+		slidingAnn().LeaseMode: "wobbly",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = slidingAnn()
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "badmode"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if get(t, cl, gvk, "default", "badmode").GetAnnotations()[r.Annotations.ExpireAt] == "" {
+		t.Fatalf("expected expire-at to be set for fixed fallback")
+	}
+	found := false
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, "InvalidLeaseMode") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected InvalidLeaseMode event")
+	}
+}
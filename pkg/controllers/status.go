@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"object-lease-controller/pkg/util"
+)
+
+// Condition types of the structured status, following kstatus. Reconciling
+// and Stalled are only present while they are true.
+const (
+	ConditionReady       = "Ready"
+	ConditionReconciling = "Reconciling"
+	ConditionStalled     = "Stalled"
+)
+
+// readyReasons are the reasons of a lease that is running
+var readyReasons = map[string]bool{
+	"LeaseActive":       true,
+	"LeaseExpiringSoon": true,
+	"LeasePaused":       true,
+}
+
+// settledReasons are the reasons of an expired lease the controller has
+// nothing left to do for, until it is renewed or its grace period ends
+var settledReasons = map[string]bool{
+	"ExpiryActionApplied": true,
+	"LeaseWouldExpire":    true,
+	"LeaseHibernated":     true,
+}
+
+// LeaseStatus is the machine-readable counterpart of lease-status
+type LeaseStatus struct {
+	Phase    string `json:"phase,omitempty"`
+	ExpireAt string `json:"expireAt,omitempty"`
+	// Remaining is the time that was left when the status last changed
+	Remaining string `json:"remaining,omitempty"`
+	// LastTransitionTime is when the phase or reason last changed
+	LastTransitionTime string             `json:"lastTransitionTime"`
+	Reason             string             `json:"reason"`
+	Message            string             `json:"message"`
+	RenewCount         int                `json:"renewCount"`
+	CleanupJob         *CleanupJobRef     `json:"cleanupJob,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions"`
+}
+
+// CleanupJobRef points at the cleanup job of a lease
+type CleanupJobRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// setStatus writes the human lease-status together with the structured
+// status, along with the other updates
+func (r *LeaseWatcher) setStatus(ctx context.Context, obj *unstructured.Unstructured, reason, message string, updates map[string]string) {
+	if updates == nil {
+		updates = map[string]string{}
+	}
+	updates[r.Annotations.Status] = message
+	if r.Annotations.StatusJSON != "" {
+		updates[r.Annotations.StatusJSON] = r.structuredStatus(obj, reason, message, updates)
+	}
+	r.updateAnnotations(ctx, obj, updates)
+}
+
+// structuredStatus renders the structured status obj gets with updates
+// applied. The previous status is kept when only the remaining time would
+// change, so reconciles without news do not write to the object.
+func (r *LeaseWatcher) structuredStatus(obj *unstructured.Unstructured, reason, message string, updates map[string]string) string {
+	anns := obj.GetAnnotations()
+	value := func(key string) string {
+		if v, ok := updates[key]; ok && key != "" {
+			return v
+		}
+		return anns[key]
+	}
+	now := time.Now().UTC()
+
+	status := LeaseStatus{
+		Phase:              value(r.Annotations.Phase),
+		ExpireAt:           value(r.Annotations.ExpireAt),
+		LastTransitionTime: now.Format(time.RFC3339),
+		Reason:             reason,
+		Message:            message,
+	}
+	if expireAt, err := time.Parse(time.RFC3339, status.ExpireAt); err == nil {
+		remaining := expireAt.Sub(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		status.Remaining = r.formatDuration(obj, remaining.Truncate(time.Second))
+	}
+	if count, err := strconv.Atoi(value(r.Annotations.RenewCount)); err == nil {
+		status.RenewCount = count
+	}
+	if name := value(r.Annotations.CleanupJobName); name != "" {
+		namespace, _ := r.cleanupJobNamespace(obj)
+		status.CleanupJob = &CleanupJobRef{Namespace: namespace, Name: name}
+	}
+
+	prev := anns[r.Annotations.StatusJSON]
+	var old LeaseStatus
+	hasOld := prev != "" && json.Unmarshal([]byte(prev), &old) == nil
+	if hasOld {
+		status.Conditions = old.Conditions
+		if old.Phase == status.Phase && old.Reason == status.Reason {
+			status.LastTransitionTime = old.LastTransitionTime
+		}
+	}
+	setLeaseConditions(&status.Conditions, reason, message)
+
+	if hasOld {
+		unchanged := status
+		unchanged.Remaining = old.Remaining
+		if b, err := json.Marshal(unchanged); err == nil && string(b) == prev {
+			return prev
+		}
+	}
+	b, _ := json.Marshal(status)
+	return string(b)
+}
+
+// formatDuration formats d for the status of obj. Leases with an ISO 8601
+// TTL get ISO 8601 durations back.
+func (r *LeaseWatcher) formatDuration(obj *unstructured.Unstructured, d time.Duration) string {
+	if util.IsISODuration(obj.GetAnnotations()[r.Annotations.TTL]) {
+		return util.FormatISODuration(d)
+	}
+	return util.FormatFlexibleDuration(d)
+}
+
+// setLeaseConditions updates the kstatus conditions for reason. Invalid
+// settings stall the lease, expiries in progress are reconciling.
+func setLeaseConditions(conditions *[]metav1.Condition, reason, message string) {
+	ready := readyReasons[reason]
+	stalled := strings.HasPrefix(reason, "Invalid")
+	reconciling := !ready && !stalled && !settledReasons[reason]
+
+	readyStatus := metav1.ConditionFalse
+	if ready {
+		readyStatus = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{Type: ConditionReady, Status: readyStatus, Reason: reason, Message: message})
+	for _, c := range []struct {
+		condType string
+		on       bool
+	}{{ConditionReconciling, reconciling}, {ConditionStalled, stalled}} {
+		if c.on {
+			meta.SetStatusCondition(conditions, metav1.Condition{Type: c.condType, Status: metav1.ConditionTrue, Reason: reason, Message: message})
+		} else {
+			meta.RemoveStatusCondition(conditions, c.condType)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testStatusJSON = "object-lease-controller.ullberg.io/lease-status-json"
+
+func newStatusWatcher(t *testing.T, objs ...client.Object) (*LeaseWatcher, client.Client) {
+	t.Helper()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	r, cl, _ := newWatcher(t, gvk, objs...)
+	r.Annotations.Phase = "object-lease-controller.ullberg.io/lease-phase"
+	r.Annotations.StatusJSON = testStatusJSON
+	return r, cl
+}
+
+func leaseStatusOf(t *testing.T, cl client.Client, name string) LeaseStatus {
+	t.Helper()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	raw := get(t, cl, gvk, "default", name).GetAnnotations()[testStatusJSON]
+	var status LeaseStatus
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		t.Fatalf("invalid structured status %q: %v", raw, err)
+	}
+	return status
+}
+
+func TestReconcile_ActiveLeaseHasStructuredStatus(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "active")
+	start := time.Now().UTC().Truncate(time.Second)
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "1h", defaultAnn().LeaseStart: start.Format(time.RFC3339)})
+	r, cl := newStatusWatcher(t, obj)
+
+	reconcileName(t, r, "active")
+	status := leaseStatusOf(t, cl, "active")
+	if status.Phase != PhaseActive || status.Reason != "LeaseActive" {
+		t.Fatalf("unexpected phase %q and reason %q", status.Phase, status.Reason)
+	}
+	if status.ExpireAt != start.Add(time.Hour).Format(time.RFC3339) || status.Remaining == "" {
+		t.Fatalf("unexpected expireAt %q and remaining %q", status.ExpireAt, status.Remaining)
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, ConditionReady) {
+		t.Fatalf("expected Ready to be true, got %v", status.Conditions)
+	}
+	if meta.FindStatusCondition(status.Conditions, ConditionReconciling) != nil || meta.FindStatusCondition(status.Conditions, ConditionStalled) != nil {
+		t.Fatalf("expected only the Ready condition, got %v", status.Conditions)
+	}
+	if human := get(t, cl, gvk, "default", "active").GetAnnotations()[defaultAnn().Status]; human != status.Message {
+		t.Fatalf("expected the human status %q as message, got %q", human, status.Message)
+	}
+
+	// Only the remaining time changed, the status is left alone
+	before := get(t, cl, gvk, "default", "active").GetAnnotations()[testStatusJSON]
+	time.Sleep(1100 * time.Millisecond)
+	reconcileName(t, r, "active")
+	if after := get(t, cl, gvk, "default", "active").GetAnnotations()[testStatusJSON]; after != before {
+		t.Fatalf("structured status changed without news: %s vs %s", before, after)
+	}
+}
+
+func TestReconcile_InvalidTTLStallsStructuredStatus(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "invalid")
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "soon"})
+	r, cl := newStatusWatcher(t, obj)
+
+	reconcileName(t, r, "invalid")
+	status := leaseStatusOf(t, cl, "invalid")
+	if status.Reason != "InvalidTTL" {
+		t.Fatalf("reason = %q, want InvalidTTL", status.Reason)
+	}
+	if !meta.IsStatusConditionTrue(status.Conditions, ConditionStalled) || meta.IsStatusConditionTrue(status.Conditions, ConditionReady) {
+		t.Fatalf("expected Stalled and not Ready, got %v", status.Conditions)
+	}
+}
+
+func TestSetLeaseConditions_TransitionsKeepReadyTime(t *testing.T) {
+	var conditions []metav1.Condition
+	setLeaseConditions(&conditions, "LeaseActive", "Lease active.")
+	ready := meta.FindStatusCondition(conditions, ConditionReady)
+	ready.LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	since := ready.LastTransitionTime
+
+	setLeaseConditions(&conditions, "LeaseExpiringSoon", "Lease expiring soon.")
+	if got := meta.FindStatusCondition(conditions, ConditionReady); !got.LastTransitionTime.Equal(&since) || got.Reason != "LeaseExpiringSoon" {
+		t.Fatalf("expected Ready to keep its transition time with the new reason, got %+v", got)
+	}
+
+	setLeaseConditions(&conditions, "LeaseGroupWaiting", "Waiting.")
+	if meta.IsStatusConditionTrue(conditions, ConditionReady) || !meta.IsStatusConditionTrue(conditions, ConditionReconciling) {
+		t.Fatalf("expected Reconciling and not Ready, got %v", conditions)
+	}
+
+	setLeaseConditions(&conditions, "ExpiryActionApplied", "Applied.")
+	if meta.FindStatusCondition(conditions, ConditionReconciling) != nil {
+		t.Fatalf("expected Reconciling to be removed once the action is applied, got %v", conditions)
+	}
+}
+
+func TestReconcile_ISOTTLReportsISODurations(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "iso")
+	start := time.Now().UTC().Truncate(time.Second)
+	obj.SetAnnotations(map[string]string{defaultAnn().TTL: "P1DT2H", defaultAnn().LeaseStart: start.Format(time.RFC3339)})
+	r, cl := newStatusWatcher(t, obj)
+
+	reconcileName(t, r, "iso")
+	status := leaseStatusOf(t, cl, "iso")
+	if status.ExpireAt != start.Add(26*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("expireAt = %q, want 26h after the start", status.ExpireAt)
+	}
+	if !strings.HasPrefix(status.Remaining, "P1DT") {
+		t.Fatalf("expected an ISO 8601 remaining time, got %q", status.Remaining)
+	}
+}
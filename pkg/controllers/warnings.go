@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"object-lease-controller/pkg/util"
+)
+
+// warningThresholds returns the pre-expiry warning thresholds for obj, longest
+// first. The expiry-warnings annotation overrides the controller-wide setting.
+func (r *LeaseWatcher) warningThresholds(obj *unstructured.Unstructured) []time.Duration {
+	v := obj.GetAnnotations()[r.Annotations.ExpiryWarnings]
+	if r.Annotations.ExpiryWarnings == "" || v == "" {
+		return r.WarningThresholds
+	}
+	thresholds, err := util.ParseDurationList(v)
+	if err != nil {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(obj, nil, "Warning", "InvalidExpiryWarnings", "InvalidExpiryWarnings", "Invalid expiry-warnings: %v, using controller defaults", err)
+		}
+		return r.WarningThresholds
+	}
+	return thresholds
+}
+
+// crossedWarning finds the tightest threshold that remaining has dropped below
+// and how long until the next threshold (or expiry) is reached. thresholds
+// must be sorted longest first.
+func crossedWarning(thresholds []time.Duration, remaining time.Duration) (crossed time.Duration, ok bool, requeue time.Duration) {
+	requeue = remaining
+	for _, t := range thresholds {
+		if remaining <= t {
+			crossed, ok = t, true
+			continue
+		}
+		// First threshold still ahead of us
+		requeue = remaining - t
+		break
+	}
+	return crossed, ok, requeue
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	controller_runtime "sigs.k8s.io/controller-runtime"
+
+	ometrics "object-lease-controller/pkg/metrics"
+)
+
+func warningsAnn() Annotations {
+	a := defaultAnn()
+	a.ExpiryWarnings = "object-lease-controller.ullberg.io/expiry-warnings"
+	return a
+}
+
+func countEvents(rec *fakeEventsRecorder, reason string) int {
+	n := 0
+	for len(rec.Events) > 0 {
+		if strings.Contains(<-rec.Events, reason) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCrossedWarning(t *testing.T) {
+	thresholds := []time.Duration{24 * time.Hour, time.Hour, 10 * time.Minute}
+	tests := []struct {
+		remaining   time.Duration
+		wantCrossed time.Duration
+		wantOK      bool
+		wantRequeue time.Duration
+	}{
+		{48 * time.Hour, 0, false, 24 * time.Hour},
+		{24 * time.Hour, 24 * time.Hour, true, 23 * time.Hour},
+		{2 * time.Hour, 24 * time.Hour, true, time.Hour},
+		{30 * time.Minute, time.Hour, true, 20 * time.Minute},
+		{5 * time.Minute, 10 * time.Minute, true, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		crossed, ok, requeue := crossedWarning(thresholds, tt.remaining)
+		if crossed != tt.wantCrossed || ok != tt.wantOK || requeue != tt.wantRequeue {
+			t.Errorf("crossedWarning(%v) = (%v, %v, %v), want (%v, %v, %v)", tt.remaining, crossed, ok, requeue, tt.wantCrossed, tt.wantOK, tt.wantRequeue)
+		}
+	}
+
+	if _, ok, requeue := crossedWarning(nil, time.Hour); ok || requeue != time.Hour {
+		t.Errorf("no thresholds should requeue at expiry, got ok=%v requeue=%v", ok, requeue)
+	}
+}
+
+func TestReconcile_ExpiryWarningSentOncePerThreshold(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := warningsAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "warn")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:        "2h",
+		a.LeaseStart: time.Now().UTC().Add(-90 * time.Minute).Format(time.RFC3339),
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	reg := withIsolatedRegistry(t)
+	r.Metrics = ometrics.NewLeaseMetrics(gvk)
+	r.Annotations = a
+	r.WarningThresholds = []time.Duration{24 * time.Hour, time.Hour, 10 * time.Minute}
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+	req := controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "warn"}}
+
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	// About 30m left, next warning at 10m
+	if res.RequeueAfter <= 15*time.Minute || res.RequeueAfter > 20*time.Minute {
+		t.Fatalf("RequeueAfter = %v, want about 20m", res.RequeueAfter)
+	}
+	status := get(t, cl, gvk, "default", "warn").GetAnnotations()[a.Status]
+	if !strings.Contains(status, "expiring soon") || !strings.Contains(status, "less than 1h") {
+		t.Fatalf("unexpected status %q", status)
+	}
+	if n := countEvents(rec, "LeaseExpiringSoon"); n != 1 {
+		t.Fatalf("expected one LeaseExpiringSoon event, got %d", n)
+	}
+
+	// Same threshold on the next pass, no repeat warning
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if n := countEvents(rec, "LeaseExpiringSoon"); n != 0 {
+		t.Fatalf("expected no repeat warning, got %d", n)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == "object_lease_controller_expiry_warnings_total" {
+			if v := mf.Metric[0].GetCounter().GetValue(); v != 1 {
+				t.Fatalf("expiry_warnings_total = %v, want 1", v)
+			}
+			return
+		}
+	}
+	t.Fatalf("expiry_warnings_total metric not found")
+}
+
+func TestReconcile_ExpiryWarningsAnnotationOverride(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := warningsAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "override")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:            "2h",
+		a.LeaseStart:     time.Now().UTC().Add(-90 * time.Minute).Format(time.RFC3339),
+		a.ExpiryWarnings: "5m",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = a
+	r.WarningThresholds = []time.Duration{time.Hour}
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "override"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	status := get(t, cl, gvk, "default", "override").GetAnnotations()[a.Status]
+	if !strings.HasPrefix(status, "Lease active.") {
+		t.Fatalf("annotation thresholds should replace the controller default, got %q", status)
+	}
+}
+
+func TestReconcile_InvalidExpiryWarningsFallsBack(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
+	a := warningsAnn()
+
+	obj := &unstructured.Unstructured{}
+	setMeta(obj, gvk, "default", "bad")
+	obj.SetAnnotations(map[string]string{
+		a.TTL:            "2h",
+		a.LeaseStart:     time.Now().UTC().Add(-90 * time.Minute).Format(time.RFC3339),
+		a.ExpiryWarnings: "later",
+	})
+
+	r, cl, _ := newWatcher(t, gvk, obj)
+	r.Annotations = a
+	r.WarningThresholds = []time.Duration{time.Hour}
+	rec := newFakeEventsRecorder(10)
+	r.Recorder = rec
+
+	if _, err := r.Reconcile(ctx, controller_runtime.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "bad"}}); err != nil {
+		t.Fatalf("reconcile error: %v", err)
+	}
+	if n := countEvents(rec, "InvalidExpiryWarnings"); n != 1 {
+		t.Fatalf("expected InvalidExpiryWarnings event, got %d", n)
+	}
+	status := get(t, cl, gvk, "default", "bad").GetAnnotations()[a.Status]
+	if !strings.Contains(status, "expiring soon") {
+		t.Fatalf("expected controller thresholds to apply, got %q", status)
+	}
+}
@@ -13,15 +13,34 @@ type LeaseMetrics struct {
 	Info              prometheus.Gauge
 	LeasesStarted     prometheus.Counter
 	LeasesExpired     prometheus.Counter
-	InvalidTTL        prometheus.Counter
-	ReconcileErrors   prometheus.Counter
-	ReconcileDuration prometheus.Histogram
+	LeasesSlid        prometheus.Counter
+	HeartbeatsMissed  prometheus.Counter
+	LeasesHibernated  prometheus.Counter
+	LeasesResumed     prometheus.Counter
+	ExpiryWarnings    prometheus.Counter
+	LeasesWouldExpire prometheus.Counter
+	LeasesPaused      prometheus.Counter
+	LeasesRenewed     prometheus.Counter
+	RenewalsDenied    prometheus.Counter
+	// DeletionsFrozen is 1 while the kill-switch ConfigMap freezes deletions
+	DeletionsFrozen prometheus.Gauge
+	// Deletion rate limit and mass-expiry circuit breaker
+	DeletionsRateLimited prometheus.Counter
+	CircuitBreakerOpen   prometheus.Gauge
+	CircuitBreakerTrips  prometheus.Counter
+	InvalidTTL           prometheus.Counter
+	InvalidDeleteAt      prometheus.Counter
+	ReconcileErrors      prometheus.Counter
+	ReconcileDuration    prometheus.Histogram
 
 	// Cleanup job metrics
 	CleanupJobsCreated   prometheus.Counter
 	CleanupJobsFailed    prometheus.Counter
 	CleanupJobsCompleted prometheus.Counter
 	CleanupJobDuration   prometheus.Histogram
+
+	// ExpiryActions counts on-expire actions by action name and result
+	ExpiryActions *prometheus.CounterVec
 }
 
 // NewLeaseMetrics registers and returns metrics scoped to a specific GVK via const labels.
@@ -51,12 +70,96 @@ func NewLeaseMetrics(gvk schema.GroupVersionKind) *LeaseMetrics {
 			Help:        "Number of leases that have expired",
 			ConstLabels: constLabels,
 		}),
+		LeasesSlid: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "leases_slid_total",
+			Help:        "Number of sliding leases restarted because the object was edited",
+			ConstLabels: constLabels,
+		}),
+		HeartbeatsMissed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "heartbeats_missed_total",
+			Help:        "Number of heartbeat leases whose heartbeat was overdue by half the lease window",
+			ConstLabels: constLabels,
+		}),
+		LeasesHibernated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "leases_hibernated_total",
+			Help:        "Number of expired leases whose workload was scaled to zero before deletion",
+			ConstLabels: constLabels,
+		}),
+		LeasesResumed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "leases_resumed_total",
+			Help:        "Number of hibernated workloads restored after their lease was renewed",
+			ConstLabels: constLabels,
+		}),
+		ExpiryWarnings: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "expiry_warnings_total",
+			Help:        "Number of pre-expiry warnings sent to lease owners",
+			ConstLabels: constLabels,
+		}),
+		LeasesWouldExpire: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "leases_would_expire_total",
+			Help:        "Number of leases that expired while the controller runs in dry-run mode",
+			ConstLabels: constLabels,
+		}),
+		LeasesPaused: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "leases_paused_total",
+			Help:        "Number of times a lease countdown was paused",
+			ConstLabels: constLabels,
+		}),
+		LeasesRenewed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "leases_renewed_total",
+			Help:        "Number of lease renewals granted",
+			ConstLabels: constLabels,
+		}),
+		RenewalsDenied: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "lease_renewals_denied_total",
+			Help:        "Number of lease renewals denied by max-renewals or max-lifetime",
+			ConstLabels: constLabels,
+		}),
+		DeletionsFrozen: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "deletions_frozen",
+			Help:        "1 while the kill switch freezes deletions, 0 otherwise",
+			ConstLabels: constLabels,
+		}),
+		DeletionsRateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "deletions_rate_limited_total",
+			Help:        "Number of times an expiry was delayed by the deletion rate limit",
+			ConstLabels: constLabels,
+		}),
+		CircuitBreakerOpen: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "circuit_breaker_open",
+			Help:        "1 while the mass-expiry circuit breaker halts deletions, 0 otherwise",
+			ConstLabels: constLabels,
+		}),
+		CircuitBreakerTrips: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "circuit_breaker_trips_total",
+			Help:        "Number of times the mass-expiry circuit breaker opened",
+			ConstLabels: constLabels,
+		}),
 		InvalidTTL: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace:   "object_lease_controller",
 			Name:        "invalid_ttl_total",
 			Help:        "Number of objects with invalid TTL annotation encountered",
 			ConstLabels: constLabels,
 		}),
+		InvalidDeleteAt: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "invalid_delete_at_total",
+			Help:        "Number of objects with invalid delete-at annotation encountered",
+			ConstLabels: constLabels,
+		}),
 		ReconcileErrors: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace:   "object_lease_controller",
 			Name:        "reconcile_errors_total",
@@ -95,19 +198,40 @@ func NewLeaseMetrics(gvk schema.GroupVersionKind) *LeaseMetrics {
 			Buckets:     prometheus.DefBuckets,
 			ConstLabels: constLabels,
 		}),
+		ExpiryActions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "object_lease_controller",
+			Name:        "expiry_actions_total",
+			Help:        "Number of on-expire actions executed, by action and result",
+			ConstLabels: constLabels,
+		}, []string{"action", "result"}),
 	}
 
 	crmetrics.Registry.MustRegister(
 		m.Info,
 		m.LeasesStarted,
 		m.LeasesExpired,
+		m.LeasesSlid,
+		m.HeartbeatsMissed,
+		m.LeasesHibernated,
+		m.LeasesResumed,
+		m.ExpiryWarnings,
+		m.LeasesWouldExpire,
+		m.LeasesPaused,
+		m.LeasesRenewed,
+		m.RenewalsDenied,
+		m.DeletionsFrozen,
+		m.DeletionsRateLimited,
+		m.CircuitBreakerOpen,
+		m.CircuitBreakerTrips,
 		m.InvalidTTL,
+		m.InvalidDeleteAt,
 		m.ReconcileErrors,
 		m.ReconcileDuration,
 		m.CleanupJobsCreated,
 		m.CleanupJobsFailed,
 		m.CleanupJobsCompleted,
 		m.CleanupJobDuration,
+		m.ExpiryActions,
 	)
 
 	// Ensure info is visible.
@@ -0,0 +1,294 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LeaseClock selects which time counts towards a TTL
+type LeaseClock string
+
+const (
+	// LeaseClockWall counts every hour (default)
+	LeaseClockWall LeaseClock = "wall"
+	// LeaseClockBusinessHours only counts time inside business hours on
+	// business days that are not holidays
+	LeaseClockBusinessHours LeaseClock = "business-hours"
+)
+
+// ParseLeaseClock parses a lease-clock annotation value. An empty value means LeaseClockWall.
+func ParseLeaseClock(val string) (LeaseClock, error) {
+	switch LeaseClock(strings.ToLower(strings.TrimSpace(val))) {
+	case "", LeaseClockWall:
+		return LeaseClockWall, nil
+	case LeaseClockBusinessHours:
+		return LeaseClockBusinessHours, nil
+	default:
+		return LeaseClockWall, fmt.Errorf("unknown lease clock: %q", val)
+	}
+}
+
+// Business hours and days used when none are configured
+const (
+	DefaultBusinessHours = "09:00-17:00"
+	DefaultBusinessDays  = "mon-fri"
+)
+
+// DefaultBusinessClock returns the shared clock for the default business
+// hours and days, without holidays
+var DefaultBusinessClock = sync.OnceValue(func() *BusinessClock {
+	c, err := NewBusinessClock(DefaultBusinessHours, DefaultBusinessDays)
+	if err != nil {
+		panic(fmt.Sprintf("default business clock: %v", err))
+	}
+	return c
+})
+
+// BusinessClock counts time inside business hours. The hours and days are
+// fixed, the holidays are kept up to date from the holiday ConfigMap. Hours
+// and dates are read in the timezone of each lease.
+type BusinessClock struct {
+	// open and close are minutes after midnight
+	open, close int
+	days        [7]bool
+
+	mu       sync.RWMutex
+	holidays map[string]bool
+}
+
+// NewBusinessClock parses business hours like "09:00-17:00" and business days
+// like "mon-fri" or "mon,tue,thu". Empty values use the defaults.
+func NewBusinessClock(hours, days string) (*BusinessClock, error) {
+	if strings.TrimSpace(hours) == "" {
+		hours = DefaultBusinessHours
+	}
+	if strings.TrimSpace(days) == "" {
+		days = DefaultBusinessDays
+	}
+	c := &BusinessClock{}
+	open, closing, ok := strings.Cut(strings.TrimSpace(hours), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid business hours %q: expected HH:MM-HH:MM", hours)
+	}
+	var err error
+	if c.open, err = parseMinuteOfDay(open); err != nil {
+		return nil, fmt.Errorf("invalid business hours %q: %w", hours, err)
+	}
+	if c.close, err = parseMinuteOfDay(closing); err != nil {
+		return nil, fmt.Errorf("invalid business hours %q: %w", hours, err)
+	}
+	if c.open >= c.close {
+		return nil, fmt.Errorf("invalid business hours %q: they have to open before they close", hours)
+	}
+
+	for _, part := range strings.Split(strings.ToLower(days), ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		from, ok := weekdays[first]
+		if !ok {
+			return nil, fmt.Errorf("invalid business days %q: unknown day %q", days, first)
+		}
+		to := from
+		if isRange {
+			if to, ok = weekdays[last]; !ok {
+				return nil, fmt.Errorf("invalid business days %q: unknown day %q", days, last)
+			}
+		}
+		// Ranges may wrap around the week, e.g. sun-thu or fri-mon
+		for d := from; ; d = (d + 1) % 7 {
+			c.days[d] = true
+			if d == to {
+				break
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseMinuteOfDay parses HH:MM into minutes after midnight, 24:00 included
+func parseMinuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if strings.TrimSpace(s) == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q", s)
+}
+
+// SetHolidays replaces the holidays, given as 2006-01-02 dates. Returns true
+// if they changed.
+func (c *BusinessClock) SetHolidays(dates []string) bool {
+	holidays := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		holidays[d] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed := len(holidays) != len(c.holidays)
+	for d := range holidays {
+		if !c.holidays[d] {
+			changed = true
+		}
+	}
+	c.holidays = holidays
+	return changed
+}
+
+// Holidays returns the holidays, sorted
+func (c *BusinessClock) Holidays() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	dates := make([]string, 0, len(c.holidays))
+	for d := range c.holidays {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// IsHoliday reports whether the day of t in loc is a holiday
+func (c *BusinessClock) IsHoliday(t time.Time, loc *time.Location) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.holidays[t.In(loc).Format("2006-01-02")]
+}
+
+// businessDay reports whether the day of t is a business day and no holiday
+func (c *BusinessClock) businessDay(t time.Time) bool {
+	return c.days[t.Weekday()] && !c.IsHoliday(t, t.Location())
+}
+
+// hours returns when the day of t opens and closes
+func (c *BusinessClock) hours(t time.Time) (time.Time, time.Time) {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, c.open, 0, 0, t.Location()), time.Date(y, m, d, 0, c.close, 0, 0, t.Location())
+}
+
+// nextDay returns midnight of the day after t
+func nextDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
+
+// Add returns when d of business time has passed after start. Negative
+// durations are wall-clock time.
+func (c *BusinessClock) Add(start time.Time, d time.Duration, loc *time.Location) time.Time {
+	if d <= 0 {
+		return start.Add(d).UTC()
+	}
+	t := start.In(loc)
+	for {
+		if c.businessDay(t) {
+			open, closing := c.hours(t)
+			if t.Before(open) {
+				t = open
+			}
+			if t.Before(closing) {
+				left := closing.Sub(t)
+				if d <= left {
+					return t.Add(d).UTC()
+				}
+				d -= left
+			}
+		}
+		t = nextDay(t)
+	}
+}
+
+// Between returns the business time from from until to
+func (c *BusinessClock) Between(from, to time.Time, loc *time.Location) time.Duration {
+	var total time.Duration
+	for t := from.In(loc); t.Before(to); t = nextDay(t) {
+		if !c.businessDay(t) {
+			continue
+		}
+		open, closing := c.hours(t)
+		if to.Before(closing) {
+			closing = to
+		}
+		if t.After(open) {
+			open = t
+		}
+		if closing.After(open) {
+			total += closing.Sub(open)
+		}
+	}
+	return total
+}
+
+// NextChange returns the next time after t business hours open or close
+func (c *BusinessClock) NextChange(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	for day := t; ; day = nextDay(day) {
+		if !c.businessDay(day) {
+			continue
+		}
+		open, closing := c.hours(day)
+		if open.After(t) {
+			return open.UTC()
+		}
+		if closing.After(t) {
+			return closing.UTC()
+		}
+	}
+}
+
+// EndOfBusinessDay returns when business hours close on the current business
+// day, or on the next one when they are closed for the day or t falls on a
+// day off or a holiday
+func (c *BusinessClock) EndOfBusinessDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	for day := t; ; day = nextDay(day) {
+		if !c.businessDay(day) {
+			continue
+		}
+		if _, closing := c.hours(day); closing.After(t) {
+			return closing.UTC()
+		}
+	}
+}
+
+// EndOfWeek returns when business hours close on the last business day of the
+// week, or of the next week when that has passed. The week ends before the
+// longest run of days off, so on Friday for mon-fri and on Thursday for
+// sun-thu. A holiday moves the end back to the business day before it.
+func (c *BusinessClock) EndOfWeek(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	last := c.lastDayOfWeek()
+	y, m, d := t.Date()
+	week := time.Date(y, m, d+(int(last)-int(t.Weekday())+7)%7, 0, 0, 0, 0, loc)
+	for ; ; week = week.AddDate(0, 0, 7) {
+		for day := week; day.After(week.AddDate(0, 0, -7)); day = day.AddDate(0, 0, -1) {
+			if !c.businessDay(day) {
+				continue
+			}
+			if _, closing := c.hours(day); closing.After(t) {
+				return closing.UTC()
+			}
+			break
+		}
+	}
+}
+
+// lastDayOfWeek returns the business day followed by the longest run of days
+// off, or Saturday when every day is a business day
+func (c *BusinessClock) lastDayOfWeek() time.Weekday {
+	last, longest := time.Saturday, 0
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if !c.days[d] {
+			continue
+		}
+		off := 0
+		for !c.days[(int(d)+off+1)%7] {
+			off++
+		}
+		if off > longest {
+			last, longest = d, off
+		}
+	}
+	return last
+}
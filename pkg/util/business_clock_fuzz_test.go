@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// FuzzBusinessClock checks that Between counts back the business time Add
+// added, whatever the hours, days and start
+func FuzzBusinessClock(f *testing.F) {
+	f.Add("09:00-17:00", "mon-fri", int64(1749722400), int64(16*time.Hour))
+	f.Add("00:00-24:00", "sun-sat", int64(0), int64(time.Minute))
+	f.Add("22:30-23:15", "sat", int64(-86400), int64(90*time.Minute))
+
+	f.Fuzz(func(t *testing.T, hours, days string, start, d int64) {
+		c, err := NewBusinessClock(hours, days)
+		if err != nil {
+			return
+		}
+		ttl := time.Duration(d) % (1000 * time.Hour)
+		if ttl < 0 {
+			ttl = -ttl
+		}
+		from := time.Unix(start%(1<<40), 0).UTC()
+		end := c.Add(from, ttl, time.UTC)
+		if end.Before(from) {
+			t.Fatalf("Add(%s, %s) = %s, before the start", from, ttl, end)
+		}
+		if got := c.Between(from, end, time.UTC); got != ttl {
+			t.Fatalf("Between(%s, %s) = %s, want %s", from, end, got, ttl)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLeaseClock(t *testing.T) {
+	for val, want := range map[string]LeaseClock{"": LeaseClockWall, "wall": LeaseClockWall, " Business-Hours ": LeaseClockBusinessHours} {
+		if got, err := ParseLeaseClock(val); err != nil || got != want {
+			t.Errorf("ParseLeaseClock(%q) = (%q, %v), want %q", val, got, err, want)
+		}
+	}
+	if got, err := ParseLeaseClock("office"); err == nil || got != LeaseClockWall {
+		t.Errorf("ParseLeaseClock(office) = (%q, %v), want the wall clock and an error", got, err)
+	}
+}
+
+func TestNewBusinessClock(t *testing.T) {
+	c, err := NewBusinessClock("08:30-24:00", "sun-tue, fri")
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	if c.open != 8*60+30 || c.close != 24*60 {
+		t.Fatalf("hours = %d-%d", c.open, c.close)
+	}
+	want := [7]bool{true, true, true, false, false, true, false}
+	if c.days != want {
+		t.Fatalf("days = %v, want %v", c.days, want)
+	}
+
+	if c, err := NewBusinessClock("", " "); err != nil || c.open != 9*60 || !c.days[time.Friday] || c.days[time.Saturday] {
+		t.Fatalf("expected the default business hours, got %+v (%v)", c, err)
+	}
+
+	for _, tt := range []struct{ hours, days string }{
+		{"9-17", "mon-fri"},
+		{"17:00-09:00", "mon-fri"},
+		{"09:00-09:00", "mon-fri"},
+		{"09:00-25:00", "mon-fri"},
+		{"09:00-17:00", "mon-fry"},
+		{"09:00-17:00", "mon,"},
+	} {
+		if _, err := NewBusinessClock(tt.hours, tt.days); err == nil {
+			t.Errorf("NewBusinessClock(%q, %q) expected error", tt.hours, tt.days)
+		}
+	}
+}
+
+func TestDefaultBusinessClock(t *testing.T) {
+	c := DefaultBusinessClock()
+	if c != DefaultBusinessClock() {
+		t.Fatalf("expected one shared default clock")
+	}
+	if c.open != 9*60 || c.close != 17*60 {
+		t.Fatalf("hours = %d-%d, want 09:00-17:00", c.open, c.close)
+	}
+	want := [7]bool{false, true, true, true, true, true, false}
+	if c.days != want {
+		t.Fatalf("days = %v, want mon-fri", c.days)
+	}
+}
+
+func TestBusinessClock_Add(t *testing.T) {
+	c, err := NewBusinessClock(DefaultBusinessHours, DefaultBusinessDays)
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	c.SetHolidays([]string{"2025-06-16"})
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	// Thursday 2025-06-12
+	tests := []struct {
+		start string
+		ttl   time.Duration
+		loc   *time.Location
+		want  string
+	}{
+		{"2025-06-12T10:00:00Z", 2 * time.Hour, time.UTC, "2025-06-12T12:00:00Z"},
+		{"2025-06-12T07:00:00Z", 2 * time.Hour, time.UTC, "2025-06-12T11:00:00Z"},
+		{"2025-06-12T16:00:00Z", 2 * time.Hour, time.UTC, "2025-06-13T10:00:00Z"},
+		{"2025-06-12T10:00:00Z", 16 * time.Hour, time.UTC, "2025-06-17T10:00:00Z"},
+		{"2025-06-14T12:00:00Z", time.Hour, time.UTC, "2025-06-17T10:00:00Z"},
+		{"2025-06-12T10:00:00Z", 8 * time.Hour, time.UTC, "2025-06-13T10:00:00Z"},
+		{"2025-06-12T10:00:00Z", time.Hour, stockholm, "2025-06-12T11:00:00Z"},
+		{"2025-06-12T16:00:00Z", time.Hour, stockholm, "2025-06-13T08:00:00Z"},
+		{"2025-06-12T10:00:00Z", -time.Hour, time.UTC, "2025-06-12T09:00:00Z"},
+	}
+	for _, tt := range tests {
+		start, _ := time.Parse(time.RFC3339, tt.start)
+		if got := c.Add(start, tt.ttl, tt.loc).Format(time.RFC3339); got != tt.want {
+			t.Errorf("Add(%s, %s, %s) = %s, want %s", tt.start, tt.ttl, tt.loc, got, tt.want)
+		}
+	}
+}
+
+func TestBusinessClock_BetweenAndNextChange(t *testing.T) {
+	c, err := NewBusinessClock(DefaultBusinessHours, DefaultBusinessDays)
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	// Friday 2025-06-13 16:00 until Tuesday 10:00, with Monday off
+	from := time.Date(2025, time.June, 13, 16, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.June, 17, 10, 0, 0, 0, time.UTC)
+	if got := c.Between(from, to, time.UTC); got != 10*time.Hour {
+		t.Fatalf("Between = %s, want 10h", got)
+	}
+	if !c.SetHolidays([]string{"2025-06-16"}) || c.SetHolidays([]string{"2025-06-16"}) {
+		t.Fatalf("expected SetHolidays to report only the first change")
+	}
+	if got := c.Between(from, to, time.UTC); got != 2*time.Hour {
+		t.Fatalf("Between with a holiday = %s, want 2h", got)
+	}
+	if got := c.Add(from, c.Between(from, to, time.UTC), time.UTC); !got.Equal(to) {
+		t.Fatalf("Add(Between) = %s, want %s", got, to)
+	}
+
+	for start, want := range map[string]string{
+		"2025-06-13T08:00:00Z": "2025-06-13T09:00:00Z",
+		"2025-06-13T09:00:00Z": "2025-06-13T17:00:00Z",
+		"2025-06-13T17:00:00Z": "2025-06-17T09:00:00Z",
+	} {
+		t0, _ := time.Parse(time.RFC3339, start)
+		if got := c.NextChange(t0, time.UTC).Format(time.RFC3339); got != want {
+			t.Errorf("NextChange(%s) = %s, want %s", start, got, want)
+		}
+	}
+}
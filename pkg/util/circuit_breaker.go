@@ -0,0 +1,124 @@
+package util
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultMassExpiryMinObjects is the smallest number of tracked leases for
+// which the mass-expiry circuit breaker can open. Below it a handful of
+// expiries would always look like a large share.
+const DefaultMassExpiryMinObjects = 10
+
+// CircuitBreaker halts deletions when an unusually large share of the tracked
+// leases is due at the same time, e.g. after an outage or a clock jump.
+type CircuitBreaker struct {
+	// Threshold is the share (0-1] of tracked leases that may be due before the breaker opens
+	Threshold float64
+	// Window is how far ahead leases count as due
+	Window time.Duration
+	// MinObjects is the smallest number of tracked leases the breaker acts on
+	MinObjects int
+
+	mu      sync.Mutex
+	expires map[types.NamespacedName]time.Time
+	// handled are expiries that were acted on, they count as due until
+	// Window has passed so a mass expiry does not shrink as it is handled
+	handled map[types.NamespacedName]time.Time
+	seeded  bool
+	open    bool
+}
+
+func NewCircuitBreaker(threshold float64, window time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold:  threshold,
+		Window:     window,
+		MinObjects: DefaultMassExpiryMinObjects,
+		expires:    map[types.NamespacedName]time.Time{},
+		handled:    map[types.NamespacedName]time.Time{},
+	}
+}
+
+// Seeded reports whether the breaker was seeded with the full set of leases
+func (c *CircuitBreaker) Seeded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seeded
+}
+
+// Seed records the expiries of all leases known at startup. Leases that were
+// observed or handled already keep what was recorded for them.
+func (c *CircuitBreaker) Seed(expires map[types.NamespacedName]time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, expireAt := range expires {
+		if _, ok := c.expires[key]; ok {
+			continue
+		}
+		if _, ok := c.handled[key]; ok {
+			continue
+		}
+		c.expires[key] = expireAt
+	}
+	c.seeded = true
+}
+
+// Observe records the current expiry of a tracked lease. A lease that was
+// handled is tracked again once it expires after it was handled.
+func (c *CircuitBreaker) Observe(key types.NamespacedName, expireAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at, ok := c.handled[key]; ok {
+		if !expireAt.After(at) {
+			return
+		}
+		delete(c.handled, key)
+	}
+	c.expires[key] = expireAt
+}
+
+// Handled records that the expiry of a lease was acted on at the given time.
+// It keeps counting as due until Window has passed.
+func (c *CircuitBreaker) Handled(key types.NamespacedName, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, key)
+	if _, ok := c.handled[key]; !ok {
+		c.handled[key] = at
+	}
+}
+
+// Forget stops tracking a lease that was removed. Handled expiries are kept
+// until Window has passed.
+func (c *CircuitBreaker) Forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, key)
+}
+
+// Evaluate counts the leases due by now+Window, and the expiries handled
+// within the last Window, and opens or closes the breaker. changed is true
+// when the breaker flipped on this call.
+func (c *CircuitBreaker) Evaluate(now time.Time) (open bool, due, total int, changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, at := range c.handled {
+		if !at.Add(c.Window).After(now) {
+			delete(c.handled, key)
+		}
+	}
+	due = len(c.handled)
+	total = len(c.expires) + len(c.handled)
+	horizon := now.Add(c.Window)
+	for _, t := range c.expires {
+		if !t.After(horizon) {
+			due++
+		}
+	}
+	open = total >= c.MinObjects && float64(due) > c.Threshold*float64(total)
+	changed = open != c.open
+	c.open = open
+	return open, due, total, changed
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCircuitBreaker_OpensAndCloses(t *testing.T) {
+	now := time.Now()
+	c := NewCircuitBreaker(0.5, 10*time.Minute)
+
+	for i := 0; i < 10; i++ {
+		c.Observe(types.NamespacedName{Namespace: "ns", Name: fmt.Sprint(i)}, now.Add(24*time.Hour))
+	}
+	if open, _, _, _ := c.Evaluate(now); open {
+		t.Fatalf("breaker should be closed with nothing due")
+	}
+
+	// A clock jump makes 6 of 10 leases due at once
+	for i := 0; i < 6; i++ {
+		c.Observe(types.NamespacedName{Namespace: "ns", Name: fmt.Sprint(i)}, now.Add(-time.Hour))
+	}
+	open, due, total, changed := c.Evaluate(now)
+	if !open || !changed || due != 6 || total != 10 {
+		t.Fatalf("Evaluate = (%v, %d, %d, %v), want (true, 6, 10, true)", open, due, total, changed)
+	}
+	if _, _, _, changed := c.Evaluate(now); changed {
+		t.Fatalf("second evaluation should not report a change")
+	}
+
+	// Owners renew two leases, back under the threshold
+	c.Observe(types.NamespacedName{Namespace: "ns", Name: "0"}, now.Add(time.Hour))
+	c.Forget(types.NamespacedName{Namespace: "ns", Name: "1"})
+	if open, _, _, changed := c.Evaluate(now); open || !changed {
+		t.Fatalf("expected breaker to close, got open=%v changed=%v", open, changed)
+	}
+}
+
+func TestCircuitBreaker_IgnoresSmallSets(t *testing.T) {
+	now := time.Now()
+	c := NewCircuitBreaker(0.1, time.Minute)
+	for i := 0; i < DefaultMassExpiryMinObjects-1; i++ {
+		c.Observe(types.NamespacedName{Namespace: "ns", Name: fmt.Sprint(i)}, now.Add(-time.Minute))
+	}
+	if open, _, _, _ := c.Evaluate(now); open {
+		t.Fatalf("breaker should not open below MinObjects")
+	}
+}
+
+func TestCircuitBreaker_HandledExpiriesCountUntilWindowPasses(t *testing.T) {
+	now := time.Now()
+	c := NewCircuitBreaker(0.5, 10*time.Minute)
+	expires := map[types.NamespacedName]time.Time{}
+	for i := 0; i < 10; i++ {
+		expires[types.NamespacedName{Namespace: "ns", Name: fmt.Sprint(i)}] = now.Add(24 * time.Hour)
+	}
+	c.Seed(expires)
+	if !c.Seeded() {
+		t.Fatalf("expected the breaker to be seeded")
+	}
+
+	// Six leases are handled one after the other, each looks small on its own
+	for i := 0; i < 6; i++ {
+		key := types.NamespacedName{Namespace: "ns", Name: fmt.Sprint(i)}
+		c.Observe(key, now.Add(-time.Minute))
+		c.Handled(key, now)
+		// A deleted object is forgotten, its handled expiry still counts
+		c.Forget(key)
+	}
+	open, due, total, _ := c.Evaluate(now)
+	if !open || due != 6 || total != 10 {
+		t.Fatalf("Evaluate = (%v, %d, %d), want (true, 6, 10)", open, due, total)
+	}
+
+	// A late seed does not bring back handled leases
+	c.Seed(expires)
+	if _, due, _, _ := c.Evaluate(now); due != 6 {
+		t.Fatalf("due = %d after a second seed, want 6", due)
+	}
+
+	// Once the window has passed the handled expiries no longer count
+	if open, due, total, _ := c.Evaluate(now.Add(11 * time.Minute)); open || due != 0 || total != 4 {
+		t.Fatalf("Evaluate = (%v, %d, %d), want (false, 0, 4)", open, due, total)
+	}
+}
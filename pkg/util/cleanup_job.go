@@ -38,6 +38,8 @@ type CleanupJobConfig struct {
 	TTLSecondsAfterFinished int32
 	BackoffLimit            int32
 	EnvFromSecrets          []string // List of secret names to mount as environment variables
+	// Namespace the Job runs in. Empty runs it in the object's namespace.
+	Namespace string
 }
 
 // ParseCleanupJobConfig extracts cleanup job configuration from object annotations
@@ -157,11 +159,16 @@ func CreateCleanupJob(
 		{Name: "OBJECT_ANNOTATIONS", Value: string(annotations)},
 	}
 
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+
 	// Create Job spec
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("lease-cleanup-%s-", obj.GetName()),
-			Namespace:    obj.GetNamespace(),
+			Namespace:    namespace,
 			Labels: map[string]string{
 				"object-lease-controller.ullberg.io/source-kind": gvk.Kind,
 				"object-lease-controller.ullberg.io/source-name": obj.GetName(),
@@ -241,19 +248,27 @@ func WaitForJobCompletion(ctx context.Context, c client.Client, job *batchv1.Job
 				return fmt.Errorf("failed to get job status: %w", err)
 			}
 
-			// Check if job completed
-			for _, condition := range currentJob.Status.Conditions {
-				if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
-					return nil // Job completed successfully
-				}
-				if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
-					return fmt.Errorf("job failed: %s", condition.Message)
-				}
+			if done, err := JobFinished(currentJob); done {
+				return err
 			}
 		}
 	}
 }
 
+// JobFinished reports whether a Job has finished. A failed Job is finished
+// with an error.
+func JobFinished(job *batchv1.Job) (bool, error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
+			return true, nil
+		}
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true, fmt.Errorf("job failed: %s", condition.Message)
+		}
+	}
+	return false, nil
+}
+
 // Helper function to create int32 pointer
 func int32Ptr(i int32) *int32 {
 	return &i
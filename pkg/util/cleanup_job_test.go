@@ -347,6 +347,35 @@ func (c *failCreateClient) Create(ctx context.Context, obj client.Object, opts .
 	return fmt.Errorf("create error")
 }
 
+func TestCreateCleanupJob_InConfiguredNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = batchv1.AddToScheme(scheme)
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// Namespaces are cluster-scoped, their jobs run elsewhere
+	obj := &unstructured.Unstructured{}
+	obj.SetName("team-a")
+
+	gvk := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"}
+	cfg := &CleanupJobConfig{
+		ConfigMapName:           "cm",
+		ScriptKey:               "script",
+		ServiceAccount:          DefaultServiceAccount,
+		Image:                   DefaultJobImage,
+		TTLSecondsAfterFinished: DefaultJobTTL,
+		BackoffLimit:            DefaultJobBackoffLimit,
+		Namespace:               "lease-ops",
+	}
+
+	job, err := CreateCleanupJob(context.Background(), cl, obj, gvk, cfg, time.Now(), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to create cleanup job: %v", err)
+	}
+	if job.Namespace != "lease-ops" {
+		t.Errorf("Expected namespace 'lease-ops', got %s", job.Namespace)
+	}
+}
+
 func TestCreateCleanupJob_FailsOnClientCreate(t *testing.T) {
 	scheme := runtime.NewScheme()
 	_ = batchv1.AddToScheme(scheme)
@@ -510,6 +539,27 @@ func TestWaitForJobCompletion_Failed(t *testing.T) {
 	}
 }
 
+func TestJobFinished(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []batchv1.JobCondition
+		wantDone   bool
+		wantErr    bool
+	}{
+		{"running", nil, false, false},
+		{"complete", []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}, true, false},
+		{"failed", []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "boom"}}, true, true},
+		{"not yet complete", []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionFalse}}, false, false},
+	}
+	for _, tt := range tests {
+		job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: tt.conditions}}
+		done, err := JobFinished(job)
+		if done != tt.wantDone || (err != nil) != tt.wantErr {
+			t.Errorf("%s: JobFinished = (%v, %v), want (%v, err=%v)", tt.name, done, err, tt.wantDone, tt.wantErr)
+		}
+	}
+}
+
 // failGetClient returns error from Get()
 type failGetClient struct{ client.Client }
 
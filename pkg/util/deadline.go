@@ -0,0 +1,223 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+	// clockTime matches 18:00, 18, 6pm and 6:30pm
+	clockTime = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+)
+
+// deadlinePhrases resolve fixed phrases against the start of a lease
+var deadlinePhrases = map[string]func(clock *BusinessClock, from time.Time) time.Time{
+	"end of business day": endOfBusinessDay,
+	"end of day":          endOfDay,
+	"end of week":         endOfWeek,
+	"end of month":        endOfMonth,
+	"close of business":   endOfBusinessDay,
+	"eod":                 endOfDay,
+	"cob":                 endOfBusinessDay,
+}
+
+// IsDeadlineExpression reports whether val is a deadline expression rather
+// than a duration. Expressions starting with "until" always are, so typos in
+// them are reported as such.
+func IsDeadlineExpression(val string) bool {
+	lower := strings.ToLower(strings.TrimSpace(val))
+	if lower == "until" || strings.HasPrefix(lower, "until ") {
+		return true
+	}
+	_, err := ParseDeadline(val, time.UTC, nil)
+	return err == nil
+}
+
+// ParseDeadline parses a deadline expression into the function that resolves
+// it against the start of a lease. Expressions name a day, a time or both,
+// optionally after "until" and followed by an IANA timezone:
+//
+//	until friday 18:00 Europe/Stockholm
+//	tomorrow 9am
+//	17:30
+//	2026-12-24
+//	end of business day
+//
+// A day without a time means the end of that day, a time without a day its
+// next occurrence. Times are read in loc unless the expression names a zone.
+// The end of the business day and of the week follow the business hours, days
+// and holidays of business, or the defaults when it is nil.
+func ParseDeadline(expr string, loc *time.Location, business *BusinessClock) (func(from time.Time) time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if business == nil {
+		business = DefaultBusinessClock()
+	}
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "until") {
+		fields = fields[1:]
+	}
+	if n := len(fields); n > 1 && (strings.Contains(fields[n-1], "/") || strings.EqualFold(fields[n-1], "UTC")) {
+		name := fields[n-1]
+		if strings.EqualFold(name, "UTC") {
+			name = "UTC"
+		}
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q in deadline %q", fields[n-1], expr)
+		}
+		loc, fields = l, fields[:n-1]
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty deadline %q", expr)
+	}
+	for i := range fields {
+		fields[i] = strings.ToLower(fields[i])
+	}
+	inLoc := func(resolve func(time.Time) time.Time) func(time.Time) time.Time {
+		return func(from time.Time) time.Time {
+			return resolve(from.In(loc)).UTC()
+		}
+	}
+
+	if phrase, ok := deadlinePhrases[strings.Join(fields, " ")]; ok {
+		return inLoc(func(from time.Time) time.Time { return phrase(business, from) }), nil
+	}
+
+	// A day, a time, or a day and a time with an optional "at" between them
+	day, clock := fields[0], ""
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 2:
+		clock = fields[1]
+	case len(fields) == 3 && fields[1] == "at":
+		clock = fields[2]
+	default:
+		return nil, fmt.Errorf("invalid deadline %q", expr)
+	}
+	if clockTime.MatchString(day) && clock == "" {
+		day, clock = "", day
+	}
+
+	hour, minute, hasClock := 0, 0, clock != ""
+	if hasClock {
+		var err error
+		if hour, minute, err = parseClock(clock); err != nil {
+			return nil, fmt.Errorf("invalid time in deadline %q: %w", expr, err)
+		}
+	}
+	at := func(d time.Time, dayOffset int) time.Time {
+		return time.Date(d.Year(), d.Month(), d.Day()+dayOffset, hour, minute, 0, 0, d.Location())
+	}
+
+	switch wd, isWeekday := weekdays[day]; {
+	case day == "":
+		// The next time the clock shows the time
+		return inLoc(func(from time.Time) time.Time {
+			if t := at(from, 0); t.After(from) {
+				return t
+			}
+			return at(from, 1)
+		}), nil
+	case day == "today" || day == "tomorrow":
+		offset := 0
+		if day == "tomorrow" {
+			offset = 1
+		}
+		if !hasClock {
+			offset++
+		}
+		return inLoc(func(from time.Time) time.Time { return at(from, offset) }), nil
+	case isWeekday:
+		return inLoc(func(from time.Time) time.Time {
+			days := (int(wd) - int(from.Weekday()) + 7) % 7
+			if !hasClock {
+				// The end of that day
+				return at(from, days+1)
+			}
+			if t := at(from, days); t.After(from) {
+				return t
+			}
+			return at(from, days+7)
+		}), nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", day, loc)
+	if err != nil {
+		return nil, fmt.Errorf("unknown day %q in deadline %q", day, expr)
+	}
+	if !hasClock {
+		date = date.AddDate(0, 0, 1)
+	}
+	deadline := at(date, 0).UTC()
+	return func(time.Time) time.Time { return deadline }, nil
+}
+
+// parseClock parses a time of day like 18:00, 18, 6pm or 6:30pm, or noon
+func parseClock(s string) (int, int, error) {
+	if s == "noon" {
+		return 12, 0, nil
+	}
+	m := clockTime.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, fmt.Errorf("invalid time %q", s)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch m[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("invalid time %q", s)
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	case "":
+		if m[2] == "" {
+			// A bare number is only a time with am or pm
+			return 0, 0, fmt.Errorf("invalid time %q", s)
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour, minute, nil
+}
+
+// endOfDay is midnight at the end of the day of from
+func endOfDay(_ *BusinessClock, from time.Time) time.Time {
+	return time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, from.Location())
+}
+
+// endOfBusinessDay is when business hours close on the current business day,
+// or on the next one when they are over
+func endOfBusinessDay(clock *BusinessClock, from time.Time) time.Time {
+	return clock.EndOfBusinessDay(from, from.Location())
+}
+
+// endOfWeek is when business hours close on the last business day of the week
+func endOfWeek(clock *BusinessClock, from time.Time) time.Time {
+	return clock.EndOfWeek(from, from.Location())
+}
+
+// endOfMonth is midnight at the start of the next month
+func endOfMonth(_ *BusinessClock, from time.Time) time.Time {
+	return time.Date(from.Year(), from.Month()+1, 1, 0, 0, 0, 0, from.Location())
+}
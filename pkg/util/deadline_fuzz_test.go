@@ -0,0 +1,32 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// FuzzParseDeadline checks that deadline expressions never panic and resolve
+// to a time after the lease start, unless they name a date
+func FuzzParseDeadline(f *testing.F) {
+	for _, s := range []string{"until friday 18:00 Europe/Stockholm", "end of business day", "tomorrow 9am", "17:30", "2025-12-24", "sat at noon", "until", "fri 99:99", "end of week UTC"} {
+		f.Add(s)
+	}
+
+	from := time.Date(2025, time.June, 11, 8, 0, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, in string) {
+		deadline, err := ParseDeadline(in, time.UTC, nil)
+		if err != nil {
+			return
+		}
+		if got := deadline(from); !got.After(from) && !hasDate(in) {
+			t.Fatalf("ParseDeadline(%q) = %s, not after %s", in, got, from)
+		}
+	})
+}
+
+// hasDate reports whether the expression names a date or today, which may
+// lie before the lease start
+func hasDate(in string) bool {
+	return strings.Contains(in, "-") || strings.Contains(strings.ToLower(in), "today")
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeadline(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	// Wednesday 2025-06-11 10:00 in Stockholm (UTC+2)
+	from := time.Date(2025, time.June, 11, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"until friday 18:00 Europe/Stockholm", time.UTC, time.Date(2025, time.June, 13, 16, 0, 0, 0, time.UTC)},
+		{"friday at 6pm", stockholm, time.Date(2025, time.June, 13, 16, 0, 0, 0, time.UTC)},
+		{"Friday", time.UTC, time.Date(2025, time.June, 14, 0, 0, 0, 0, time.UTC)},
+		{"wed 09:00", time.UTC, time.Date(2025, time.June, 11, 9, 0, 0, 0, time.UTC)},
+		{"wed 07:00", time.UTC, time.Date(2025, time.June, 18, 7, 0, 0, 0, time.UTC)},
+		{"wed 09:00", stockholm, time.Date(2025, time.June, 18, 7, 0, 0, 0, time.UTC)},
+		{"wed 11:00", stockholm, time.Date(2025, time.June, 11, 9, 0, 0, 0, time.UTC)},
+		{"17:30", time.UTC, time.Date(2025, time.June, 11, 17, 30, 0, 0, time.UTC)},
+		{"7am", time.UTC, time.Date(2025, time.June, 12, 7, 0, 0, 0, time.UTC)},
+		{"12am", time.UTC, time.Date(2025, time.June, 12, 0, 0, 0, 0, time.UTC)},
+		{"tomorrow noon", time.UTC, time.Date(2025, time.June, 12, 12, 0, 0, 0, time.UTC)},
+		{"today", time.UTC, time.Date(2025, time.June, 12, 0, 0, 0, 0, time.UTC)},
+		{"tomorrow", time.UTC, time.Date(2025, time.June, 13, 0, 0, 0, 0, time.UTC)},
+		{"end of business day", stockholm, time.Date(2025, time.June, 11, 15, 0, 0, 0, time.UTC)},
+		{"until end of day UTC", stockholm, time.Date(2025, time.June, 12, 0, 0, 0, 0, time.UTC)},
+		{"end of week", time.UTC, time.Date(2025, time.June, 13, 17, 0, 0, 0, time.UTC)},
+		{"end of month", time.UTC, time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-12-24", stockholm, time.Date(2025, time.December, 24, 23, 0, 0, 0, time.UTC)},
+		{"2025-12-24 15:00", time.UTC, time.Date(2025, time.December, 24, 15, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		deadline, err := ParseDeadline(tt.expr, tt.loc, nil)
+		if err != nil {
+			t.Errorf("ParseDeadline(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if got := deadline(from); !got.Equal(tt.want) {
+			t.Errorf("ParseDeadline(%q, %s) = %s, want %s", tt.expr, tt.loc, got, tt.want)
+		}
+	}
+}
+
+func TestParseDeadline_BusinessDayRollsOver(t *testing.T) {
+	deadline, err := ParseDeadline("end of business day", time.UTC, nil)
+	if err != nil {
+		t.Fatalf("ParseDeadline error: %v", err)
+	}
+	// Friday evening rolls over to Monday
+	from := time.Date(2025, time.June, 13, 18, 0, 0, 0, time.UTC)
+	if got, want := deadline(from), time.Date(2025, time.June, 16, 17, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("deadline = %s, want %s", got, want)
+	}
+}
+
+func TestParseDeadline_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "until", "fryday", "until fryday 18:00", "friday 25:00", "friday 13pm", "friday 18", "monday tuesday wednesday", "friday 18:00 Mars/Olympus", "2025-13-01", "1h"} {
+		if _, err := ParseDeadline(expr, time.UTC, nil); err == nil {
+			t.Errorf("ParseDeadline(%q) expected error", expr)
+		}
+	}
+}
+
+func TestIsDeadlineExpression(t *testing.T) {
+	for val, want := range map[string]bool{
+		"until friday 18:00": true,
+		"until fryday":       true,
+		"end of day":         true,
+		"tomorrow 9am":       true,
+		"1h":                 false,
+		"P1D":                false,
+		"soon":               false,
+	} {
+		if got := IsDeadlineExpression(val); got != want {
+			t.Errorf("IsDeadlineExpression(%q) = %v, want %v", val, got, want)
+		}
+	}
+}
+
+func TestParseDeadline_FollowsTheBusinessClock(t *testing.T) {
+	clock, err := NewBusinessClock("08:00-16:00", "sun-thu")
+	if err != nil {
+		t.Fatalf("NewBusinessClock error: %v", err)
+	}
+	clock.SetHolidays([]string{"2025-06-12"})
+	// Wednesday 2025-06-11 10:00
+	from := time.Date(2025, time.June, 11, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"end of business day", from, time.Date(2025, time.June, 11, 16, 0, 0, 0, time.UTC)},
+		// Thursday is a holiday and Friday and Saturday are days off
+		{"cob", from.Add(8 * time.Hour), time.Date(2025, time.June, 15, 16, 0, 0, 0, time.UTC)},
+		// The week ends on Thursday, a holiday, so on Wednesday
+		{"end of week", from, time.Date(2025, time.June, 11, 16, 0, 0, 0, time.UTC)},
+		{"end of week", from.Add(8 * time.Hour), time.Date(2025, time.June, 19, 16, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		deadline, err := ParseDeadline(tt.expr, time.UTC, clock)
+		if err != nil {
+			t.Errorf("ParseDeadline(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if got := deadline(tt.from); !got.Equal(tt.want) {
+			t.Errorf("ParseDeadline(%q) from %s = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
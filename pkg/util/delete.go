@@ -7,10 +7,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func DeleteWithUIDPrecondition(ctx context.Context, c client.Client, obj client.Object) error {
+// DeleteWithUIDPrecondition deletes obj only if it still has the same UID.
+// An empty propagation uses the API server default.
+func DeleteWithUIDPrecondition(ctx context.Context, c client.Client, obj client.Object, propagation ...metav1.DeletionPropagation) error {
 	uid := obj.GetUID()
 	opts := &client.DeleteOptions{
 		Preconditions: &metav1.Preconditions{UID: &uid},
 	}
+	if len(propagation) > 0 && propagation[0] != "" {
+		opts.PropagationPolicy = &propagation[0]
+	}
 	return c.Delete(ctx, obj, opts)
 }
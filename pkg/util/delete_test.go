@@ -112,3 +112,22 @@ func TestDeleteWithUIDPrecondition_PropagatesError(t *testing.T) {
 		t.Fatalf("expected UID precondition to be set even on error")
 	}
 }
+
+func TestDeleteWithUIDPrecondition_SetsPropagation(t *testing.T) {
+	t.Parallel()
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "abc"}}
+	base := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(cm.DeepCopy()).Build()
+	cc := &captureClient{Client: base}
+
+	if err := DeleteWithUIDPrecondition(context.Background(), cc, cm, metav1.DeletePropagationOrphan); err != nil {
+		t.Fatalf("DeleteWithUIDPrecondition error: %v", err)
+	}
+	var applied client.DeleteOptions
+	for _, o := range cc.capturedOpts {
+		o.ApplyToDelete(&applied)
+	}
+	if applied.PropagationPolicy == nil || *applied.PropagationPolicy != metav1.DeletePropagationOrphan {
+		t.Fatalf("expected Orphan propagation, got %v", applied.PropagationPolicy)
+	}
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DeletionBudget is a token bucket that limits how fast expired objects are
+// acted on, both for the whole GVK and for each namespace. A zero rate
+// leaves that scope unlimited.
+type DeletionBudget struct {
+	mu         sync.Mutex
+	gvk        *rate.Limiter
+	nsPerMin   int
+	namespaces map[string]*rate.Limiter
+}
+
+// NewDeletionBudget creates a budget allowing perMinute deletions for the GVK
+// and nsPerMinute deletions per namespace. Each bucket can burst up to one
+// minute's worth of deletions.
+func NewDeletionBudget(perMinute, nsPerMinute int) *DeletionBudget {
+	b := &DeletionBudget{nsPerMin: nsPerMinute, namespaces: map[string]*rate.Limiter{}}
+	if perMinute > 0 {
+		b.gvk = newPerMinuteLimiter(perMinute)
+	}
+	return b
+}
+
+func newPerMinuteLimiter(perMinute int) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute)
+}
+
+// Take spends one token from the GVK and namespace buckets. When either is
+// empty nothing is spent, and the returned wait says when to try again.
+func (b *DeletionBudget) Take(namespace string, now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	limiters := make([]*rate.Limiter, 0, 2)
+	if b.gvk != nil {
+		limiters = append(limiters, b.gvk)
+	}
+	if b.nsPerMin > 0 {
+		b.evictFull(now)
+		l, ok := b.namespaces[namespace]
+		if !ok {
+			l = newPerMinuteLimiter(b.nsPerMin)
+			b.namespaces[namespace] = l
+		}
+		limiters = append(limiters, l)
+	}
+
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	var wait time.Duration
+	for _, l := range limiters {
+		r := l.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		// Hand the tokens back, the caller retries after wait
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+		return false, wait
+	}
+	return true, 0
+}
+
+// evictFull drops namespace buckets that have refilled, a fresh bucket
+// behaves the same and the map would otherwise grow with every namespace
+func (b *DeletionBudget) evictFull(now time.Time) {
+	for ns, l := range b.namespaces {
+		if l.TokensAt(now) >= float64(l.Burst()) {
+			delete(b.namespaces, ns)
+		}
+	}
+}
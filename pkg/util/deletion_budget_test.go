@@ -0,0 +1,92 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeletionBudget_GVKLimit(t *testing.T) {
+	b := NewDeletionBudget(2, 0)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := b.Take("ns", now); !ok {
+			t.Fatalf("take %d should be within the burst", i)
+		}
+	}
+	ok, wait := b.Take("other", now)
+	if ok {
+		t.Fatalf("expected the GVK budget to be spent")
+	}
+	if wait <= 0 || wait > 30*time.Second {
+		t.Fatalf("wait = %v, want up to 30s for 2/min", wait)
+	}
+	if ok, _ := b.Take("other", now.Add(wait)); !ok {
+		t.Fatalf("expected a token after waiting %v", wait)
+	}
+}
+
+func TestDeletionBudget_NamespaceLimit(t *testing.T) {
+	b := NewDeletionBudget(0, 1)
+	now := time.Now()
+
+	if ok, _ := b.Take("a", now); !ok {
+		t.Fatalf("first take in a should succeed")
+	}
+	if ok, _ := b.Take("a", now); ok {
+		t.Fatalf("second take in a should be limited")
+	}
+	if ok, _ := b.Take("b", now); !ok {
+		t.Fatalf("namespace b has its own budget")
+	}
+}
+
+func TestDeletionBudget_EvictsRefilledNamespaces(t *testing.T) {
+	b := NewDeletionBudget(0, 1)
+	now := time.Now()
+
+	if ok, _ := b.Take("a", now); !ok {
+		t.Fatalf("first take in a should succeed")
+	}
+	if ok, _ := b.Take("b", now); !ok {
+		t.Fatalf("first take in b should succeed")
+	}
+	if len(b.namespaces) != 2 {
+		t.Fatalf("expected 2 namespace buckets, got %d", len(b.namespaces))
+	}
+	if ok, _ := b.Take("c", now.Add(time.Minute)); !ok {
+		t.Fatalf("first take in c should succeed")
+	}
+	if _, ok := b.namespaces["a"]; ok {
+		t.Fatalf("refilled bucket for a should have been evicted")
+	}
+	if len(b.namespaces) != 1 {
+		t.Fatalf("expected only c to be tracked, got %d buckets", len(b.namespaces))
+	}
+}
+
+func TestDeletionBudget_LimitedTakeSpendsNothing(t *testing.T) {
+	b := NewDeletionBudget(1, 1)
+	now := time.Now()
+
+	if ok, _ := b.Take("a", now); !ok {
+		t.Fatalf("first take should succeed")
+	}
+	// GVK budget is empty, namespace b must keep its token
+	if ok, _ := b.Take("b", now); ok {
+		t.Fatalf("expected the GVK budget to be spent")
+	}
+	later := now.Add(time.Minute)
+	if ok, _ := b.Take("b", later); !ok {
+		t.Fatalf("namespace b token should not have been spent by the limited take")
+	}
+}
+
+func TestDeletionBudget_Unlimited(t *testing.T) {
+	b := NewDeletionBudget(0, 0)
+	for i := 0; i < 100; i++ {
+		if ok, _ := b.Take("ns", time.Now()); !ok {
+			t.Fatalf("unlimited budget should never limit")
+		}
+	}
+}
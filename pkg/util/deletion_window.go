@@ -0,0 +1,159 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// windowSearchLimit bounds how far ahead the next deletion window is searched
+const windowSearchLimit = 8 * 366 * 24 * time.Hour
+
+var (
+	cronMonths = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronWeekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// DeletionWindow is a cron-like schedule of the minutes expiry actions may
+// run in. The five fields are minute, hour, day of month, month and day of
+// week, so "* 2-4 * * mon-fri" is open on weekdays from 02:00 until 05:00.
+// Like cron, a day matches either restricted day field when both are set.
+type DeletionWindow struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// ParseDeletionWindow parses a deletion window schedule
+func ParseDeletionWindow(spec string) (*DeletionWindow, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid deletion window %q: expected 5 fields, minute hour day-of-month month day-of-week", spec)
+	}
+	w := &DeletionWindow{}
+	var err error
+	if w.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid deletion window %q: minute: %w", spec, err)
+	}
+	if w.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid deletion window %q: hour: %w", spec, err)
+	}
+	if w.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid deletion window %q: day of month: %w", spec, err)
+	}
+	if w.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("invalid deletion window %q: month: %w", spec, err)
+	}
+	if w.dow, err = parseCronField(fields[4], 0, 7, cronWeekdays); err != nil {
+		return nil, fmt.Errorf("invalid deletion window %q: day of week: %w", spec, err)
+	}
+	// 7 is Sunday as well
+	if w.dow&(1<<7) != 0 {
+		w.dow |= 1
+	}
+	w.domAny = strings.HasPrefix(fields[2], "*")
+	w.dowAny = strings.HasPrefix(fields[4], "*")
+
+	if _, ok := w.Next(time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), time.UTC); !ok {
+		return nil, fmt.Errorf("invalid deletion window %q: it never opens", spec)
+	}
+	return w, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+// like "*", "*/15", "1-5", "mon-fri" or "0-30/10" into a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	value := func(s string) (int, error) {
+		if n, ok := names[strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is not between %d and %d", s, min, max)
+		}
+		return n, nil
+	}
+
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		first, last := min, max
+		switch from, to, isRange := strings.Cut(rng, "-"); {
+		case rng == "*":
+		case isRange:
+			var err error
+			if first, err = value(from); err != nil {
+				return 0, err
+			}
+			if last, err = value(to); err != nil {
+				return 0, err
+			}
+			if first > last {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			var err error
+			if first, err = value(rng); err != nil {
+				return 0, err
+			}
+			// A single value with a step runs to the end, like cron
+			if !hasStep {
+				last = first
+			}
+		}
+		for n := first; n <= last; n += step {
+			bits |= 1 << n
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches reports whether the day of t is in the window
+func (w *DeletionWindow) dayMatches(t time.Time) bool {
+	dom := w.dom&(1<<t.Day()) != 0
+	dow := w.dow&(1<<t.Weekday()) != 0
+	if w.domAny || w.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns t when the window is open at t, otherwise when it opens next,
+// read in loc. ok is false when it does not open within eight years.
+func (w *DeletionWindow) Next(t time.Time, loc *time.Location) (time.Time, bool) {
+	t = t.In(loc)
+	limit := t.Add(windowSearchLimit)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		var next time.Time
+		switch {
+		case w.month&(1<<m) == 0:
+			next = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !w.dayMatches(t):
+			next = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case w.hour&(1<<t.Hour()) == 0:
+			next = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case w.minute&(1<<t.Minute()) == 0:
+			next = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t.UTC(), true
+		}
+		// Daylight saving changes can map a jump onto the past
+		if !next.After(t) {
+			next = t.Truncate(time.Minute).Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// FuzzDeletionWindow checks that schedules never panic and that the window
+// is open at the time Next returns
+func FuzzDeletionWindow(f *testing.F) {
+	for _, s := range []string{"* 2-4 * * mon-fri", "*/15 22 * * sun,7", "0 0 1 jan-mar *", "0 12 15 * fri", "0 12 29 2 *", "1-59/7 */5 1,15 * *"} {
+		f.Add(s, int64(1749722400))
+	}
+
+	f.Fuzz(func(t *testing.T, spec string, unix int64) {
+		w, err := ParseDeletionWindow(spec)
+		if err != nil {
+			return
+		}
+		from := time.Unix(unix%(1<<40), 0).UTC()
+		opens, ok := w.Next(from, time.UTC)
+		if !ok {
+			return
+		}
+		if opens.Before(from) {
+			t.Fatalf("%q.Next(%s) = %s, before the start", spec, from, opens)
+		}
+		if again, _ := w.Next(opens, time.UTC); !again.Equal(opens) {
+			t.Fatalf("%q is not open at %s, Next returns %s", spec, opens, again)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeletionWindow_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* 2-4 * *",
+		"* 2-4 * * mon-fri extra",
+		"60 * * * *",
+		"* 24 * * *",
+		"* 5-2 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"* * * * funday",
+		"* * 31 feb *",
+		"1,,2 * * * *",
+	} {
+		if _, err := ParseDeletionWindow(spec); err == nil {
+			t.Errorf("ParseDeletionWindow(%q) expected error", spec)
+		}
+	}
+}
+
+func TestDeletionWindow_Next(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	tests := []struct {
+		spec string
+		from string
+		loc  *time.Location
+		want string
+	}{
+		// Thursday 2025-06-12
+		{"* 2-4 * * mon-fri", "2025-06-12T03:30:15Z", time.UTC, "2025-06-12T03:30:15Z"},
+		{"* 2-4 * * mon-fri", "2025-06-12T05:00:00Z", time.UTC, "2025-06-13T02:00:00Z"},
+		{"* 2-4 * * mon-fri", "2025-06-13T05:00:00Z", time.UTC, "2025-06-16T02:00:00Z"},
+		{"* 2-4 * * 1-5", "2025-06-12T01:59:59Z", time.UTC, "2025-06-12T02:00:00Z"},
+		{"* 2-4 * * mon-fri", "2025-06-12T05:00:00Z", stockholm, "2025-06-13T00:00:00Z"},
+		{"*/15 22 * * sun,7", "2025-06-12T00:00:00Z", time.UTC, "2025-06-15T22:00:00Z"},
+		{"30 1 * * *", "2025-06-12T01:31:00Z", time.UTC, "2025-06-13T01:30:00Z"},
+		{"0 0 1 jan-mar *", "2025-06-12T00:00:00Z", time.UTC, "2026-01-01T00:00:00Z"},
+		// Either day field matches when both are restricted
+		{"0 12 15 * fri", "2025-06-12T00:00:00Z", time.UTC, "2025-06-13T12:00:00Z"},
+		{"0 12 29 2 *", "2025-03-01T00:00:00Z", time.UTC, "2028-02-29T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		w, err := ParseDeletionWindow(tt.spec)
+		if err != nil {
+			t.Errorf("ParseDeletionWindow(%q) error: %v", tt.spec, err)
+			continue
+		}
+		from, _ := time.Parse(time.RFC3339, tt.from)
+		got, ok := w.Next(from, tt.loc)
+		if !ok || got.Format(time.RFC3339) != tt.want {
+			t.Errorf("%q.Next(%s, %s) = (%s, %v), want %s", tt.spec, tt.from, tt.loc, got.Format(time.RFC3339), ok, tt.want)
+		}
+	}
+}
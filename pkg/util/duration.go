@@ -2,19 +2,99 @@ package util
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// ParseFlexibleDuration parses flexible TTL like "4h", "2d", "1w"
+// ParseFlexibleDuration parses flexible TTL like "4h", "2d", "1w", or ISO
+// 8601 durations like "P1DT2H". Months count as 30 days and years as 365
+// days.
 func ParseFlexibleDuration(val string) (time.Duration, error) {
+	elems, neg, err := parseDurationElements(val)
+	if err != nil {
+		return 0, err
+	}
+	var sumDur time.Duration
+	for _, e := range elems {
+		sumDur += e.dur
+	}
+
+	if neg {
+		sumDur = -sumDur
+	}
+
+	return sumDur, nil
+}
+
+// LeaseExpiry parses a flexible TTL into the function that computes when a
+// lease starting at a given time expires. In calendar mode whole months and
+// years are calendar months and years, so one month from January 31 is the
+// last day of February. Fractions of them count as 30 and 365 days.
+func LeaseExpiry(val string, calendar bool) (func(start time.Time) time.Time, error) {
+	elems, neg, err := parseDurationElements(val)
+	if err != nil {
+		return nil, err
+	}
+	var fixed time.Duration
+	months := 0
+	for _, e := range elems {
+		if !calendar || e.months == 0 {
+			fixed += e.dur
+			continue
+		}
+		whole := math.Trunc(e.num)
+		months += int(whole) * e.months
+		fixed += time.Duration(float64(e.unit) * (e.num - whole))
+	}
+	if neg {
+		months, fixed = -months, -fixed
+	}
+	return func(start time.Time) time.Time {
+		return AddMonths(start, months).Add(fixed)
+	}, nil
+}
+
+// AddMonths adds n calendar months to t. A day past the end of the target
+// month is clamped to its last day instead of rolling over like AddDate.
+func AddMonths(t time.Time, n int) time.Time {
+	if n == 0 {
+		return t
+	}
+	y, m, d := t.Date()
+	first := time.Date(y, m, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, n, 0)
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+// durationElement is one number and unit of a flexible duration
+type durationElement struct {
+	// dur is the length of the element, with months of 30 and years of 365 days
+	dur  time.Duration
+	num  float64
+	unit time.Duration
+	// months is the number of calendar months in one unit, 0 for units of a
+	// fixed length
+	months int
+}
+
+// parseDurationElements splits a flexible duration into its elements and
+// reports whether it is negative
+func parseDurationElements(val string) ([]durationElement, bool, error) {
 	neg := false
 	if len(val) > 0 && val[0] == '-' {
 		neg = true
 		val = val[1:]
 	}
+	if IsISODuration(val) {
+		elems, err := parseISODuration(val)
+		return elems, neg, err
+	}
 
 	re := regexp.MustCompile(`(\d*\.\d+|\d+)([A-Za-zµμ]*)`)
 
@@ -28,9 +108,9 @@ func ParseFlexibleDuration(val string) (time.Duration, error) {
 
 	strs := re.FindAllStringSubmatch(val, -1)
 	if len(strs) == 0 {
-		return 0, fmt.Errorf("invalid duration string: %q", val)
+		return nil, false, fmt.Errorf("invalid duration string: %q", val)
 	}
-	var sumDur time.Duration
+	elems := make([]durationElement, 0, len(strs))
 	for _, m := range strs {
 		// m[0] full match, m[1] numeric part, m[2] unit part
 		numStr := m[1]
@@ -38,7 +118,7 @@ func ParseFlexibleDuration(val string) (time.Duration, error) {
 
 		if unitStr == "" {
 			// default to seconds if unit omitted? We choose to error since ambiguous
-			return 0, fmt.Errorf("missing unit in duration element: %q", m[0])
+			return nil, false, fmt.Errorf("missing unit in duration element: %q", m[0])
 		}
 
 		// Normalize common micro symbol variations
@@ -54,15 +134,16 @@ func ParseFlexibleDuration(val string) (time.Duration, error) {
 			// safe to delegate to time.ParseDuration
 			dur, err := time.ParseDuration(numStr + uLower)
 			if err != nil {
-				return 0, err
+				return nil, false, err
 			}
-			sumDur += dur
+			elems = append(elems, durationElement{dur: dur})
 			continue
 		}
 
 		// Custom units: days, weeks, months, years
 		l := strings.ToLower(unitStr)
 		var unitDur time.Duration
+		months := 0
 		switch l {
 		case "d":
 			unitDur = unitMap["d"]
@@ -70,29 +151,74 @@ func ParseFlexibleDuration(val string) (time.Duration, error) {
 			unitDur = unitMap["w"]
 		case "mth":
 			// 'mth' is a month alias. 'm' is reserved for minutes and handled by time.ParseDuration above.
-			unitDur = unitMap["mth"]
+			unitDur, months = unitMap["mth"], 1
 		case "month":
-			unitDur = unitMap["mth"]
+			unitDur, months = unitMap["mth"], 1
 		case "mo":
-			unitDur = unitMap["mth"]
+			unitDur, months = unitMap["mth"], 1
 		case "y":
-			unitDur = unitMap["y"]
+			unitDur, months = unitMap["y"], 12
 		default:
-			return 0, fmt.Errorf("unknown duration unit: %q", unitStr)
+			return nil, false, fmt.Errorf("unknown duration unit: %q", unitStr)
 		}
 
 		// Convert numeric part to float so we can support fractions like 1.5d
 		num, err := strconv.ParseFloat(numStr, 64)
 		if err != nil {
-			return 0, err
+			return nil, false, err
 		}
 		part := time.Duration(float64(unitDur) * num)
-		sumDur += part
+		elems = append(elems, durationElement{dur: part, num: num, unit: unitDur, months: months})
 	}
+	return elems, neg, nil
+}
 
-	if neg {
-		sumDur = -sumDur
+// ParseDurationList parses a comma separated list of flexible durations like
+// "24h,1h,10m". The result is sorted from longest to shortest. Empty input
+// returns nil.
+func ParseDurationList(val string) ([]time.Duration, error) {
+	if strings.TrimSpace(val) == "" {
+		return nil, nil
 	}
+	var out []time.Duration
+	for _, part := range strings.Split(val, ",") {
+		d, err := ParseFlexibleDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("duration must be positive: %q", part)
+		}
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] > out[j] })
+	return out, nil
+}
 
-	return sumDur, nil
+// FormatFlexibleDuration formats a duration with the units accepted by
+// ParseFlexibleDuration, e.g. "1d2h" or "10m". Sub-second precision is dropped.
+func FormatFlexibleDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + FormatFlexibleDuration(-d)
+	}
+	d = d.Truncate(time.Second)
+	if d == 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	for _, u := range []struct {
+		unit string
+		dur  time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	} {
+		if n := d / u.dur; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.unit)
+			d -= n * u.dur
+		}
+	}
+	return b.String()
 }
@@ -1,6 +1,10 @@
 package util
 
-import "testing"
+import (
+	"math"
+	"testing"
+	"time"
+)
 
 // FuzzParseFlexibleDuration exercises ParseFlexibleDuration with random inputs
 // to improve coverage and expose edge-cases in parsing flexible durations.
@@ -62,3 +66,55 @@ func FuzzParseFlexibleDuration(f *testing.F) {
 		}()
 	})
 }
+
+// FuzzLeaseExpiry checks that calendar expiries never panic and agree with
+// ParseFlexibleDuration on whether a TTL is valid
+func FuzzLeaseExpiry(f *testing.F) {
+	for _, s := range []string{"1mo", "1y", "1.5mo", "-2y3mo", "1mo2d3h", "12month", "1e10mo", "abc", ""} {
+		f.Add(s, true)
+		f.Add(s, false)
+	}
+
+	start := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, in string, calendar bool) {
+		expiry, err := LeaseExpiry(in, calendar)
+		if _, perr := ParseFlexibleDuration(in); (err == nil) != (perr == nil) {
+			t.Fatalf("LeaseExpiry(%q) error %v, ParseFlexibleDuration error %v", in, err, perr)
+		}
+		if err == nil {
+			_ = expiry(start)
+		}
+	})
+}
+
+// FuzzParseISODuration runs ISO 8601 shaped inputs through the parser to
+// make sure they never panic
+func FuzzParseISODuration(f *testing.F) {
+	for _, s := range []string{"P1DT2H", "PT30M", "P2W", "P1Y2M3W4DT5H6M7.5S", "PT0,5H", "-P1D", "P0001-02-03T04:05:06", "P00010203T040506", "P", "PT", "P1.5DT2H", "P99999999999999999999Y", "PT9999999999999999999H"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		_, _ = ParseFlexibleDuration(in)
+		_, _ = LeaseExpiry(in, true)
+	})
+}
+
+// FuzzFormatISODuration checks that formatted durations parse back to the
+// same value
+func FuzzFormatISODuration(f *testing.F) {
+	for _, d := range []int64{0, 1, -1, int64(time.Hour), int64(26 * time.Hour), int64(1500 * time.Millisecond), math.MaxInt64} {
+		f.Add(d)
+	}
+
+	f.Fuzz(func(t *testing.T, d int64) {
+		if d == math.MinInt64 {
+			return
+		}
+		s := FormatISODuration(time.Duration(d))
+		got, err := ParseFlexibleDuration(s)
+		if err != nil || got != time.Duration(d) {
+			t.Fatalf("ParseFlexibleDuration(%q) = %v, %v, want %v", s, got, err, time.Duration(d))
+		}
+	})
+}
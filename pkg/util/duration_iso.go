@@ -0,0 +1,170 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// isoDesignators matches the designator form, e.g. P1Y2M3W4DT5H6M7.5S
+	isoDesignators = regexp.MustCompile(`^P(?:([\d.,]+)Y)?(?:([\d.,]+)M)?(?:([\d.,]+)W)?(?:([\d.,]+)D)?(?:T(?:([\d.,]+)H)?(?:([\d.,]+)M)?(?:([\d.,]+)S)?)?$`)
+	// isoAlternative matches the alternative form, e.g. P0001-02-03T04:05:06
+	// or P00010203T040506
+	isoAlternative = regexp.MustCompile(`^P(\d{4})-?(\d{2})-?(\d{2})(?:T(\d{2}):?(\d{2}):?(\d{2}(?:[.,]\d+)?))?$`)
+	isoNumber      = regexp.MustCompile(`^\d+(?:[.,]\d+)?$`)
+)
+
+// IsISODuration reports whether val is written as an ISO 8601 duration
+func IsISODuration(val string) bool {
+	val = strings.TrimPrefix(strings.TrimSpace(val), "-")
+	return len(val) > 0 && (val[0] == 'P' || val[0] == 'p')
+}
+
+// parseISODuration splits an unsigned ISO 8601 duration into elements. Years
+// and months are calendar units like y and mo, weeks and days count as 7 and
+// 1 days.
+func parseISODuration(val string) ([]durationElement, error) {
+	val = strings.ToUpper(strings.TrimSpace(val))
+	if m := isoAlternative.FindStringSubmatch(val); m != nil {
+		return parseISOAlternative(val, m)
+	}
+	m := isoDesignators.FindStringSubmatch(val)
+	if m == nil || val == "P" || strings.HasSuffix(val, "T") {
+		return nil, fmt.Errorf("invalid ISO 8601 duration: %q", val)
+	}
+
+	units := []struct {
+		unit   string
+		length time.Duration
+		months int
+	}{
+		{"y", 365 * 24 * time.Hour, 12},
+		{"mo", 30 * 24 * time.Hour, 1},
+		{"w", 7 * 24 * time.Hour, 0},
+		{"d", 24 * time.Hour, 0},
+		{"h", time.Hour, 0},
+		{"m", time.Minute, 0},
+		{"s", time.Second, 0},
+	}
+	var elems []durationElement
+	fraction := false
+	for i, u := range units {
+		numStr := m[i+1]
+		if numStr == "" {
+			continue
+		}
+		// Only the smallest element may have a fraction
+		if fraction {
+			return nil, fmt.Errorf("invalid ISO 8601 duration: %q, only the last element may have a fraction", val)
+		}
+		if !isoNumber.MatchString(numStr) {
+			return nil, fmt.Errorf("invalid number %q in ISO 8601 duration %q", numStr, val)
+		}
+		numStr = strings.ReplaceAll(numStr, ",", ".")
+		fraction = strings.Contains(numStr, ".")
+		elem, err := isoElement(numStr, u.unit, u.length, u.months)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// parseISOAlternative converts the alternative form. Each value has to stay
+// below the point where it carries over into the next unit.
+func parseISOAlternative(val string, m []string) ([]durationElement, error) {
+	limits := []struct {
+		unit   string
+		length time.Duration
+		months int
+		max    float64
+	}{
+		{"y", 365 * 24 * time.Hour, 12, 9999},
+		{"mo", 30 * 24 * time.Hour, 1, 12},
+		{"d", 24 * time.Hour, 0, 30},
+		{"h", time.Hour, 0, 24},
+		{"m", time.Minute, 0, 59},
+		{"s", time.Second, 0, 59.999999999},
+	}
+	var elems []durationElement
+	for i, l := range limits {
+		numStr := strings.ReplaceAll(m[i+1], ",", ".")
+		if numStr == "" {
+			continue
+		}
+		if num, err := strconv.ParseFloat(numStr, 64); err != nil || num > l.max {
+			return nil, fmt.Errorf("invalid ISO 8601 duration: %q, %s out of range", val, l.unit)
+		}
+		elem, err := isoElement(numStr, l.unit, l.length, l.months)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// isoElement builds one element. Hours, minutes and seconds are exact to the
+// nanosecond, longer units are scaled like the flexible units.
+func isoElement(numStr, unit string, length time.Duration, months int) (durationElement, error) {
+	if length <= time.Hour {
+		dur, err := time.ParseDuration(numStr + unit)
+		if err != nil {
+			return durationElement{}, err
+		}
+		return durationElement{dur: dur}, nil
+	}
+	num, err := strconv.ParseFloat(numStr, 64)
+	if err != nil {
+		return durationElement{}, err
+	}
+	return durationElement{dur: time.Duration(float64(length) * num), num: num, unit: length, months: months}, nil
+}
+
+// FormatISODuration formats a duration as ISO 8601 with days, hours, minutes
+// and seconds, e.g. "P1DT2H" or "PT30M". ParseFlexibleDuration reads it back
+// to the same duration.
+func FormatISODuration(d time.Duration) string {
+	sign := ""
+	u := uint64(d)
+	if d < 0 {
+		sign = "-"
+		u = uint64(-(d + 1)) + 1
+	}
+	if u == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if days := u / uint64(24*time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		u -= days * uint64(24*time.Hour)
+	}
+	if u == 0 {
+		return b.String()
+	}
+	b.WriteString("T")
+	for _, unit := range []struct {
+		designator string
+		length     time.Duration
+	}{{"H", time.Hour}, {"M", time.Minute}} {
+		if n := u / uint64(unit.length); n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.designator)
+			u -= n * uint64(unit.length)
+		}
+	}
+	if u > 0 {
+		secs, nanos := u/uint64(time.Second), u%uint64(time.Second)
+		if nanos == 0 {
+			fmt.Fprintf(&b, "%dS", secs)
+		} else {
+			fmt.Fprintf(&b, "%d.%sS", secs, strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+		}
+	}
+	return b.String()
+}
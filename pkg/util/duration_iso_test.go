@@ -0,0 +1,105 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFlexibleDuration_ISO8601(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"P1DT2H", 26 * time.Hour, false},
+		{"PT30M", 30 * time.Minute, false},
+		{"P2W", 14 * day, false},
+		{"P1M", 30 * day, false},
+		{"PT1M", time.Minute, false},
+		{"P1Y", 365 * day, false},
+		{"P1Y2M3W4DT5H6M7S", 365*day + 60*day + 21*day + 4*day + 5*time.Hour + 6*time.Minute + 7*time.Second, false},
+		{"PT1.5S", 1500 * time.Millisecond, false},
+		{"PT0,5H", 30 * time.Minute, false},
+		{"P0.5D", 12 * time.Hour, false},
+		{"pt10m", 10 * time.Minute, false},
+		{" PT10M ", 10 * time.Minute, false},
+		{"-P1D", -day, false},
+		{"PT0S", 0, false},
+		{"P0001-02-03T04:05:06", 365*day + 60*day + 3*day + 4*time.Hour + 5*time.Minute + 6*time.Second, false},
+		{"P00000010T120000", 10*day + 12*time.Hour, false},
+		{"P0000-00-01", day, false},
+
+		{"P", 0, true},
+		{"PT", 0, true},
+		{"P1DT", 0, true},
+		{"P1H", 0, true},
+		{"PT1D", 0, true},
+		{"P1.5DT2H", 0, true},
+		{"P1D2Y", 0, true},
+		{"P1..5D", 0, true},
+		{"P0000-13-00", 0, true},
+		{"P0000-00-00T25:00:00", 0, true},
+		{"PX", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFlexibleDuration(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFlexibleDuration(%q) expected error, got %v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFlexibleDuration(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseFlexibleDuration(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestLeaseExpiry_ISO8601Calendar(t *testing.T) {
+	expiry, err := LeaseExpiry("P1MT2H", true)
+	if err != nil {
+		t.Fatalf("LeaseExpiry error: %v", err)
+	}
+	start := time.Date(2025, time.January, 31, 10, 0, 0, 0, time.UTC)
+	if got, want := expiry(start), time.Date(2025, time.February, 28, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expiry = %s, want %s", got, want)
+	}
+}
+
+func TestFormatISODuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{30 * time.Minute, "PT30M"},
+		{26 * time.Hour, "P1DT2H"},
+		{48 * time.Hour, "P2D"},
+		{time.Hour + 1500*time.Millisecond, "PT1H1.5S"},
+		{-90 * time.Second, "-PT1M30S"},
+		{time.Nanosecond, "PT0.000000001S"},
+	}
+	for _, tt := range tests {
+		got := FormatISODuration(tt.in)
+		if got != tt.want {
+			t.Errorf("FormatISODuration(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+		back, err := ParseFlexibleDuration(got)
+		if err != nil || back != tt.in {
+			t.Errorf("ParseFlexibleDuration(%q) = %v, %v, want %v", got, back, err, tt.in)
+		}
+	}
+}
+
+func TestIsISODuration(t *testing.T) {
+	for in, want := range map[string]bool{"P1D": true, "-PT1H": true, "pt5m": true, "1d": false, "": false, "-": false} {
+		if got := IsISODuration(in); got != want {
+			t.Errorf("IsISODuration(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -87,3 +87,94 @@ func TestParseFlexibleDuration_RangeErrors(t *testing.T) {
 		t.Fatalf("expected range error for extremely large duration number using custom parse path")
 	}
 }
+
+func TestParseDurationList(t *testing.T) {
+	got, err := ParseDurationList("10m, 1d,1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []time.Duration{24 * time.Hour, time.Hour, 10 * time.Minute}
+	if len(got) != len(want) {
+		t.Fatalf("ParseDurationList = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ParseDurationList = %v, want %v", got, want)
+		}
+	}
+
+	if got, err := ParseDurationList(" "); err != nil || got != nil {
+		t.Fatalf("expected nil for empty input, got %v, %v", got, err)
+	}
+	for _, in := range []string{"1h,bogus", "1h,-5m", "1h,,2h"} {
+		if _, err := ParseDurationList(in); err == nil {
+			t.Errorf("ParseDurationList(%q) expected error", in)
+		}
+	}
+}
+
+func TestFormatFlexibleDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{10 * time.Minute, "10m"},
+		{time.Hour + 30*time.Minute, "1h30m"},
+		{26 * time.Hour, "1d2h"},
+		{-3 * time.Hour, "-3h"},
+		{1500 * time.Millisecond, "1s"},
+	}
+	for _, tt := range tests {
+		got := FormatFlexibleDuration(tt.in)
+		if got != tt.want {
+			t.Errorf("FormatFlexibleDuration(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+		if back, err := ParseFlexibleDuration(got); err != nil || back != tt.in.Truncate(time.Second) {
+			t.Errorf("round trip of %q = %v, %v", got, back, err)
+		}
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	jan31 := time.Date(2025, time.January, 31, 12, 0, 0, 0, time.UTC)
+	leap := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ttl      string
+		start    time.Time
+		calendar bool
+		want     time.Time
+	}{
+		{"1mo", jan31, false, jan31.Add(30 * 24 * time.Hour)},
+		{"1mo", jan31, true, time.Date(2025, time.February, 28, 12, 0, 0, 0, time.UTC)},
+		{"1mo2h", jan31, true, time.Date(2025, time.February, 28, 14, 0, 0, 0, time.UTC)},
+		{"2month", jan31, true, time.Date(2025, time.March, 31, 12, 0, 0, 0, time.UTC)},
+		{"1y", leap, true, time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)},
+		{"1y", leap, false, leap.Add(365 * 24 * time.Hour)},
+		{"1.5mo", jan31, true, time.Date(2025, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		{"-1mo", jan31, true, time.Date(2024, time.December, 31, 12, 0, 0, 0, time.UTC)},
+		{"3d", jan31, true, jan31.Add(72 * time.Hour)},
+	}
+	for _, tt := range tests {
+		expiry, err := LeaseExpiry(tt.ttl, tt.calendar)
+		if err != nil {
+			t.Fatalf("LeaseExpiry(%q) error: %v", tt.ttl, err)
+		}
+		if got := expiry(tt.start); !got.Equal(tt.want) {
+			t.Errorf("LeaseExpiry(%q, %v)(%s) = %s, want %s", tt.ttl, tt.calendar, tt.start, got, tt.want)
+		}
+	}
+	if _, err := LeaseExpiry("soon", true); err == nil {
+		t.Fatalf("expected an error for an invalid TTL")
+	}
+}
+
+func TestAddMonths_ClampsToMonthEnd(t *testing.T) {
+	start := time.Date(2025, time.March, 31, 8, 30, 0, 0, time.UTC)
+	if got, want := AddMonths(start, 1), time.Date(2025, time.April, 30, 8, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("AddMonths = %s, want %s", got, want)
+	}
+	if got, want := AddMonths(start, -13), time.Date(2024, time.February, 29, 8, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("AddMonths = %s, want %s", got, want)
+	}
+}
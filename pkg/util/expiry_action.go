@@ -0,0 +1,141 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Built-in expiry action names
+const (
+	ExpiryActionDelete      = "delete"
+	ExpiryActionScaleToZero = "scale-to-zero"
+	ExpiryActionSuspend     = "suspend"
+	ExpiryActionLabel       = "label"
+	ExpiryActionPatch       = "patch"
+	// ExpiryActionHibernate scales to zero first and deletes after a grace
+	// period. The two stages are driven by the LeaseWatcher.
+	ExpiryActionHibernate = "hibernate"
+)
+
+// ExpiryAction is performed on an object when its lease expires. arg is the
+// optional argument given after the action name in the on-expire annotation,
+// e.g. "env=expired" for "label:env=expired".
+type ExpiryAction interface {
+	Execute(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error
+}
+
+// ExpiryActionFunc adapts a plain function to an ExpiryAction
+type ExpiryActionFunc func(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error
+
+// Execute calls f
+func (f ExpiryActionFunc) Execute(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error {
+	return f(ctx, c, obj, arg)
+}
+
+var (
+	expiryActionsMu sync.RWMutex
+	expiryActions   = map[string]ExpiryAction{
+		ExpiryActionDelete:      ExpiryActionFunc(deleteAction),
+		ExpiryActionScaleToZero: ExpiryActionFunc(scaleToZeroAction),
+		ExpiryActionHibernate:   ExpiryActionFunc(scaleToZeroAction),
+		ExpiryActionSuspend:     ExpiryActionFunc(suspendAction),
+		ExpiryActionLabel:       ExpiryActionFunc(labelAction),
+		ExpiryActionPatch:       ExpiryActionFunc(patchFromConfigMapAction),
+	}
+)
+
+// RegisterExpiryAction registers an action under name, replacing any existing action with that name
+func RegisterExpiryAction(name string, action ExpiryAction) {
+	expiryActionsMu.Lock()
+	defer expiryActionsMu.Unlock()
+	expiryActions[name] = action
+}
+
+// ParseExpiryAction parses an on-expire annotation value of the form "name" or
+// "name:arg" and looks up the registered action. An empty value selects delete.
+func ParseExpiryAction(val string) (name string, action ExpiryAction, arg string, err error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		val = ExpiryActionDelete
+	}
+	name, arg, _ = strings.Cut(val, ":")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	expiryActionsMu.RLock()
+	action, ok := expiryActions[name]
+	expiryActionsMu.RUnlock()
+	if !ok {
+		return "", nil, "", fmt.Errorf("unknown on-expire action: %q", name)
+	}
+	return name, action, strings.TrimSpace(arg), nil
+}
+
+// deleteAction deletes the object. arg is an optional propagation policy.
+func deleteAction(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error {
+	propagation, err := ParsePropagationPolicy(arg)
+	if err != nil {
+		return err
+	}
+	return DeleteWithUIDPrecondition(ctx, c, obj, propagation)
+}
+
+// scaleToZeroAction sets replicas to 0 through the scale subresource
+func scaleToZeroAction(ctx context.Context, c client.Client, obj *unstructured.Unstructured, _ string) error {
+	return SetReplicas(ctx, c, obj, 0)
+}
+
+// suspendAction sets spec.suspend on Jobs and CronJobs
+func suspendAction(ctx context.Context, c client.Client, obj *unstructured.Unstructured, _ string) error {
+	gvk := obj.GroupVersionKind()
+	if gvk.Group != "batch" || (gvk.Kind != "Job" && gvk.Kind != "CronJob") {
+		return fmt.Errorf("suspend is only supported for batch Jobs and CronJobs, got %s", gvk.GroupKind())
+	}
+	patch := client.RawPatch(types.MergePatchType, []byte(`{"spec":{"suspend":true}}`))
+	return c.Patch(ctx, obj.DeepCopy(), patch)
+}
+
+// labelAction adds the label given as "key=value"
+func labelAction(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error {
+	key, value, ok := strings.Cut(arg, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid label argument: expected 'key=value', got '%s'", arg)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{strings.TrimSpace(key): strings.TrimSpace(value)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, obj.DeepCopy(), client.RawPatch(types.MergePatchType, data))
+}
+
+// patchFromConfigMapAction applies the JSON merge patch stored in
+// "configmap-name/key" in the object's namespace
+func patchFromConfigMapAction(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error {
+	parts := strings.SplitN(arg, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid patch argument: expected 'configmap-name/key', got '%s'", arg)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: parts[0]}, cm); err != nil {
+		return fmt.Errorf("failed to get patch configmap: %w", err)
+	}
+	data, ok := cm.Data[parts[1]]
+	if !ok {
+		return fmt.Errorf("key %q not found in configmap %q", parts[1], parts[0])
+	}
+	if !json.Valid([]byte(data)) {
+		return fmt.Errorf("key %q in configmap %q is not valid JSON", parts[1], parts[0])
+	}
+	return c.Patch(ctx, obj.DeepCopy(), client.RawPatch(types.MergePatchType, []byte(data)))
+}
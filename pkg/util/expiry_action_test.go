@@ -0,0 +1,180 @@
+package util
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func unstructuredConfigMap(ns, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	u.SetNamespace(ns)
+	u.SetName(name)
+	return u
+}
+
+func TestParseExpiryAction(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantName string
+		wantArg  string
+		wantErr  bool
+	}{
+		{"", ExpiryActionDelete, "", false},
+		{"delete", ExpiryActionDelete, "", false},
+		{"Scale-To-Zero", ExpiryActionScaleToZero, "", false},
+		{"suspend", ExpiryActionSuspend, "", false},
+		{"label:lease=expired", ExpiryActionLabel, "lease=expired", false},
+		{"patch:patches/expire.json", ExpiryActionPatch, "patches/expire.json", false},
+		{"explode", "", "", true},
+	}
+
+	for _, tt := range tests {
+		name, action, arg, err := ParseExpiryAction(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseExpiryAction(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if name != tt.wantName || arg != tt.wantArg || action == nil {
+			t.Errorf("ParseExpiryAction(%q) = (%q, %v, %q), want (%q, action, %q)", tt.input, name, action, arg, tt.wantName, tt.wantArg)
+		}
+	}
+}
+
+func TestRegisterExpiryAction(t *testing.T) {
+	called := false
+	RegisterExpiryAction("test-noop", ExpiryActionFunc(func(ctx context.Context, c client.Client, obj *unstructured.Unstructured, arg string) error {
+		called = arg == "x"
+		return nil
+	}))
+
+	_, action, arg, err := ParseExpiryAction("test-noop:x")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := action.Execute(context.Background(), nil, nil, arg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !called {
+		t.Fatalf("expected registered action to be called with its argument")
+	}
+}
+
+func TestLabelAction(t *testing.T) {
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns"}}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(cm).Build()
+
+	if err := labelAction(ctx, c, unstructuredConfigMap("ns", "cm"), "lease=expired"); err != nil {
+		t.Fatalf("labelAction error: %v", err)
+	}
+	got := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "ns", Name: "cm"}, got); err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	if got.Labels["lease"] != "expired" {
+		t.Fatalf("expected label lease=expired, got %v", got.Labels)
+	}
+
+	if err := labelAction(ctx, c, unstructuredConfigMap("ns", "cm"), "no-equals"); err == nil {
+		t.Fatalf("expected error for malformed label argument")
+	}
+}
+
+func TestPatchFromConfigMapAction(t *testing.T) {
+	ctx := context.Background()
+	target := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "target", Namespace: "ns"}, Data: map[string]string{"a": "1"}}
+	patches := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "patches", Namespace: "ns"},
+		Data: map[string]string{
+			"expire.json": `{"data":{"a":"2"}}`,
+			"broken.json": `{not json`,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(target, patches).Build()
+
+	if err := patchFromConfigMapAction(ctx, c, unstructuredConfigMap("ns", "target"), "patches/expire.json"); err != nil {
+		t.Fatalf("patch action error: %v", err)
+	}
+	got := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "ns", Name: "target"}, got); err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	if got.Data["a"] != "2" {
+		t.Fatalf("expected patched data a=2, got %v", got.Data)
+	}
+
+	for _, arg := range []string{"patches/broken.json", "patches/missing.json", "nope/expire.json", "no-slash"} {
+		if err := patchFromConfigMapAction(ctx, c, unstructuredConfigMap("ns", "target"), arg); err == nil {
+			t.Errorf("expected error for argument %q", arg)
+		}
+	}
+}
+
+func TestSuspendAction(t *testing.T) {
+	ctx := context.Background()
+	s := newScheme(t)
+	if err := batchv1.AddToScheme(s); err != nil {
+		t.Fatalf("add to scheme: %v", err)
+	}
+	cj := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "ns"}}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(cj).Build()
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("CronJob"))
+	u.SetNamespace("ns")
+	u.SetName("nightly")
+	if err := suspendAction(ctx, c, u, ""); err != nil {
+		t.Fatalf("suspend error: %v", err)
+	}
+	got := &batchv1.CronJob{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "ns", Name: "nightly"}, got); err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	if got.Spec.Suspend == nil || !*got.Spec.Suspend {
+		t.Fatalf("expected spec.suspend=true")
+	}
+
+	if err := suspendAction(ctx, c, unstructuredConfigMap("ns", "cm"), ""); err == nil || !strings.Contains(err.Error(), "only supported") {
+		t.Fatalf("expected unsupported kind error, got %v", err)
+	}
+}
+
+func TestScaleToZeroAction_UsesScaleSubresource(t *testing.T) {
+	var gotSubResource, gotPatch string
+	c := fake.NewClientBuilder().WithScheme(newScheme(t)).WithInterceptorFuncs(interceptor.Funcs{
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			gotSubResource = subResourceName
+			data, _ := patch.Data(obj)
+			gotPatch = string(data)
+			return nil
+		},
+	}).Build()
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	u.SetNamespace("ns")
+	u.SetName("web")
+	if err := scaleToZeroAction(context.Background(), c, u, ""); err != nil {
+		t.Fatalf("scale error: %v", err)
+	}
+	if gotSubResource != "scale" {
+		t.Fatalf("expected scale subresource, got %q", gotSubResource)
+	}
+	if gotPatch != `{"spec":{"replicas":0}}` {
+		t.Fatalf("unexpected scale patch: %s", gotPatch)
+	}
+}
@@ -0,0 +1,44 @@
+package util
+
+import "sync"
+
+// KillSwitch holds the cluster-wide deletion freeze read from the kill-switch
+// ConfigMap. It is shared between the reconciler that watches the ConfigMap
+// and the LeaseWatcher that consults it before acting on an expired lease.
+// Until the ConfigMap was read once the state is unknown.
+type KillSwitch struct {
+	mu     sync.RWMutex
+	synced bool
+	frozen bool
+	reason string
+}
+
+func NewKillSwitch() *KillSwitch {
+	return &KillSwitch{}
+}
+
+// Set records whether deletions are frozen and why. Returns true if the state changed.
+func (k *KillSwitch) Set(frozen bool, reason string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	changed := k.frozen != frozen
+	k.synced = true
+	k.frozen = frozen
+	k.reason = reason
+	return changed
+}
+
+// Frozen reports whether deletions are frozen, and the reason given in the ConfigMap
+func (k *KillSwitch) Frozen() (bool, string) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.frozen, k.reason
+}
+
+// Synced reports whether the ConfigMap was read, before that deletions are
+// held as if frozen
+func (k *KillSwitch) Synced() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.synced
+}
@@ -0,0 +1,25 @@
+package util
+
+import "testing"
+
+func TestKillSwitch_SetReportsChanges(t *testing.T) {
+	k := NewKillSwitch()
+	if k.Synced() {
+		t.Fatalf("new kill switch should not be synced")
+	}
+	if !k.Set(true, "incident") {
+		t.Fatalf("expected change when freezing")
+	}
+	if !k.Synced() {
+		t.Fatalf("expected the kill switch to be synced once set")
+	}
+	if k.Set(true, "still incident") {
+		t.Fatalf("expected no change when already frozen")
+	}
+	if frozen, reason := k.Frozen(); !frozen || reason != "still incident" {
+		t.Fatalf("Frozen() = (%v, %q), want (true, %q)", frozen, reason, "still incident")
+	}
+	if !k.Set(false, "") {
+		t.Fatalf("expected change when unfreezing")
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LeaseMode selects how the lease clock is driven
+type LeaseMode string
+
+const (
+	// LeaseModeFixed counts from lease-start until the TTL elapses (default)
+	LeaseModeFixed LeaseMode = "fixed"
+	// LeaseModeSliding restarts the lease whenever the object is changed by someone other than the controller
+	LeaseModeSliding LeaseMode = "sliding"
+	// LeaseModeHeartbeat counts from the last heartbeat written by an external system
+	LeaseModeHeartbeat LeaseMode = "heartbeat"
+)
+
+// ParseLeaseMode parses a lease-mode annotation value. An empty value means LeaseModeFixed.
+func ParseLeaseMode(val string) (LeaseMode, error) {
+	switch LeaseMode(strings.ToLower(strings.TrimSpace(val))) {
+	case "", LeaseModeFixed:
+		return LeaseModeFixed, nil
+	case LeaseModeSliding:
+		return LeaseModeSliding, nil
+	case LeaseModeHeartbeat:
+		return LeaseModeHeartbeat, nil
+	default:
+		return LeaseModeFixed, fmt.Errorf("unknown lease mode: %q", val)
+	}
+}
@@ -0,0 +1,29 @@
+package util
+
+import "testing"
+
+func TestParseLeaseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    LeaseMode
+		wantErr bool
+	}{
+		{"", LeaseModeFixed, false},
+		{"fixed", LeaseModeFixed, false},
+		{"sliding", LeaseModeSliding, false},
+		{" Sliding ", LeaseModeSliding, false},
+		{"heartbeat", LeaseModeHeartbeat, false},
+		{"bogus", LeaseModeFixed, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLeaseMode(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLeaseMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLeaseMode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
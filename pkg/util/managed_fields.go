@@ -0,0 +1,35 @@
+package util
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationManager returns the field manager that last set the annotation
+// key on obj according to its managedFields, or "" when none owns it.
+func AnnotationManager(obj metav1.Object, key string) string {
+	var manager string
+	var latest *metav1.Time
+	for _, entry := range obj.GetManagedFields() {
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		var fields struct {
+			Metadata struct {
+				Annotations map[string]json.RawMessage `json:"f:annotations"`
+			} `json:"f:metadata"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields.Metadata.Annotations["f:"+key]; !ok {
+			continue
+		}
+		// Fields shared by several managers go to the most recent one
+		if manager == "" || entry.Time != nil && (latest == nil || entry.Time.After(latest.Time)) {
+			manager, latest = entry.Manager, entry.Time
+		}
+	}
+	return manager
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func managedEntry(manager string, at time.Time, fields string) v1.ManagedFieldsEntry {
+	t := v1.NewTime(at)
+	return v1.ManagedFieldsEntry{
+		Manager:    manager,
+		Operation:  v1.ManagedFieldsOperationUpdate,
+		Time:       &t,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &v1.FieldsV1{Raw: []byte(fields)},
+	}
+}
+
+func TestAnnotationManager(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	meta := &v1.ObjectMeta{ManagedFields: []v1.ManagedFieldsEntry{
+		managedEntry("kubectl-create", now.Add(-time.Hour), `{"f:data":{"f:key":{}},"f:metadata":{"f:annotations":{".":{},"f:ttl":{}}}}`),
+		managedEntry("kubectl-annotate", now.Add(-time.Minute), `{"f:metadata":{"f:annotations":{"f:renew":{}}}}`),
+		managedEntry("argocd", now.Add(-2*time.Minute), `{"f:metadata":{"f:annotations":{"f:renew":{}}}}`),
+		managedEntry("broken", now, `not json`),
+	}}
+
+	cases := map[string]string{
+		"renew":   "kubectl-annotate",
+		"ttl":     "kubectl-create",
+		"missing": "",
+	}
+	for key, want := range cases {
+		if got := AnnotationManager(meta, key); got != want {
+			t.Errorf("AnnotationManager(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
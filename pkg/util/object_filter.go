@@ -40,9 +40,24 @@ func stripU(in *unstructured.Unstructured, keep map[string]struct{}) *unstructur
 	out.SetNamespace(in.GetNamespace())
 	out.SetUID(in.GetUID())
 	out.SetResourceVersion(in.GetResourceVersion())
+	// Generation and labels are needed to detect user edits for sliding leases
+	if gen := in.GetGeneration(); gen != 0 {
+		out.SetGeneration(gen)
+	}
+	if labels := in.GetLabels(); len(labels) > 0 {
+		out.SetLabels(labels)
+	}
 	if ts := in.GetDeletionTimestamp(); ts != nil {
 		out.SetDeletionTimestamp(ts)
 	}
+	// Finalizers show what a foreground deletion is still waiting on
+	if f := in.GetFinalizers(); len(f) > 0 {
+		out.SetFinalizers(f)
+	}
+	// Owner references are followed to inherit leases
+	if owners := in.GetOwnerReferences(); len(owners) > 0 {
+		out.SetOwnerReferences(owners)
+	}
 	if anns := in.GetAnnotations(); len(anns) > 0 {
 		filtered := make(map[string]string, 4)
 		for k, v := range anns {
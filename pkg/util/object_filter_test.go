@@ -172,3 +172,33 @@ func TestMinimalObjectTransform_StripsManagedFields(t *testing.T) {
 		t.Fatalf("expected managed fields to be stripped, got %v", out.GetManagedFields())
 	}
 }
+
+func TestStripU_PreservesGenerationAndLabels(t *testing.T) {
+	t.Parallel()
+
+	u := &unstructured.Unstructured{}
+	u.SetName("gen")
+	u.SetNamespace("ns")
+	u.SetGeneration(7)
+	u.SetLabels(map[string]string{"app": "web"})
+	u.SetFinalizers([]string{"foregroundDeletion"})
+	u.SetOwnerReferences([]v1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "owner", UID: "uid-1"}})
+	u.Object["spec"] = map[string]interface{}{"replicas": int64(3)}
+
+	out := stripU(u, map[string]struct{}{})
+	if out.GetGeneration() != 7 {
+		t.Fatalf("expected generation 7, got %d", out.GetGeneration())
+	}
+	if out.GetLabels()["app"] != "web" {
+		t.Fatalf("expected labels to be preserved, got %v", out.GetLabels())
+	}
+	if f := out.GetFinalizers(); len(f) != 1 || f[0] != "foregroundDeletion" {
+		t.Fatalf("expected finalizers to be preserved, got %v", f)
+	}
+	if o := out.GetOwnerReferences(); len(o) != 1 || o[0].Name != "owner" {
+		t.Fatalf("expected owner references to be preserved, got %v", o)
+	}
+	if _, ok := out.Object["spec"]; ok {
+		t.Fatalf("expected spec to be stripped")
+	}
+}
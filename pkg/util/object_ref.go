@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ObjectRef points at another object by kind, namespace and name
+type ObjectRef struct {
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+}
+
+// ParseObjectRef parses a reference of the form Kind.version.group/namespace/name.
+// The group is left out for the core group, e.g. "ConfigMap.v1/default/settings".
+// Without a namespace the reference resolves in defaultNamespace. An empty
+// namespace, e.g. "Namespace.v1//team-a", refers to a cluster-scoped object.
+func ParseObjectRef(val, defaultNamespace string) (ObjectRef, error) {
+	parts := strings.Split(strings.TrimSpace(val), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ObjectRef{}, fmt.Errorf("invalid object reference %q: expected Kind.version.group/[namespace/]name", val)
+	}
+	kind, rest, ok := strings.Cut(parts[0], ".")
+	if !ok || kind == "" || rest == "" {
+		return ObjectRef{}, fmt.Errorf("invalid object reference %q: expected Kind.version.group/[namespace/]name", val)
+	}
+	version, group, _ := strings.Cut(rest, ".")
+	ref := ObjectRef{
+		GVK:       schema.GroupVersionKind{Group: group, Version: version, Kind: kind},
+		Namespace: defaultNamespace,
+		Name:      parts[len(parts)-1],
+	}
+	if len(parts) == 3 {
+		ref.Namespace = parts[1]
+	}
+	if ref.Name == "" {
+		return ObjectRef{}, fmt.Errorf("invalid object reference %q: missing name", val)
+	}
+	return ref, nil
+}
+
+// String formats the reference so ParseObjectRef reads it back. It always
+// includes the namespace, which makes it usable as an index key.
+func (r ObjectRef) String() string {
+	kind := r.GVK.Kind + "." + r.GVK.Version
+	if r.GVK.Group != "" {
+		kind += "." + r.GVK.Group
+	}
+	return kind + "/" + r.Namespace + "/" + r.Name
+}
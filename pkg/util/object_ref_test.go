@@ -0,0 +1,43 @@
+package util
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ObjectRef
+		wantErr bool
+	}{
+		{"Deployment.v1.apps/prod/web", ObjectRef{schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "prod", "web"}, false},
+		{"ConfigMap.v1/settings", ObjectRef{schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "default", "settings"}, false},
+		{"Application.v1alpha2.startpunkt.ullberg.us/apps/site", ObjectRef{schema.GroupVersionKind{Group: "startpunkt.ullberg.us", Version: "v1alpha2", Kind: "Application"}, "apps", "site"}, false},
+		{"Namespace.v1//team-a", ObjectRef{schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, "", "team-a"}, false},
+		{"", ObjectRef{}, true},
+		{"web", ObjectRef{}, true},
+		{"Deployment/web", ObjectRef{}, true},
+		{"Deployment.v1.apps/", ObjectRef{}, true},
+		{"Deployment.v1.apps/a/b/c", ObjectRef{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseObjectRef(tt.input, "default")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseObjectRef(%q) = (%+v, %v), want (%+v, err=%v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestObjectRef_StringRoundTrip(t *testing.T) {
+	for _, in := range []string{"Deployment.v1.apps/prod/web", "ConfigMap.v1/default/settings", "Namespace.v1//team-a"} {
+		ref, err := ParseObjectRef(in, "other")
+		if err != nil {
+			t.Fatalf("ParseObjectRef(%q) error: %v", in, err)
+		}
+		if got := ref.String(); got != in {
+			t.Errorf("String() = %q, want %q", got, in)
+		}
+	}
+}
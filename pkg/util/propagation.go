@@ -0,0 +1,25 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ParsePropagationPolicy parses a deletion propagation policy, ignoring case.
+// An empty value returns "" so the API server default applies.
+func ParsePropagationPolicy(val string) (metav1.DeletionPropagation, error) {
+	switch strings.ToLower(strings.TrimSpace(val)) {
+	case "":
+		return "", nil
+	case "foreground":
+		return metav1.DeletePropagationForeground, nil
+	case "background":
+		return metav1.DeletePropagationBackground, nil
+	case "orphan":
+		return metav1.DeletePropagationOrphan, nil
+	default:
+		return "", fmt.Errorf("invalid deletion propagation %q: expected Foreground, Background or Orphan", val)
+	}
+}
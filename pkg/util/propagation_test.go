@@ -0,0 +1,27 @@
+package util
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePropagationPolicy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    metav1.DeletionPropagation
+		wantErr bool
+	}{
+		{"", "", false},
+		{"Foreground", metav1.DeletePropagationForeground, false},
+		{"background", metav1.DeletePropagationBackground, false},
+		{" ORPHAN ", metav1.DeletePropagationOrphan, false},
+		{"cascade", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePropagationPolicy(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePropagationPolicy(%q) = (%q, %v), want (%q, err=%v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var scaleGVK = schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "Scale"}
+
+// GetReplicas reads spec.replicas through the scale subresource
+func GetReplicas(ctx context.Context, c client.Client, obj *unstructured.Unstructured) (int64, error) {
+	scale := &unstructured.Unstructured{}
+	scale.SetGroupVersionKind(scaleGVK)
+	if err := c.SubResource("scale").Get(ctx, obj, scale); err != nil {
+		return 0, err
+	}
+	replicas, _, err := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	if err != nil {
+		return 0, fmt.Errorf("invalid scale replicas: %w", err)
+	}
+	return replicas, nil
+}
+
+// SetReplicas sets spec.replicas through the scale subresource
+func SetReplicas(ctx context.Context, c client.Client, obj *unstructured.Unstructured, replicas int64) error {
+	scale := &unstructured.Unstructured{}
+	scale.SetGroupVersionKind(scaleGVK)
+	patch := client.RawPatch(types.MergePatchType, []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)))
+	return c.SubResource("scale").Patch(ctx, obj, patch, client.WithSubResourceBody(scale))
+}
+
+// IsScaleUnsupported reports whether err means the object has no scale
+// subresource, which retrying does not fix
+func IsScaleUnsupported(err error) bool {
+	return apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) || meta.IsNoMatchError(err)
+}